@@ -22,6 +22,7 @@ func ConnectDB() {
 	log.Println("Berhasil terhubung ke PostgreSQL!")
 
 	// --- TAMBAHKAN MIGRATE DI SINI ---
+	DedupeCertificates(database)
 	log.Println("Menjalankan AutoMigrate...")
 
 	err = database.AutoMigrate(
@@ -29,7 +30,11 @@ func ConnectDB() {
 		&models.LearningPath{},
 		&models.Chapter{}, // New grouping level
 		&models.Quiz{},
+		&models.LessonPrerequisite{},
+		&models.PathPrerequisite{},
+		&models.QuestionBank{},
 		&models.Question{},
+		&models.QuizAttempt{},
 		&models.Post{},
 		&models.Category{},
 		&models.PathCategory{},
@@ -37,15 +42,30 @@ func ConnectDB() {
 		&models.Asset{},
 		&models.Thread{},
 		&models.Comment{},
+		&models.Cohort{},
 		&models.Enrollment{},
 		&models.UserProgress{},
+		&models.PeerReview{},
+		&models.Submission{},
+		&models.SubmissionComment{},
 		&models.Like{},
 		&models.Certificate{},
 		&models.Contact{},
 		&models.CertificateTemplate{},
+		&models.Plan{},
 		&models.Payment{},
+		&models.Subscription{},
+		&models.Coupon{},
+		&models.CouponRedemption{},
+		&models.CreditTransaction{},
+		&models.Invoice{},
+		&models.InvoiceCounter{},
+		&models.PaymentRefund{},
+		&models.PaymentNotification{},
+		&models.UserSession{},
 		&models.Subscriber{},
 		&models.Resume{},
+		&models.Upload{},
 	)
 
 	if err != nil {
@@ -57,4 +77,12 @@ func ConnectDB() {
 
 	// Run Seeder
 	SeedSuperAdmin()
+	SeedPlans()
+
+	// Data migrations
+	MigrateLegacyQuestions()
+	MigrateLegacySubmissions()
+	MigrateEnrollments()
+	MigrateCertificateTemplates()
+	MigrateProSubscriptions()
 }
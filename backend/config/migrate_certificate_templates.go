@@ -0,0 +1,146 @@
+package config
+
+import (
+	"log"
+
+	"github.com/imam/backend-blog-kuis/models"
+	"gorm.io/gorm"
+)
+
+// legacyCertLayout adalah kolom posisi sertifikat lama pada learning_paths dan certificate_templates
+type legacyCertLayout struct {
+	ID         uint
+	Title      string
+	CertBg     string
+	CertColor  string
+	CertPdfURL string `gorm:"column:cert_pdf_url"`
+	CertNameX  float64
+	CertNameY  float64
+	CertDateX  float64
+	CertDateY  float64
+	CertIdX    float64
+	CertIdY    float64
+}
+
+// legacyCustomCertCondition memilih learning path yang salah satu kolom Cert*-nya berbeda dari
+// nilai bawaan (latar, warna, posisi, atau ukuran font) agar desainnya tidak hilang saat kolom dihapus
+const legacyCustomCertCondition = `COALESCE(cert_bg, '') <> '' OR COALESCE(cert_pdf_url, '') <> ''
+	OR LOWER(COALESCE(NULLIF(cert_color, ''), '#2563eb')) <> '#2563eb'
+	OR COALESCE(cert_name_x, 0) <> 0 OR COALESCE(cert_name_y, 0) <> 0
+	OR COALESCE(cert_date_x, 0) <> 0 OR COALESCE(cert_date_y, 0) <> 0
+	OR COALESCE(cert_id_x, 0) <> 0 OR COALESCE(cert_id_y, 0) <> 0
+	OR COALESCE(NULLIF(cert_font_size, 0), 30) <> 30`
+
+var legacyCertPositionColumns = []string{"cert_name_x", "cert_name_y", "cert_date_x", "cert_date_y", "cert_id_x", "cert_id_y"}
+
+// legacyCertFields mengubah posisi lama (nama, tanggal, ID) menjadi daftar field template.
+// Judul path dulu selalu digambar di bawah nama, sehingga ikut dibuatkan field-nya.
+func legacyCertFields(l legacyCertLayout, fontSize int) []models.CertificateField {
+	if fontSize <= 0 {
+		fontSize = 30
+	}
+	nameX, nameY := orDefault(l.CertNameX, 100), orDefault(l.CertNameY, 400)
+	return []models.CertificateField{
+		{Type: models.CertFieldName, X: nameX, Y: nameY, Bold: true},
+		{Type: models.CertFieldPathTitle, X: nameX, Y: nameY - float64(fontSize)*1.4, FontSize: fontSize * 6 / 10, Bold: true},
+		{Type: models.CertFieldDate, X: orDefault(l.CertDateX, 100), Y: orDefault(l.CertDateY, 120), FontSize: 12},
+		{Type: models.CertFieldID, X: orDefault(l.CertIdX, 841.89-260), Y: orDefault(l.CertIdY, 60), FontSize: 12, Label: "ID: "},
+	}
+}
+
+func orDefault(value, fallback float64) float64 {
+	if value == 0 {
+		return fallback
+	}
+	return value
+}
+
+// MigrateCertificateTemplates memindahkan kolom Cert* lama ke pustaka template:
+// posisi pada template global menjadi field, dan setiap learning path yang punya desain
+// sendiri (kolom Cert* mana pun yang tidak bawaan) dibuatkan template yang di-assign ke path
+// tersebut. Kolom lama lalu dihapus.
+func MigrateCertificateTemplates() {
+	migrator := DB.Migrator()
+
+	if migrator.HasColumn(&models.CertificateTemplate{}, "cert_name_x") {
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			var rows []struct {
+				legacyCertLayout
+				CertFontSize int
+			}
+			if err := tx.Table("certificate_templates").
+				Select("id, cert_name_x, cert_name_y, cert_date_x, cert_date_y, cert_id_x, cert_id_y, cert_font_size").
+				Scan(&rows).Error; err != nil {
+				return err
+			}
+			for _, row := range rows {
+				template := models.CertificateTemplate{ID: row.ID, Name: "Template Bawaan", Fields: legacyCertFields(row.legacyCertLayout, row.CertFontSize)}
+				if err := tx.Model(&template).Select("Name", "Fields").Updates(&template).Error; err != nil {
+					return err
+				}
+			}
+			for _, column := range legacyCertPositionColumns {
+				if err := tx.Migrator().DropColumn(&models.CertificateTemplate{}, column); err != nil {
+					return err
+				}
+			}
+			log.Printf("Migrasi template sertifikat: %d template global dikonversi", len(rows))
+			return nil
+		})
+		if err != nil {
+			log.Printf("Gagal migrasi template sertifikat global: %v", err)
+			return
+		}
+	}
+
+	if !migrator.HasColumn(&models.LearningPath{}, "cert_bg") {
+		return
+	}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var rows []struct {
+			legacyCertLayout
+			CertFontSize int
+		}
+		if err := tx.Table("learning_paths").
+			Select("id, title, cert_bg, cert_color, cert_pdf_url, cert_name_x, cert_name_y, cert_date_x, cert_date_y, cert_id_x, cert_id_y, cert_font_size").
+			Where(legacyCustomCertCondition).
+			Scan(&rows).Error; err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			template := models.CertificateTemplate{
+				Name:            "Sertifikat " + row.Title,
+				BackgroundImage: row.CertBg,
+				PrimaryColor:    row.CertColor,
+				CertPdfURL:      row.CertPdfURL,
+				CertFontSize:    row.CertFontSize,
+				Fields:          legacyCertFields(row.legacyCertLayout, row.CertFontSize),
+			}
+			if template.CertFontSize <= 0 {
+				template.CertFontSize = 30
+			}
+			if template.PrimaryColor == "" {
+				template.PrimaryColor = "#2563eb"
+			}
+			if err := tx.Create(&template).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.LearningPath{}).Where("id = ?", row.ID).
+				Update("certificate_template_id", template.ID).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, column := range append([]string{"cert_bg", "cert_color", "cert_pdf_url", "cert_font_size"}, legacyCertPositionColumns...) {
+			if err := tx.Migrator().DropColumn(&models.LearningPath{}, column); err != nil {
+				return err
+			}
+		}
+		log.Printf("Migrasi template sertifikat: %d learning path dipindahkan ke template", len(rows))
+		return nil
+	})
+	if err != nil {
+		log.Printf("Gagal migrasi template sertifikat learning path: %v", err)
+	}
+}
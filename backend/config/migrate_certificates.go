@@ -0,0 +1,35 @@
+package config
+
+import (
+	"log"
+
+	"github.com/imam/backend-blog-kuis/models"
+	"gorm.io/gorm"
+)
+
+// DedupeCertificates menghapus sertifikat ganda untuk pasangan user-path yang sama
+// (menyisakan yang paling awal diterbitkan) agar unique index (user, path) bisa dibuat.
+// Dipanggil sebelum AutoMigrate.
+func DedupeCertificates(db *gorm.DB) {
+	if !db.Migrator().HasTable(&models.Certificate{}) {
+		return
+	}
+
+	result := db.Exec(`
+		DELETE FROM certificates
+		WHERE id IN (
+			SELECT id FROM (
+				SELECT id, ROW_NUMBER() OVER (PARTITION BY user_id, learning_path_id ORDER BY issued_at ASC, id ASC) AS rn
+				FROM certificates
+			) ranked
+			WHERE ranked.rn > 1
+		)`)
+
+	if result.Error != nil {
+		log.Printf("Gagal menghapus sertifikat ganda: %v", result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Menghapus %d sertifikat ganda sebelum migrasi", result.RowsAffected)
+	}
+}
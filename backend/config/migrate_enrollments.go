@@ -0,0 +1,36 @@
+package config
+
+import "log"
+
+// MigrateEnrollments membuat Enrollment untuk user yang sudah punya progres di sebuah path
+// sebelum tabel enrollments dipakai, sehingga pendaftaran lama tetap tercatat.
+func MigrateEnrollments() {
+	result := DB.Exec(`
+		INSERT INTO enrollments (user_id, learning_path_id, enrolled_at)
+		SELECT user_progresses.user_id, quizzes.path_id, MIN(user_progresses.created_at)
+		FROM user_progresses
+		JOIN quizzes ON quizzes.id = user_progresses.quiz_id
+		JOIN learning_paths ON learning_paths.id = quizzes.path_id
+		WHERE NOT EXISTS (
+			SELECT 1 FROM enrollments
+			WHERE enrollments.user_id = user_progresses.user_id AND enrollments.learning_path_id = quizzes.path_id
+		)
+		GROUP BY user_progresses.user_id, quizzes.path_id`)
+
+	if result.Error != nil {
+		log.Printf("Gagal migrasi enrollment: %v", result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Migrasi enrollment: %d enrollment dibuat dari progres lama", result.RowsAffected)
+	}
+
+	// UserCount sebelumnya tidak pernah diperbarui, sinkronkan dengan jumlah enrollment
+	if err := DB.Exec(`
+		UPDATE learning_paths SET user_count = (
+			SELECT COUNT(*) FROM enrollments
+			WHERE enrollments.learning_path_id = learning_paths.id AND enrollments.deleted_at IS NULL
+		)`).Error; err != nil {
+		log.Printf("Gagal sinkronisasi jumlah peserta: %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/imam/backend-blog-kuis/models"
+)
+
+// MigrateLegacyQuestions mengubah soal lama (opsi dipisahkan koma) ke skema soal terstruktur
+func MigrateLegacyQuestions() {
+	var questions []models.Question
+	DB.Where("choices IS NULL AND ((options IS NOT NULL AND options != '') OR (correct_answer IS NOT NULL AND correct_answer != ''))").
+		Find(&questions)
+
+	if len(questions) == 0 {
+		return
+	}
+
+	log.Printf("Migrasi %d soal dari format lama...", len(questions))
+	for i := range questions {
+		q := &questions[i]
+		convertLegacyQuestion(q)
+		if err := DB.Model(q).Select("Kind", "Options", "Answer", "Points").Updates(q).Error; err != nil {
+			log.Printf("Gagal migrasi soal #%d: %v", q.ID, err)
+		}
+	}
+}
+
+// convertLegacyQuestion memetakan opsi lama ke option ber-ID dan mencari kunci jawabannya
+func convertLegacyQuestion(q *models.Question) {
+	correct := strings.TrimSpace(q.LegacyCorrectAnswer)
+	if q.Points <= 0 {
+		q.Points = 1
+	}
+
+	var parts []string
+	for _, p := range strings.Split(q.LegacyOptions, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			parts = append(parts, p)
+		}
+	}
+
+	// Tanpa opsi berarti soal isian dengan satu jawaban persis
+	if len(parts) == 0 {
+		q.Kind = models.QuestionKindShortText
+		q.Answer = models.QuestionAnswer{Patterns: []string{regexp.QuoteMeta(correct)}}
+		return
+	}
+
+	q.Kind = models.QuestionKindSingleChoice
+	if len(parts) == 2 && isTrueFalsePair(parts[0], parts[1]) {
+		q.Kind = models.QuestionKindTrueFalse
+	}
+
+	q.Options = nil
+	q.Answer = models.QuestionAnswer{}
+	for i, p := range parts {
+		id := fmt.Sprintf("opt%d", i+1)
+		q.Options = append(q.Options, models.QuestionOption{ID: id, Text: p})
+		if len(q.Answer.OptionIDs) == 0 && strings.EqualFold(p, correct) {
+			q.Answer.OptionIDs = []string{id}
+		}
+	}
+
+	// Beberapa soal lama menyimpan kunci jawaban sebagai huruf (A, B, C, ...)
+	if len(q.Answer.OptionIDs) == 0 && len(correct) == 1 {
+		idx := int(strings.ToUpper(correct)[0] - 'A')
+		if idx >= 0 && idx < len(q.Options) {
+			q.Answer.OptionIDs = []string{q.Options[idx].ID}
+		}
+	}
+
+	if len(q.Answer.OptionIDs) == 0 {
+		log.Printf("Peringatan: kunci jawaban soal #%d (%q) tidak cocok dengan opsi manapun", q.ID, correct)
+	}
+}
+
+func isTrueFalsePair(a, b string) bool {
+	pair := strings.ToLower(a) + "/" + strings.ToLower(b)
+	switch pair {
+	case "true/false", "false/true", "benar/salah", "salah/benar":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"log"
+
+	"github.com/imam/backend-blog-kuis/models"
+)
+
+// MigrateLegacySubmissions membuat baris Submission pertama untuk progres project lama
+// yang sudah punya file/link tetapi belum memiliki riwayat submission.
+func MigrateLegacySubmissions() {
+	var progresses []models.UserProgress
+	DB.Joins("JOIN quizzes ON quizzes.id = user_progresses.quiz_id").
+		Where("quizzes.type = 'project'").
+		Where("(user_progresses.submission_file_url != '' OR user_progresses.submission_drive_link != '')").
+		Where("NOT EXISTS (SELECT 1 FROM submissions WHERE submissions.progress_id = user_progresses.id)").
+		Find(&progresses)
+
+	for _, p := range progresses {
+		status := p.ApprovalStatus
+		if status == "" {
+			status = "pending"
+		}
+		submission := models.Submission{
+			ProgressID: p.ID,
+			UserID:     p.UserID,
+			QuizID:     p.QuizID,
+			Attempt:    1,
+			FileURL:    p.SubmissionFileURL,
+			DriveLink:  p.SubmissionDriveLink,
+			Status:     status,
+			ReviewNote: p.AdminNote,
+			CreatedAt:  p.UpdatedAt,
+		}
+		if status != "pending" {
+			reviewedAt := p.UpdatedAt
+			submission.ReviewedAt = &reviewedAt
+		}
+		DB.Create(&submission)
+	}
+
+	if len(progresses) > 0 {
+		log.Printf("Migrasi riwayat submission: %d submission dibuat", len(progresses))
+	}
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/imam/backend-blog-kuis/models"
+)
+
+// MigrateProSubscriptions membuat Subscription untuk user yang sudah ber-role "pro" sebelum
+// langganan dicatat. Masa aktif dihitung dari pembayaran sukses terakhir (30 hari, atau 365
+// hari untuk paket tahunan); langganan yang sudah lewat langsung diakhiri oleh job expiry.
+func MigrateProSubscriptions() {
+	var users []models.User
+	DB.Where("role = ?", "pro").
+		Where("NOT EXISTS (SELECT 1 FROM subscriptions WHERE subscriptions.user_id = users.id)").
+		Find(&users)
+
+	for _, user := range users {
+		var payment models.Payment
+		start := time.Now()
+		duration := 30
+		subscription := models.Subscription{UserID: user.ID, Status: "active"}
+
+		if err := DB.Where("user_id = ? AND status = ?", user.ID, "success").Order("updated_at DESC").First(&payment).Error; err == nil {
+			start = payment.UpdatedAt
+			if payment.PaidAt != nil {
+				start = *payment.PaidAt
+			}
+			planType := strings.ToLower(payment.PaymentType)
+			if strings.Contains(planType, "annual") || strings.Contains(planType, "tahun") {
+				duration = 365
+			}
+			subscription.PaymentID = &payment.ID
+			subscription.PlanID = payment.PlanID
+		}
+		subscription.StartsAt = start
+		subscription.EndsAt = start.AddDate(0, 0, duration)
+
+		if err := DB.Create(&subscription).Error; err != nil {
+			log.Printf("Gagal migrasi langganan user #%d: %v", user.ID, err)
+		}
+	}
+
+	if len(users) > 0 {
+		log.Printf("Migrasi langganan: %d user pro dibuatkan langganan", len(users))
+	}
+}
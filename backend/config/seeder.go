@@ -33,3 +33,31 @@ func SeedSuperAdmin() {
 		}
 	}
 }
+
+// SeedPlans membuat paket langganan bawaan jika katalog masih kosong
+func SeedPlans() {
+	var count int64
+	DB.Model(&models.Plan{}).Count(&count)
+	if count > 0 {
+		return
+	}
+
+	plan := models.Plan{
+		Code:         "pro_monthly",
+		Name:         "Pro",
+		Description:  "Untuk profesional yang ingin mendalami teknologi lebih serius.",
+		Price:        99000,
+		DurationDays: 30,
+		Features: []string{
+			"Semua fitur paket Free",
+			"Artikel eksklusif & studi kasus",
+			"Kuis advance dengan sertifikat",
+			"Download Asset 3D premium",
+			"Prioritas dukungan komunitas",
+		},
+		Active: true,
+	}
+	if err := DB.Create(&plan).Error; err != nil {
+		log.Println("Gagal seeding paket langganan:", err)
+	}
+}
@@ -86,22 +86,12 @@ func UserCreateAsset(c *gin.Context) {
 	}
 
 	// Ambil userID dari context (set oleh middleware)
-	userID, exists := c.Get("user_id")
+	userID, exists := currentUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User tidak terautentikasi"})
 		return
 	}
-
-	// Konversi userID ke uint (JWT menyimpan angka sebagai float64)
-	switch v := userID.(type) {
-	case float64:
-		asset.UserID = uint(v)
-	case uint:
-		asset.UserID = v
-	default:
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "ID user tidak valid"})
-		return
-	}
+	asset.UserID = userID
 
 	if err := config.DB.Create(&asset).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan asset"})
@@ -8,9 +8,9 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/imam/backend-blog-kuis/config"
 	"github.com/imam/backend-blog-kuis/models"
+	"github.com/imam/backend-blog-kuis/services"
 	"github.com/imam/backend-blog-kuis/utils"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -42,6 +42,21 @@ func Register(c *gin.Context) {
 	}
 	user.Password = string(hashedPassword)
 
+	// Kode referral milik user dibuat sistem; pengundang hanya dari kode referral yang valid
+	user.ReferralCode = nil
+	user.ReferredByID = nil
+	if user.ReferredByCode != "" {
+		referrer, err := services.FindReferrer(user.ReferredByCode)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		user.ReferredByID = &referrer.ID
+		if user.ReferralSource == "" {
+			user.ReferralSource = "referral"
+		}
+	}
+
 	if err := config.DB.Create(&user).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Username/Email sudah digunakan"})
 		return
@@ -82,23 +97,19 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	// GENERATE JWT TOKEN YANG ASLI
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id":  user.ID,
-		"username": user.Username,
-		"role":     user.Role,
-		"exp":      time.Now().Add(time.Hour * 24).Unix(), // Token berlaku 24 jam
-	})
-
-	tokenString, err := token.SignedString(utils.GetJWTSecret())
+	// Buat sesi baru: access token berumur pendek + refresh token yang disimpan di server
+	tokens, err := services.CreateSession(user, sessionClient(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal generate token"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"token":   tokenString, // Sekarang ini adalah JWT valid (header.payload.sig)
-		"message": "Login berhasil",
+		"token":              tokens.AccessToken,
+		"refresh_token":      tokens.RefreshToken,
+		"expires_at":         tokens.ExpiresAt,
+		"refresh_expires_at": tokens.RefreshExpiresAt,
+		"message":            "Login berhasil",
 	})
 }
 
@@ -174,28 +185,20 @@ func ResetPassword(c *gin.Context) {
 		return
 	}
 
+	// Password diganti: keluarkan semua perangkat yang masih login
+	services.RevokeUserSessions(user.ID, 0, services.SessionPasswordReset)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Password berhasil diperbarui"})
 }
 
 // GetCurrentUser returns the current logged in user profile
 func GetCurrentUser(c *gin.Context) {
-	userIDRaw, exists := c.Get("user_id")
-	if !exists {
+	userID, ok := currentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	var userID uint
-	switch v := userIDRaw.(type) {
-	case float64:
-		userID = uint(v)
-	case uint:
-		userID = v
-	default:
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
-		return
-	}
-
 	var user models.User
 	if err := config.DB.First(&user, userID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User tidak ditemukan"})
@@ -207,23 +210,12 @@ func GetCurrentUser(c *gin.Context) {
 
 // UpdateProfile updates the current user's profile information
 func UpdateProfile(c *gin.Context) {
-	userIDRaw, exists := c.Get("user_id")
-	if !exists {
+	userID, ok := currentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	var userID uint
-	switch v := userIDRaw.(type) {
-	case float64:
-		userID = uint(v)
-	case uint:
-		userID = v
-	default:
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
-		return
-	}
-
 	var input struct {
 		Email          string `json:"email"`
 		Phone          string `json:"phone"`
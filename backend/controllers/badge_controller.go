@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/imam/backend-blog-kuis/config"
+	"github.com/imam/backend-blog-kuis/models"
+	"github.com/imam/backend-blog-kuis/services"
+	"github.com/imam/backend-blog-kuis/utils"
+)
+
+// apiBaseURL mengembalikan URL publik backend untuk ID JSON-LD (API_URL, atau host dari request)
+func apiBaseURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return utils.APIURL(scheme + "://" + c.Request.Host)
+}
+
+// loadBadgeCertificate memuat sertifikat beserta relasi yang dibutuhkan badge; sertifikat dicabut ditolak
+func loadBadgeCertificate(c *gin.Context) (models.Certificate, bool) {
+	var cert models.Certificate
+	if err := config.DB.Preload("User").Preload("LearningPath.Category").
+		Where("certificate_id = ?", c.Param("id")).First(&cert).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sertifikat tidak ditemukan"})
+		return cert, false
+	}
+	if cert.IsRevoked() {
+		c.JSON(http.StatusGone, gin.H{"error": "Sertifikat sudah dicabut"})
+		return cert, false
+	}
+	return cert, true
+}
+
+// GetBadgeIssuer - Profil penerbit Open Badges beserta kunci verifikasi (Public)
+func GetBadgeIssuer(c *gin.Context) {
+	profile, err := services.BadgeIssuerProfile(apiBaseURL(c))
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Kunci verifikasi belum tersedia"})
+		return
+	}
+	c.Header("Content-Type", "application/ld+json")
+	c.JSON(http.StatusOK, profile)
+}
+
+// GetBadgeAchievement - Metadata badge class (Achievement) sebuah learning path (Public)
+func GetBadgeAchievement(c *gin.Context) {
+	var path models.LearningPath
+	if err := config.DB.Preload("Category").First(&path, c.Param("pathId")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Learning path tidak ditemukan"})
+		return
+	}
+	c.Header("Content-Type", "application/ld+json")
+	c.JSON(http.StatusOK, services.BadgeAchievement(apiBaseURL(c), path))
+}
+
+// GetBadgeAchievementImage - Gambar badge (PNG) sebuah learning path (Public)
+func GetBadgeAchievementImage(c *gin.Context) {
+	var path models.LearningPath
+	if err := config.DB.First(&path, c.Param("pathId")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Learning path tidak ditemukan"})
+		return
+	}
+	image, err := services.BadgeImagePNG(path)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membuat gambar badge"})
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.Data(http.StatusOK, "image/png", image)
+}
+
+// GetCertificateBadge - Export sertifikat sebagai OpenBadgeCredential (Open Badges 3.0, JSON-LD)
+func GetCertificateBadge(c *gin.Context) {
+	cert, ok := loadBadgeCertificate(c)
+	if !ok {
+		return
+	}
+
+	credential, err := services.OpenBadgeCredential(apiBaseURL(c), cert)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membuat badge: " + err.Error()})
+		return
+	}
+
+	if c.Query("download") == "1" || c.Query("download") == "true" {
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="badge-%s.json"`, cert.CertificateID))
+	}
+	c.Header("Content-Type", "application/ld+json")
+	c.JSON(http.StatusOK, credential)
+}
+
+// GetCertificateBakedBadge - Download badge PNG/SVG dengan credential tertanam (?format=png|svg)
+func GetCertificateBakedBadge(c *gin.Context) {
+	cert, ok := loadBadgeCertificate(c)
+	if !ok {
+		return
+	}
+
+	credential, err := services.OpenBadgeCredential(apiBaseURL(c), cert)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membuat badge: " + err.Error()})
+		return
+	}
+	credentialJSON, _ := json.Marshal(credential)
+
+	format := c.DefaultQuery("format", "png")
+	var data []byte
+	var contentType string
+	switch format {
+	case "svg":
+		data = services.BakedBadgeSVG(cert.LearningPath, cert.User.Username, credentialJSON)
+		contentType = "image/svg+xml"
+	case "png":
+		image, err := services.BadgeImagePNG(cert.LearningPath)
+		if err == nil {
+			data, err = services.BakeBadgePNG(image, credentialJSON)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membuat gambar badge"})
+			return
+		}
+		contentType = "image/png"
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format harus png atau svg"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="badge-%s.%s"`, cert.CertificateID, format))
+	c.Data(http.StatusOK, contentType, data)
+}
@@ -1,19 +1,25 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/imam/backend-blog-kuis/config"
 	"github.com/imam/backend-blog-kuis/models"
+	"github.com/imam/backend-blog-kuis/services"
 )
 
 // GetUserCertificates - Fetch all certificates for the logged-in user
 func GetUserCertificates(c *gin.Context) {
-	userID := c.Query("userId")
-	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "userId is required"})
+	userID, ok := targetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
@@ -36,57 +42,271 @@ func GetCertificateByID(c *gin.Context) {
 		return
 	}
 
+	template := services.ResolveCertificateTemplate(certificate.LearningPath)
+	nameField, _ := template.Field(models.CertFieldName)
+	dateField, _ := template.Field(models.CertFieldDate)
+	idField, _ := template.Field(models.CertFieldID)
+
+	status := services.CertificateValid
+	if certificate.IsRevoked() {
+		status = services.CertificateRevoked
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"id":           certificate.CertificateID,
-		"userName":     certificate.User.Username,
-		"pathTitle":    certificate.LearningPath.Title,
-		"issuedAt":     certificate.IssuedAt,
-		"certPdfUrl":   certificate.LearningPath.CertPdfURL,
-		"certNameX":    certificate.LearningPath.CertNameX,
-		"certNameY":    certificate.LearningPath.CertNameY,
-		"certFontSize": certificate.LearningPath.CertFontSize,
+		"id":            certificate.CertificateID,
+		"status":        status,
+		"revoked":       certificate.IsRevoked(),
+		"revokedAt":     certificate.RevokedAt,
+		"revokedReason": certificate.RevokedReason,
+		"userName":      certificate.User.Username,
+		"pathTitle":     certificate.LearningPath.Title,
+		"issuedAt":      certificate.IssuedAt,
+		"pdfUrl":        "/api/certificates/" + certificate.CertificateID + "/pdf",
+		"verifyUrl":     services.CertificateVerifyURL(certificate),
+		"payload":       certificate.Payload,
+		"signature":     certificate.Signature,
+		"template":      template,
+		// Posisi lama tetap dikirim untuk tampilan sertifikat di frontend
+		"certBg":       template.BackgroundImage,
+		"certColor":    template.PrimaryColor,
+		"certPdfUrl":   template.CertPdfURL,
+		"certNameX":    nameField.X,
+		"certNameY":    nameField.Y,
+		"certDateX":    dateField.X,
+		"certDateY":    dateField.Y,
+		"certIdX":      idField.X,
+		"certIdY":      idField.Y,
+		"certFontSize": template.CertFontSize,
 	})
 }
 
+// GetCertificatePDF - Render sertifikat menjadi PDF di server (hasil disimpan di cache disk)
+func GetCertificatePDF(c *gin.Context) {
+	certID := c.Param("id")
+
+	var certificate models.Certificate
+	if err := config.DB.Preload("User").Preload("LearningPath").Where("certificate_id = ?", certID).First(&certificate).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sertifikat tidak ditemukan"})
+		return
+	}
+	// Sertifikat bisa dicabut kapan saja, sehingga cache wajib divalidasi ulang di setiap request
+	c.Header("Cache-Control", "private, no-cache")
+	if certificate.IsRevoked() {
+		c.JSON(http.StatusGone, gin.H{"error": "Sertifikat sudah dicabut"})
+		return
+	}
+
+	filePath, err := services.CertificatePDF(certificate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membuat PDF sertifikat: " + err.Error()})
+		return
+	}
+
+	disposition := "inline"
+	if c.Query("download") == "1" || c.Query("download") == "true" {
+		disposition = "attachment"
+	}
+	c.Header("Content-Type", "application/pdf")
+	c.Header("Content-Disposition", fmt.Sprintf(`%s; filename="sertifikat-%s.pdf"`, disposition, certificate.CertificateID))
+	// ETag mengikuti isi PDF dan status pencabutan; c.File menjawab If-None-Match dengan 304
+	c.Header("ETag", fmt.Sprintf(`"%s-active"`, strings.TrimSuffix(filepath.Base(filePath), ".pdf")))
+	c.File(filePath)
+}
+
+// GetCertificatePublicKey - Kunci publik Ed25519 (JWKS) untuk verifikasi sertifikat secara offline
+func GetCertificatePublicKey(c *gin.Context) {
+	jwk, err := services.CertificatePublicKeyJWK()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Kunci verifikasi belum tersedia"})
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.JSON(http.StatusOK, gin.H{"keys": []map[string]string{jwk}})
+}
+
+// VerifyCertificate - Verifikasi payload sertifikat bertanda tangan (token dari QR code atau payload + signature)
+func VerifyCertificate(c *gin.Context) {
+	var input struct {
+		Token     string `json:"token" form:"token"`
+		Payload   string `json:"payload" form:"payload"`
+		Signature string `json:"signature" form:"signature"`
+	}
+	if c.Request.Method == http.MethodGet {
+		c.ShouldBindQuery(&input)
+	} else if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	payload, signature := []byte(input.Payload), input.Signature
+	if input.Token != "" {
+		var err error
+		if payload, signature, err = services.ParseCertificateToken(input.Token); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if len(payload) == 0 || signature == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token atau payload dan signature diperlukan"})
+		return
+	}
+
+	c.JSON(http.StatusOK, services.VerifyCertificateToken(payload, signature))
+}
+
 // GetAllCertificates - Fetch all certificates (Admin Only)
 func GetAllCertificates(c *gin.Context) {
 	var certificates []models.Certificate
-	if err := config.DB.Preload("User").Preload("LearningPath").Find(&certificates).Error; err != nil {
+	if err := config.DB.Preload("User").Preload("LearningPath").Preload("RevokedBy").Find(&certificates).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil semua sertifikat"})
 		return
 	}
 
 	// Format response to be flat and easy for the table
 	type FormattedCert struct {
-		ID            uint      `json:"id"`
-		CertificateID string    `json:"certificateId"`
-		UserName      string    `json:"userName"`
-		UserEmail     string    `json:"userEmail"`
-		PathTitle     string    `json:"pathTitle"`
-		IssuedAt      time.Time `json:"issuedAt"`
+		ID            uint       `json:"id"`
+		CertificateID string     `json:"certificateId"`
+		UserName      string     `json:"userName"`
+		UserEmail     string     `json:"userEmail"`
+		PathTitle     string     `json:"pathTitle"`
+		IssuedAt      time.Time  `json:"issuedAt"`
+		Revoked       bool       `json:"revoked"`
+		RevokedAt     *time.Time `json:"revokedAt"`
+		RevokedReason string     `json:"revokedReason"`
+		RevokedBy     string     `json:"revokedBy"`
 	}
 
 	results := []FormattedCert{}
 	for _, cert := range certificates {
-		results = append(results, FormattedCert{
+		formatted := FormattedCert{
 			ID:            cert.ID,
 			CertificateID: cert.CertificateID,
 			UserName:      cert.User.Username,
 			UserEmail:     cert.User.Email,
 			PathTitle:     cert.LearningPath.Title,
 			IssuedAt:      cert.IssuedAt,
-		})
+			Revoked:       cert.IsRevoked(),
+			RevokedAt:     cert.RevokedAt,
+			RevokedReason: cert.RevokedReason,
+		}
+		if cert.RevokedBy != nil {
+			formatted.RevokedBy = cert.RevokedBy.Username
+		}
+		results = append(results, formatted)
 	}
 
 	c.JSON(http.StatusOK, results)
 }
 
-// RevokeCertificate - Delete a certificate by its internal ID (Admin Only)
+// BackfillCertificates - Menerbitkan sertifikat untuk learner yang sudah memenuhi syarat (Super Admin)
+func BackfillCertificates(c *gin.Context) {
+	result := services.BackfillCertificates()
+	c.JSON(http.StatusOK, gin.H{"message": "Backfill sertifikat selesai", "data": result})
+}
+
+// respondCertificateError memetakan error service sertifikat ke status HTTP
+func respondCertificateError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrCertificateNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrCertificateAlreadyRevoked), errors.Is(err, services.ErrCertificateNotRevoked):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// RevokeCertificate - Mencabut sertifikat berdasarkan ID internal; data tetap disimpan (Admin Only)
 func RevokeCertificate(c *gin.Context) {
-	id := c.Param("id")
-	if err := config.DB.Delete(&models.Certificate{}, id).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mencabut sertifikat"})
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID sertifikat tidak valid"})
+		return
+	}
+
+	var input struct {
+		Reason string `json:"reason"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if input.Reason == "" {
+		input.Reason = c.Query("reason")
+	}
+
+	cert, err := services.RevokeCertificate(uint(id), input.Reason, reviewerID(c))
+	if err != nil {
+		respondCertificateError(c, err, "Gagal mencabut sertifikat")
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "Sertifikat berhasil dicabut"})
+	c.JSON(http.StatusOK, gin.H{"message": "Sertifikat berhasil dicabut", "data": cert})
+}
+
+// ReinstateCertificate - Memulihkan sertifikat yang sudah dicabut (Admin Only)
+func ReinstateCertificate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID sertifikat tidak valid"})
+		return
+	}
+
+	cert, err := services.ReinstateCertificate(uint(id))
+	if err != nil {
+		respondCertificateError(c, err, "Gagal memulihkan sertifikat")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Sertifikat berhasil dipulihkan", "data": cert})
+}
+
+// GetRevokedCertificates - Daftar publik sertifikat yang dicabut dengan pagination (Public)
+func GetRevokedCertificates(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	var total int64
+	query := config.DB.Model(&models.Certificate{}).Where("revoked_at IS NOT NULL")
+	query.Count(&total)
+
+	var certificates []models.Certificate
+	if err := query.Preload("LearningPath").Order("revoked_at DESC").
+		Limit(limit).Offset((page - 1) * limit).Find(&certificates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil daftar pencabutan"})
+		return
+	}
+
+	// Nama pemegang tidak ditampilkan; cukup nomor sertifikat untuk dicocokkan verifier
+	type RevokedCert struct {
+		CertificateID string     `json:"certificateId"`
+		PathTitle     string     `json:"pathTitle"`
+		IssuedAt      time.Time  `json:"issuedAt"`
+		RevokedAt     *time.Time `json:"revokedAt"`
+		Reason        string     `json:"reason"`
+	}
+
+	results := []RevokedCert{}
+	for _, cert := range certificates {
+		results = append(results, RevokedCert{
+			CertificateID: cert.CertificateID,
+			PathTitle:     cert.LearningPath.Title,
+			IssuedAt:      cert.IssuedAt,
+			RevokedAt:     cert.RevokedAt,
+			Reason:        cert.RevokedReason,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  results,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
 }
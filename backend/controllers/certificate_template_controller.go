@@ -1,65 +1,347 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
+	"regexp"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/imam/backend-blog-kuis/config"
 	"github.com/imam/backend-blog-kuis/models"
+	"github.com/imam/backend-blog-kuis/services"
+	"gorm.io/gorm"
 )
 
-// GetCertificateTemplate - Get the active certificate template
+// certificateTemplateInput adalah body request untuk membuat/memperbarui template sertifikat
+type certificateTemplateInput struct {
+	Name            string                    `json:"name"`
+	BackgroundImage string                    `json:"backgroundImage"`
+	PrimaryColor    string                    `json:"primaryColor"`
+	CertPdfURL      string                    `json:"certPdfUrl"`
+	CertFontSize    int                       `json:"certFontSize"`
+	Fields          []models.CertificateField `json:"fields"`
+	Active          bool                      `json:"active"`
+}
+
+// CertificateTemplateResponse adalah template beserta learning path yang memakainya
+type CertificateTemplateResponse struct {
+	models.CertificateTemplate
+	PathIDs []uint `json:"pathIds"`
+}
+
+var hexColorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// validFieldTypes adalah jenis field yang didukung renderer sertifikat
+var validFieldTypes = map[string]bool{
+	models.CertFieldName:                true,
+	models.CertFieldDate:                true,
+	models.CertFieldID:                  true,
+	models.CertFieldPathTitle:           true,
+	models.CertFieldInstructorSignature: true,
+	models.CertFieldScore:               true,
+}
+
+// validateCertificateTemplate memeriksa warna, ukuran font, dan posisi setiap field
+func validateCertificateTemplate(input *certificateTemplateInput) error {
+	if input.PrimaryColor == "" {
+		input.PrimaryColor = "#2563eb"
+	}
+	if !hexColorPattern.MatchString(input.PrimaryColor) {
+		return errors.New("warna utama harus berupa hex color")
+	}
+	if input.CertFontSize <= 0 {
+		input.CertFontSize = 30
+	}
+	for _, f := range input.Fields {
+		if !validFieldTypes[f.Type] {
+			return errors.New("jenis field tidak dikenal: " + f.Type)
+		}
+		if f.X < 0 || f.Y < 0 || f.X > 841.89 || f.Y > 595.28 {
+			return errors.New("posisi field " + f.Type + " di luar halaman")
+		}
+		if f.Color != "" && !hexColorPattern.MatchString(f.Color) {
+			return errors.New("warna field " + f.Type + " harus berupa hex color")
+		}
+	}
+	return nil
+}
+
+// applyCertificateTemplateInput menyalin input ke model template
+func applyCertificateTemplateInput(template *models.CertificateTemplate, input certificateTemplateInput) {
+	template.Name = input.Name
+	template.BackgroundImage = input.BackgroundImage
+	template.PrimaryColor = input.PrimaryColor
+	template.CertPdfURL = input.CertPdfURL
+	template.CertFontSize = input.CertFontSize
+	template.Fields = input.Fields
+	template.Active = input.Active
+}
+
+// saveCertificateTemplate menyimpan template; jika Active, template lain tidak lagi menjadi bawaan
+func saveCertificateTemplate(template *models.CertificateTemplate) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if template.Active {
+			if err := tx.Model(&models.CertificateTemplate{}).Where("id <> ? AND active = ?", template.ID, true).
+				Update("active", false).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Save(template).Error
+	})
+}
+
+// toCertificateTemplateResponses melengkapi template dengan ID learning path yang memakainya
+func toCertificateTemplateResponses(templates []models.CertificateTemplate) []CertificateTemplateResponse {
+	ids := []uint{}
+	for _, t := range templates {
+		ids = append(ids, t.ID)
+	}
+
+	var paths []models.LearningPath
+	if len(ids) > 0 {
+		config.DB.Select("id, certificate_template_id").Where("certificate_template_id IN ?", ids).Order("id ASC").Find(&paths)
+	}
+	assigned := map[uint][]uint{}
+	for _, p := range paths {
+		assigned[*p.CertificateTemplateID] = append(assigned[*p.CertificateTemplateID], p.ID)
+	}
+
+	results := []CertificateTemplateResponse{}
+	for _, t := range templates {
+		if t.Fields == nil {
+			t.Fields = []models.CertificateField{}
+		}
+		pathIDs := assigned[t.ID]
+		if pathIDs == nil {
+			pathIDs = []uint{}
+		}
+		results = append(results, CertificateTemplateResponse{CertificateTemplate: t, PathIDs: pathIDs})
+	}
+	return results
+}
+
+// GetCertificateTemplate - Get the default (active) certificate template
 func GetCertificateTemplate(c *gin.Context) {
+	c.JSON(http.StatusOK, services.ResolveCertificateTemplate(models.LearningPath{}))
+}
+
+// UpdateCertificateTemplate - Update the default certificate template (dibuat jika belum ada)
+func UpdateCertificateTemplate(c *gin.Context) {
+	var input certificateTemplateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	var template models.CertificateTemplate
 	if err := config.DB.Where("active = ?", true).First(&template).Error; err != nil {
-		// If not found, create a default one
-		template = models.CertificateTemplate{
-			BackgroundImage: "",
-			PrimaryColor:    "#2563eb",
-			Active:          true,
+		template = models.CertificateTemplate{Name: "Template Bawaan"}
+	}
+	if input.Name == "" {
+		input.Name = template.Name
+	}
+	input.Active = true
+	if err := validateCertificateTemplate(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validasi gagal: " + err.Error()})
+		return
+	}
+
+	applyCertificateTemplateInput(&template, input)
+	if err := saveCertificateTemplate(&template); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui template sertifikat"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Template sertifikat diperbarui", "data": template})
+}
+
+// GetCertificateTemplates - Daftar semua template sertifikat (Super Admin)
+func GetCertificateTemplates(c *gin.Context) {
+	var templates []models.CertificateTemplate
+	config.DB.Order("active DESC, name ASC").Find(&templates)
+	c.JSON(http.StatusOK, toCertificateTemplateResponses(templates))
+}
+
+// GetCertificateTemplateByID - Detail template sertifikat (Super Admin)
+func GetCertificateTemplateByID(c *gin.Context) {
+	var template models.CertificateTemplate
+	if err := config.DB.First(&template, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template sertifikat tidak ditemukan"})
+		return
+	}
+	c.JSON(http.StatusOK, toCertificateTemplateResponses([]models.CertificateTemplate{template})[0])
+}
+
+// CreateCertificateTemplate - Membuat template sertifikat baru (Super Admin)
+func CreateCertificateTemplate(c *gin.Context) {
+	var input certificateTemplateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validasi gagal: nama template wajib diisi"})
+		return
+	}
+	if err := validateCertificateTemplate(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validasi gagal: " + err.Error()})
+		return
+	}
+
+	var template models.CertificateTemplate
+	applyCertificateTemplateInput(&template, input)
+	if err := saveCertificateTemplate(&template); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membuat template sertifikat"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Template sertifikat berhasil dibuat", "data": toCertificateTemplateResponses([]models.CertificateTemplate{template})[0]})
+}
+
+// UpdateCertificateTemplateByID - Memperbarui template sertifikat (Super Admin)
+func UpdateCertificateTemplateByID(c *gin.Context) {
+	var template models.CertificateTemplate
+	if err := config.DB.First(&template, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template sertifikat tidak ditemukan"})
+		return
+	}
+
+	var input certificateTemplateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validasi gagal: nama template wajib diisi"})
+		return
+	}
+	if err := validateCertificateTemplate(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validasi gagal: " + err.Error()})
+		return
+	}
+
+	applyCertificateTemplateInput(&template, input)
+	if err := saveCertificateTemplate(&template); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui template sertifikat"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Template sertifikat diperbarui", "data": toCertificateTemplateResponses([]models.CertificateTemplate{template})[0]})
+}
+
+// DeleteCertificateTemplate - Menghapus template; path yang memakainya kembali ke template bawaan (Super Admin)
+func DeleteCertificateTemplate(c *gin.Context) {
+	var template models.CertificateTemplate
+	if err := config.DB.First(&template, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template sertifikat tidak ditemukan"})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.LearningPath{}).Where("certificate_template_id = ?", template.ID).
+			Update("certificate_template_id", nil).Error; err != nil {
+			return err
 		}
-		config.DB.Create(&template)
+		return tx.Delete(&template).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghapus template sertifikat"})
+		return
 	}
-	c.JSON(http.StatusOK, template)
+	c.JSON(http.StatusOK, gin.H{"message": "Template sertifikat berhasil dihapus"})
 }
 
-// UpdateCertificateTemplate - Update the certificate template
-func UpdateCertificateTemplate(c *gin.Context) {
-	var input struct {
-		BackgroundImage string  `json:"backgroundImage"`
-		PrimaryColor    string  `json:"primaryColor"`
-		CertPdfURL      string  `json:"certPdfUrl"`
-		CertNameX       float64 `json:"certNameX"`
-		CertNameY       float64 `json:"certNameY"`
-		CertDateX       float64 `json:"certDateX"`
-		CertDateY       float64 `json:"certDateY"`
-		CertIdX         float64 `json:"certIdX"`
-		CertIdY         float64 `json:"certIdY"`
-		CertFontSize    int     `json:"certFontSize"`
+// AssignCertificateTemplate - Mengganti daftar learning path yang memakai template ini (Super Admin)
+func AssignCertificateTemplate(c *gin.Context) {
+	var template models.CertificateTemplate
+	if err := config.DB.First(&template, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template sertifikat tidak ditemukan"})
+		return
 	}
 
+	var input struct {
+		PathIDs []uint `json:"pathIds"`
+	}
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	var found int64
+	config.DB.Model(&models.LearningPath{}).Where("id IN ?", input.PathIDs).Count(&found)
+	if int(found) != len(input.PathIDs) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Learning path tidak ditemukan"})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		unassign := tx.Model(&models.LearningPath{}).Where("certificate_template_id = ?", template.ID)
+		if len(input.PathIDs) > 0 {
+			unassign = unassign.Where("id NOT IN ?", input.PathIDs)
+		}
+		if err := unassign.Update("certificate_template_id", nil).Error; err != nil {
+			return err
+		}
+		if len(input.PathIDs) == 0 {
+			return nil
+		}
+		return tx.Model(&models.LearningPath{}).Where("id IN ?", input.PathIDs).
+			Update("certificate_template_id", template.ID).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengatur learning path template"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Learning path template diperbarui", "data": toCertificateTemplateResponses([]models.CertificateTemplate{template})[0]})
+}
+
+// PreviewCertificateTemplate - Render contoh sertifikat dari template tersimpan (GET /:id/preview)
+// atau dari desain yang belum disimpan (POST /preview). ?pathId= memakai judul path sungguhan.
+func PreviewCertificateTemplate(c *gin.Context) {
 	var template models.CertificateTemplate
-	if err := config.DB.Where("active = ?", true).First(&template).Error; err != nil {
-		template = models.CertificateTemplate{Active: true}
-		config.DB.Create(&template)
+	if id := c.Param("id"); id != "" {
+		if err := config.DB.First(&template, id).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Template sertifikat tidak ditemukan"})
+			return
+		}
+	} else {
+		var input certificateTemplateInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := validateCertificateTemplate(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Validasi gagal: " + err.Error()})
+			return
+		}
+		applyCertificateTemplateInput(&template, input)
+	}
+	if len(template.Fields) == 0 {
+		template.Fields = services.DefaultCertificateFields()
 	}
 
-	template.BackgroundImage = input.BackgroundImage
-	template.PrimaryColor = input.PrimaryColor
-	template.CertPdfURL = input.CertPdfURL
-	template.CertNameX = input.CertNameX
-	template.CertNameY = input.CertNameY
-	template.CertDateX = input.CertDateX
-	template.CertDateY = input.CertDateY
-	template.CertIdX = input.CertIdX
-	template.CertIdY = input.CertIdY
-	template.CertFontSize = input.CertFontSize
-	config.DB.Save(&template)
+	path := models.LearningPath{Title: "Judul Learning Path"}
+	if pathID := c.Query("pathId"); pathID != "" {
+		config.DB.First(&path, pathID)
+	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Template sertifikat diperbarui", "data": template})
+	sampleScore := 87.5
+	sample := models.Certificate{
+		CertificateID:  "AIOT-0-PREVIEW",
+		IssuedAt:       time.Now(),
+		User:           models.User{Username: "Nama Peserta"},
+		LearningPath:   path,
+		LearningPathID: path.ID,
+	}
+
+	data, err := services.RenderCertificatePDF(sample, template, &sampleScore)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membuat preview: " + err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", `inline; filename="preview-sertifikat.pdf"`)
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "application/pdf", data)
 }
@@ -0,0 +1,273 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/imam/backend-blog-kuis/config"
+	"github.com/imam/backend-blog-kuis/models"
+	"github.com/imam/backend-blog-kuis/services"
+	"gorm.io/gorm"
+)
+
+// CohortResponse adalah data cohort beserta instruktur dan sisa kuota
+type CohortResponse struct {
+	models.Cohort
+	Instructors    []models.CohortInstructor `json:"instructors"`
+	EnrolledCount  int                       `json:"enrolledCount"`
+	SeatsLeft      *int                      `json:"seatsLeft"` // Kosong jika kuota tidak dibatasi
+	EnrollmentOpen bool                      `json:"enrollmentOpen"`
+}
+
+// cohortInput adalah body request untuk membuat/memperbarui cohort
+type cohortInput struct {
+	models.Cohort
+	InstructorIDs []uint `json:"instructorIds"`
+}
+
+// toCohortResponses melengkapi cohort dengan instruktur, jumlah peserta dan status pendaftaran
+func toCohortResponses(cohorts []models.Cohort) []CohortResponse {
+	ids := []uint{}
+	for _, ch := range cohorts {
+		ids = append(ids, ch.ID)
+	}
+
+	type enrolledCount struct {
+		CohortID uint
+		Total    int
+	}
+	var counts []enrolledCount
+	if len(ids) > 0 {
+		config.DB.Model(&models.Enrollment{}).
+			Select("cohort_id, COUNT(*) AS total").
+			Where("cohort_id IN ?", ids).
+			Group("cohort_id").
+			Scan(&counts)
+	}
+	enrolled := map[uint]int{}
+	for _, ec := range counts {
+		enrolled[ec.CohortID] = ec.Total
+	}
+
+	now := time.Now()
+	results := []CohortResponse{}
+	for _, ch := range cohorts {
+		response := CohortResponse{
+			Cohort:         ch,
+			Instructors:    []models.CohortInstructor{},
+			EnrolledCount:  enrolled[ch.ID],
+			EnrollmentOpen: ch.EnrollmentOpen(now),
+		}
+		if ch.Capacity > 0 {
+			left := ch.Capacity - enrolled[ch.ID]
+			if left < 0 {
+				left = 0
+			}
+			response.SeatsLeft = &left
+			if left == 0 {
+				response.EnrollmentOpen = false
+			}
+		}
+		for _, u := range ch.Instructors {
+			response.Instructors = append(response.Instructors, models.CohortInstructor{
+				ID:             u.ID,
+				Username:       u.Username,
+				ProfilePicture: u.ProfilePicture,
+			})
+		}
+		results = append(results, response)
+	}
+	return results
+}
+
+// validateCohortInput memastikan path, jadwal pendaftaran, dan instruktur valid
+func validateCohortInput(input *cohortInput) error {
+	var path models.LearningPath
+	if err := config.DB.First(&path, input.LearningPathID).Error; err != nil {
+		return errors.New("learning path tidak ditemukan")
+	}
+	if input.EnrollmentOpensAt != nil && input.EnrollmentClosesAt != nil && input.EnrollmentClosesAt.Before(*input.EnrollmentOpensAt) {
+		return errors.New("penutupan pendaftaran harus setelah pembukaan")
+	}
+
+	var found int64
+	config.DB.Model(&models.User{}).Where("id IN ?", input.InstructorIDs).Count(&found)
+	if int(found) != len(input.InstructorIDs) {
+		return errors.New("instruktur tidak ditemukan")
+	}
+	return nil
+}
+
+// GetPathCohorts - Daftar cohort pada sebuah learning path (Public)
+func GetPathCohorts(c *gin.Context) {
+	var cohorts []models.Cohort
+	config.DB.Preload("Instructors").
+		Where("learning_path_id = ?", c.Param("id")).
+		Order("start_date ASC").
+		Find(&cohorts)
+
+	c.JSON(http.StatusOK, toCohortResponses(cohorts))
+}
+
+// EnrollCohort - User mendaftar ke sebuah cohort (sekaligus ke learning path-nya)
+func EnrollCohort(c *gin.Context) {
+	uid, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var cohort models.Cohort
+	if err := config.DB.First(&cohort, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cohort tidak ditemukan"})
+		return
+	}
+
+	enrollment, err := services.EnrollUser(uid, cohort.LearningPathID, &cohort.ID)
+	if err != nil {
+		respondEnrollmentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Berhasil mendaftar cohort", "data": enrollment})
+}
+
+// GetCohorts - Daftar semua cohort, bisa difilter dengan ?pathId= (Admin)
+func GetCohorts(c *gin.Context) {
+	var cohorts []models.Cohort
+	db := config.DB.Preload("Instructors")
+	if pathID := c.Query("pathId"); pathID != "" {
+		db = db.Where("learning_path_id = ?", pathID)
+	}
+	db.Order("start_date DESC").Find(&cohorts)
+
+	c.JSON(http.StatusOK, toCohortResponses(cohorts))
+}
+
+// GetCohort - Detail cohort beserta daftar peserta (Admin)
+func GetCohort(c *gin.Context) {
+	var cohort models.Cohort
+	if err := config.DB.Preload("Instructors").First(&cohort, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cohort tidak ditemukan"})
+		return
+	}
+
+	type Participant struct {
+		UserID     uint      `json:"userId"`
+		Username   string    `json:"username"`
+		Email      string    `json:"email"`
+		EnrolledAt time.Time `json:"enrolledAt"`
+	}
+
+	var enrollments []models.Enrollment
+	config.DB.Preload("User").Where("cohort_id = ?", cohort.ID).Order("enrolled_at ASC").Find(&enrollments)
+
+	participants := []Participant{}
+	for _, e := range enrollments {
+		participants = append(participants, Participant{
+			UserID:     e.UserID,
+			Username:   e.User.Username,
+			Email:      e.User.Email,
+			EnrolledAt: e.EnrolledAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"cohort":       toCohortResponses([]models.Cohort{cohort})[0],
+		"participants": participants,
+	})
+}
+
+// CreateCohort - Membuat cohort baru (Admin)
+func CreateCohort(c *gin.Context) {
+	var input cohortInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validasi gagal: " + err.Error()})
+		return
+	}
+
+	if err := validateCohortInput(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validasi gagal: " + err.Error()})
+		return
+	}
+
+	cohort := input.Cohort
+	cohort.ID = 0
+	cohort.Instructors = nil
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&cohort).Error; err != nil {
+			return err
+		}
+		return replaceCohortInstructors(tx, &cohort, input.InstructorIDs)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membuat cohort"})
+		return
+	}
+
+	config.DB.Preload("Instructors").First(&cohort, cohort.ID)
+	c.JSON(http.StatusOK, gin.H{"message": "Cohort berhasil dibuat", "data": toCohortResponses([]models.Cohort{cohort})[0]})
+}
+
+// UpdateCohort - Memperbarui cohort (Admin)
+func UpdateCohort(c *gin.Context) {
+	var cohort models.Cohort
+	if err := config.DB.First(&cohort, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cohort tidak ditemukan"})
+		return
+	}
+
+	input := cohortInput{Cohort: cohort}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validasi gagal: " + err.Error()})
+		return
+	}
+
+	if err := validateCohortInput(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validasi gagal: " + err.Error()})
+		return
+	}
+
+	updated := input.Cohort
+	updated.ID = cohort.ID
+	updated.CreatedAt = cohort.CreatedAt
+	updated.Instructors = nil
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&updated).Error; err != nil {
+			return err
+		}
+		return replaceCohortInstructors(tx, &updated, input.InstructorIDs)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui cohort"})
+		return
+	}
+
+	config.DB.Preload("Instructors").First(&updated, updated.ID)
+	c.JSON(http.StatusOK, gin.H{"message": "Cohort diperbarui", "data": toCohortResponses([]models.Cohort{updated})[0]})
+}
+
+// DeleteCohort - Menghapus cohort; enrollment peserta tetap ada tanpa cohort (Admin)
+func DeleteCohort(c *gin.Context) {
+	if err := config.DB.Delete(&models.Cohort{}, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghapus cohort"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Cohort berhasil dihapus"})
+}
+
+// replaceCohortInstructors mengganti daftar instruktur cohort; nil berarti tidak diubah
+func replaceCohortInstructors(tx *gorm.DB, cohort *models.Cohort, instructorIDs []uint) error {
+	if instructorIDs == nil {
+		return nil
+	}
+	instructors := []models.User{}
+	if len(instructorIDs) > 0 {
+		if err := tx.Where("id IN ?", instructorIDs).Find(&instructors).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Model(cohort).Association("Instructors").Replace(instructors)
+}
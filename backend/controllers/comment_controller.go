@@ -135,7 +135,7 @@ func GetCommentsByThread(c *gin.Context) {
 // CreateComment - Create a new comment (auth required)
 func CreateComment(c *gin.Context) {
 	// Get user_id from JWT (set by AuthMiddleware)
-	userID, exists := c.Get("user_id")
+	userID, exists := currentUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
@@ -166,7 +166,7 @@ func CreateComment(c *gin.Context) {
 		AssetID:        input.AssetID,
 		LearningPathID: input.LearningPathID,
 		ThreadID:       input.ThreadID,
-		UserID:         uint(userID.(float64)),
+		UserID:         userID,
 		Content:        input.Content,
 		Rating:         input.Rating,
 		ImageURL:       input.ImageURL,
@@ -202,7 +202,7 @@ func DeleteComment(c *gin.Context) {
 	commentID := c.Param("id")
 
 	// Get user_id from JWT
-	userID, exists := c.Get("user_id")
+	userID, exists := currentUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
@@ -215,7 +215,7 @@ func DeleteComment(c *gin.Context) {
 	}
 
 	// Check if user is the owner
-	if comment.UserID != uint(userID.(float64)) {
+	if comment.UserID != userID {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Anda tidak memiliki izin untuk menghapus komentar ini"})
 		return
 	}
@@ -231,14 +231,7 @@ func DeleteComment(c *gin.Context) {
 // UpdateComment - Update a comment (only owner can update)
 func UpdateComment(c *gin.Context) {
 	commentID := c.Param("id")
-	userIDRaw, _ := c.Get("user_id")
-
-	var userID uint
-	if v, ok := userIDRaw.(float64); ok {
-		userID = uint(v)
-	} else if v, ok := userIDRaw.(uint); ok {
-		userID = v
-	}
+	userID, _ := currentUserID(c)
 
 	var comment models.Comment
 	if err := config.DB.First(&comment, commentID).Error; err != nil {
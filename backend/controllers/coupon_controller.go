@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/imam/backend-blog-kuis/config"
+	"github.com/imam/backend-blog-kuis/models"
+	"github.com/imam/backend-blog-kuis/services"
+)
+
+// validateCoupon - Normalisasi kode dan validasi nilai potongan
+func validateCoupon(coupon *models.Coupon) string {
+	coupon.Code = services.NormalizeCouponCode(coupon.Code)
+	if coupon.Code == "" {
+		return "Kode promo wajib diisi"
+	}
+	if coupon.DiscountType == "percent" && coupon.DiscountValue > 100 {
+		return "Potongan persen maksimal 100"
+	}
+	if coupon.ValidFrom != nil && coupon.ValidUntil != nil && !coupon.ValidUntil.After(*coupon.ValidFrom) {
+		return "validUntil harus setelah validFrom"
+	}
+	return ""
+}
+
+// GetAllCoupons - Daftar kupon beserta jumlah pemakaian (Super Admin)
+func GetAllCoupons(c *gin.Context) {
+	var coupons []models.Coupon
+	config.DB.Order("created_at DESC").Find(&coupons)
+
+	ids := make([]uint, len(coupons))
+	for i, coupon := range coupons {
+		ids[i] = coupon.ID
+	}
+	counts := services.CouponRedemptionCounts(config.DB, ids)
+
+	data := make([]gin.H, len(coupons))
+	for i, coupon := range coupons {
+		data[i] = gin.H{"coupon": coupon, "redemptions": counts[coupon.ID]}
+	}
+	c.JSON(http.StatusOK, data)
+}
+
+// CreateCoupon - Membuat kupon baru (Super Admin)
+func CreateCoupon(c *gin.Context) {
+	var coupon models.Coupon
+	if err := c.ShouldBindJSON(&coupon); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validasi gagal: " + err.Error()})
+		return
+	}
+	coupon.ID = 0
+	if msg := validateCoupon(&coupon); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := config.DB.Create(&coupon).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membuat kupon, pastikan kode belum dipakai"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Kupon berhasil dibuat", "data": coupon})
+}
+
+// UpdateCoupon - Memperbarui kupon; pembayaran yang sudah dibuat tidak berubah (Super Admin)
+func UpdateCoupon(c *gin.Context) {
+	var coupon models.Coupon
+	if err := config.DB.First(&coupon, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Kupon tidak ditemukan"})
+		return
+	}
+
+	id := coupon.ID
+	if err := c.ShouldBindJSON(&coupon); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validasi gagal: " + err.Error()})
+		return
+	}
+	coupon.ID = id
+	if msg := validateCoupon(&coupon); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := config.DB.Save(&coupon).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui kupon"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Kupon diperbarui", "data": coupon})
+}
+
+// DeleteCoupon - Menonaktifkan kupon agar riwayat redemption tetap utuh (Super Admin)
+func DeleteCoupon(c *gin.Context) {
+	result := config.DB.Model(&models.Coupon{}).Where("id = ?", c.Param("id")).Update("active", false)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menonaktifkan kupon"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Kupon tidak ditemukan"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Kupon dinonaktifkan"})
+}
+
+// GetCouponRedemptions - Riwayat pemakaian kupon dengan pagination (Super Admin)
+func GetCouponRedemptions(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset := (page - 1) * limit
+
+	query := config.DB.Model(&models.CouponRedemption{}).Where("coupon_id = ?", c.Param("id"))
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var redemptions []models.CouponRedemption
+	query.Preload("User").Preload("Payment").Order("created_at DESC").Limit(limit).Offset(offset).Find(&redemptions)
+
+	data := make([]gin.H, len(redemptions))
+	for i, r := range redemptions {
+		data[i] = gin.H{
+			"redemption": r,
+			"username":   r.User.Username,
+			"orderId":    r.Payment.OrderID,
+			"amount":     r.Payment.Amount,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  data,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
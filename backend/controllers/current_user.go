@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/imam/backend-blog-kuis/middleware"
+)
+
+// Helper bersama untuk membaca user yang sedang login dari context (diset oleh AuthMiddleware/OptionalAuth).
+// Controller tidak boleh mengambil ID user dari body atau query kecuali untuk override admin.
+
+// currentUserID mengambil ID user yang sedang login dari context (diset oleh AuthMiddleware)
+func currentUserID(c *gin.Context) (uint, bool) {
+	user, ok := middleware.CurrentUser(c)
+	return user.ID, ok
+}
+
+// isAdminRole memeriksa apakah user yang sedang login adalah admin atau super_admin
+func isAdminRole(c *gin.Context) bool {
+	user, _ := middleware.CurrentUser(c)
+	return user.IsAdmin()
+}
+
+// currentSessionID mengembalikan ID sesi dari access token yang sedang dipakai
+func currentSessionID(c *gin.Context) uint {
+	user, _ := middleware.CurrentUser(c)
+	return user.SessionID
+}
+
+// targetUserID mengembalikan user yang datanya dibaca: user yang sedang login, atau ?userId
+// milik user lain hanya jika yang meminta adalah admin (misal untuk halaman admin)
+func targetUserID(c *gin.Context) (uint, bool) {
+	uid, ok := currentUserID(c)
+	if !ok {
+		return 0, false
+	}
+	if requested, err := strconv.ParseUint(c.Query("userId"), 10, 64); err == nil && isAdminRole(c) {
+		return uint(requested), true
+	}
+	return uid, true
+}
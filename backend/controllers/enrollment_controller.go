@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/imam/backend-blog-kuis/config"
+	"github.com/imam/backend-blog-kuis/models"
+	"github.com/imam/backend-blog-kuis/services"
+)
+
+// respondEnrollmentError memetakan error pendaftaran ke status HTTP yang sesuai
+func respondEnrollmentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrPathNotFound), errors.Is(err, services.ErrCohortNotFound), errors.Is(err, services.ErrNotEnrolled):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrPremiumRequired):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrCohortMismatch):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrEnrollmentClosed), errors.Is(err, services.ErrCohortFull):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memproses pendaftaran"})
+	}
+}
+
+// EnrollPath - User mendaftar ke learning path (opsional memilih cohort)
+func EnrollPath(c *gin.Context) {
+	uid, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	pathID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID learning path tidak valid"})
+		return
+	}
+
+	var input struct {
+		CohortID *uint `json:"cohortId"`
+	}
+	c.ShouldBindJSON(&input)
+
+	enrollment, err := services.EnrollUser(uid, uint(pathID), input.CohortID)
+	if err != nil {
+		respondEnrollmentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Berhasil mendaftar learning path", "data": enrollment})
+}
+
+// UnenrollPath - User membatalkan pendaftaran learning path
+func UnenrollPath(c *gin.Context) {
+	uid, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	pathID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID learning path tidak valid"})
+		return
+	}
+
+	if err := services.UnenrollUser(uid, uint(pathID)); err != nil {
+		respondEnrollmentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Pendaftaran learning path dibatalkan"})
+}
+
+// GetMyEnrollments - Daftar learning path yang diikuti user
+func GetMyEnrollments(c *gin.Context) {
+	uid, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	type EnrollmentResponse struct {
+		models.Enrollment
+		LearningPath models.LearningPath `json:"learningPath"`
+	}
+
+	var enrollments []models.Enrollment
+	config.DB.Preload("LearningPath").Preload("Cohort").
+		Where("user_id = ?", uid).
+		Order("enrolled_at DESC").
+		Find(&enrollments)
+
+	results := []EnrollmentResponse{}
+	for _, e := range enrollments {
+		results = append(results, EnrollmentResponse{Enrollment: e, LearningPath: e.LearningPath})
+	}
+
+	c.JSON(http.StatusOK, results)
+}
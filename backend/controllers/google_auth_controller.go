@@ -4,13 +4,11 @@ import (
 	"context"
 	"net/http"
 	"os"
-	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/imam/backend-blog-kuis/models"
 	"github.com/imam/backend-blog-kuis/repository"
-	"github.com/imam/backend-blog-kuis/utils"
+	"github.com/imam/backend-blog-kuis/services"
 	"google.golang.org/api/idtoken"
 )
 
@@ -74,23 +72,19 @@ func GoogleLogin(c *gin.Context) {
 		}
 	}
 
-	// Generate JWT
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id":  user.ID,
-		"username": user.Username,
-		"role":     user.Role,
-		"exp":      time.Now().Add(time.Hour * 24).Unix(),
-	})
-
-	tokenString, err := token.SignedString(utils.GetJWTSecret())
+	// Buat sesi baru (access + refresh token)
+	tokens, err := services.CreateSession(user, sessionClient(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"token":       tokenString,
-		"message":     "Login successful",
-		"is_new_user": isNewUser,
+		"token":              tokens.AccessToken,
+		"refresh_token":      tokens.RefreshToken,
+		"expires_at":         tokens.ExpiresAt,
+		"refresh_expires_at": tokens.RefreshExpiresAt,
+		"message":            "Login successful",
+		"is_new_user":        isNewUser,
 	})
 }
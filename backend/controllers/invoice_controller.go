@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/imam/backend-blog-kuis/config"
+	"github.com/imam/backend-blog-kuis/models"
+	"github.com/imam/backend-blog-kuis/services"
+)
+
+// loadInvoice - Mengambil invoice yang boleh diakses user (pemilik atau admin)
+func loadInvoice(c *gin.Context) (*models.Invoice, bool) {
+	uid, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return nil, false
+	}
+
+	id, _ := strconv.Atoi(c.Param("id"))
+	invoice, err := services.FindInvoice(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invoice tidak ditemukan"})
+		return nil, false
+	}
+	if invoice.UserID != uid && !isAdminRole(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Anda tidak memiliki akses ke invoice ini"})
+		return nil, false
+	}
+	return invoice, true
+}
+
+// GetMyInvoices - Daftar invoice milik user yang sedang login
+func GetMyInvoices(c *gin.Context) {
+	uid, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var invoices []models.Invoice
+	config.DB.Preload("Payment").Where("user_id = ?", uid).Order("issued_at DESC").Find(&invoices)
+	c.JSON(http.StatusOK, invoices)
+}
+
+// GetInvoice - Detail invoice beserta riwayat refund
+func GetInvoice(c *gin.Context) {
+	invoice, ok := loadInvoice(c)
+	if !ok {
+		return
+	}
+
+	var refunds []models.PaymentRefund
+	config.DB.Where("payment_id = ?", invoice.PaymentID).Order("created_at ASC").Find(&refunds)
+	c.JSON(http.StatusOK, gin.H{"invoice": invoice, "refunds": refunds})
+}
+
+// DownloadInvoice - Mengunduh invoice sebagai PDF (bawaan) atau HTML (?format=html)
+func DownloadInvoice(c *gin.Context) {
+	invoice, ok := loadInvoice(c)
+	if !ok {
+		return
+	}
+
+	if c.Query("format") == "html" {
+		html, err := services.RenderInvoiceHTML(*invoice)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membuat invoice"})
+			return
+		}
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(html))
+		return
+	}
+
+	pdf, err := services.RenderInvoicePDF(*invoice)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membuat PDF invoice"})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.pdf"`, invoice.Number))
+	c.Data(http.StatusOK, "application/pdf", pdf)
+}
+
+// GetAllInvoices - Daftar semua invoice dengan pagination, bisa dicari dengan nomor/email (Super Admin)
+func GetAllInvoices(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset := (page - 1) * limit
+
+	query := config.DB.Model(&models.Invoice{})
+	if search := c.Query("search"); search != "" {
+		query = query.Where("number ILIKE ? OR billed_email ILIKE ?", "%"+search+"%", "%"+search+"%")
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var invoices []models.Invoice
+	if err := query.Preload("Payment").Order("issued_at DESC").Limit(limit).Offset(offset).Find(&invoices).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil invoice"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  invoices,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+// ResendInvoice - Mengirim ulang invoice ke email pembeli (Super Admin)
+func ResendInvoice(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	if err := services.SendInvoiceEmail(uint(id)); err != nil {
+		if errors.Is(err, services.ErrInvoiceNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invoice tidak ditemukan"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengirim email: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Invoice berhasil dikirim ulang"})
+}
+
+// RefundPayment - Mengembalikan dana (sebagian/penuh) melalui payment gateway dan mencabut akses premium (Super Admin)
+func RefundPayment(c *gin.Context) {
+	var input struct {
+		Amount     int64  `json:"amount"` // 0 = seluruh sisa dana
+		Reason     string `json:"reason" binding:"required"`
+		KeepAccess bool   `json:"keepAccess"` // Hanya berlaku untuk refund sebagian
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validasi gagal: " + err.Error()})
+		return
+	}
+
+	adminID, _ := currentUserID(c)
+	id, _ := strconv.Atoi(c.Param("id"))
+	payment, refund, err := services.RefundPayment(uint(id), services.RefundRequest{
+		Amount:     input.Amount,
+		Reason:     input.Reason,
+		AdminID:    adminID,
+		KeepAccess: input.KeepAccess,
+	})
+	switch {
+	case errors.Is(err, services.ErrPaymentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pembayaran tidak ditemukan"})
+	case errors.Is(err, services.ErrRefundNotAllowed),
+		errors.Is(err, services.ErrRefundAmountInvalid),
+		errors.Is(err, services.ErrRefundGateway):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrGatewayUnavailable):
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memproses refund: " + err.Error()})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Refund berhasil diproses", "data": gin.H{"payment": payment, "refund": refund}})
+	}
+}
+
+// GetPaymentRefunds - Riwayat refund sebuah pembayaran (Super Admin)
+func GetPaymentRefunds(c *gin.Context) {
+	var refunds []models.PaymentRefund
+	config.DB.Preload("Admin").Where("payment_id = ?", c.Param("id")).Order("created_at ASC").Find(&refunds)
+	c.JSON(http.StatusOK, refunds)
+}
@@ -21,15 +21,12 @@ func ToggleLike(c *gin.Context) {
 	}
 
 	// Get user_id from middleware context
-	userIDRaw, exists := c.Get("user_id")
+	userID, exists := currentUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
 
-	// Convert to uint (jwt-decode might give float64)
-	userID := uint(userIDRaw.(float64))
-
 	var existingLike models.Like
 	result := config.DB.Where("user_id = ? AND target_id = ? AND target_type = ?", userID, input.TargetID, input.TargetType).First(&existingLike)
 
@@ -57,12 +54,11 @@ func GetLikeStatus(c *gin.Context) {
 	targetID := c.Query("targetId")
 	targetType := c.Query("targetType")
 
-	userIDRaw, exists := c.Get("user_id")
+	userID, exists := currentUserID(c)
 	if !exists {
 		c.JSON(http.StatusOK, gin.H{"liked": false})
 		return
 	}
-	userID := uint(userIDRaw.(float64))
 
 	var count int64
 	config.DB.Model(&models.Like{}).Where("user_id = ? AND target_id = ? AND target_type = ?", userID, targetID, targetType).Count(&count)
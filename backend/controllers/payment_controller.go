@@ -1,43 +1,87 @@
 package controllers
 
 import (
+	"encoding/csv"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
-	"os"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/imam/backend-blog-kuis/config"
 	"github.com/imam/backend-blog-kuis/models"
-	"github.com/midtrans/midtrans-go"
-	"github.com/midtrans/midtrans-go/coreapi"
-	"github.com/midtrans/midtrans-go/snap"
+	"github.com/imam/backend-blog-kuis/services"
+	"gorm.io/gorm"
 )
 
-var c coreapi.Client
-var s snap.Client
+// checkoutInput adalah body request checkout dan quote
+type checkoutInput struct {
+	PlanID       uint   `json:"planId"`
+	Plan         string `json:"plan"` // Kode paket (pro_monthly), untuk klien lama
+	CouponCode   string `json:"couponCode"`
+	ReferralCode string `json:"referralCode"`
+	UseCredit    bool   `json:"useCredit"`
+}
 
-func InitMidtrans() {
-	serverKey := os.Getenv("MIDTRANS_SERVER_KEY")
-	env := midtrans.Sandbox
-	if os.Getenv("MIDTRANS_IS_PRODUCTION") == "true" {
-		env = midtrans.Production
+func (in checkoutInput) options() services.CheckoutOptions {
+	return services.CheckoutOptions{
+		CouponCode:   in.CouponCode,
+		ReferralCode: in.ReferralCode,
+		UseCredit:    in.UseCredit,
 	}
+}
 
-	c.New(serverKey, env)
-	s.New(serverKey, env)
+// respondCheckoutError memetakan error kupon/referral ke 400, selain itu 500
+func respondCheckoutError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrCouponNotFound),
+		errors.Is(err, services.ErrCouponNotActive),
+		errors.Is(err, services.ErrCouponExhausted),
+		errors.Is(err, services.ErrCouponUserLimit),
+		errors.Is(err, services.ErrCouponPlanNotAllowed),
+		errors.Is(err, services.ErrReferralNotFound),
+		errors.Is(err, services.ErrReferralSelf),
+		errors.Is(err, services.ErrReferralUsed):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
 }
 
-// CreateTransaction membuat transaksi baru di Midtrans
-func CreateTransaction(ctx *gin.Context) {
-	// Pastikan InitMidtrans dipanggil (bisa juga di main.go)
-	InitMidtrans()
+// QuoteTransaction menghitung rincian harga (kupon dan kredit) tanpa membuat transaksi
+func QuoteTransaction(ctx *gin.Context) {
+	var input checkoutInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	var input struct {
-		Amount int64  `json:"amount" binding:"required"`
-		Plan   string `json:"plan"` // Pro Monthly, Pro Annual, etc.
+	uid, ok := currentUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	plan, err := services.FindPlan(input.PlanID, input.Plan)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	quote, err := services.QuoteCheckout(uid, plan, input.options())
+	if err != nil {
+		respondCheckoutError(ctx, err)
+		return
 	}
+	ctx.JSON(http.StatusOK, gin.H{"data": quote})
+}
+
+// CreateTransaction membuat transaksi baru di payment gateway untuk paket yang dipilih
+func CreateTransaction(ctx *gin.Context) {
+	// Nominal diambil dari katalog paket dan kupon/kredit, bukan dari klien
+	var input checkoutInput
 
 	if err := ctx.ShouldBindJSON(&input); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -45,125 +89,169 @@ func CreateTransaction(ctx *gin.Context) {
 	}
 
 	// Ambil user dari context (setelah middleware auth)
-	userID, exists := ctx.Get("userID")
+	uid, exists := currentUserID(ctx)
 	if !exists {
 		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	// Konversi userID ke uint (asumsi userID di context float64 dari JWT)
-	uid := uint(userID.(float64))
-
 	var user models.User
 	if err := config.DB.First(&user, uid).Error; err != nil {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
-	// Buat Order ID unik
-	orderID := fmt.Sprintf("ORDER-%d-%d", uid, time.Now().Unix())
-
-	// Buat request snap
-	req := &snap.Request{
-		TransactionDetails: midtrans.TransactionDetails{
-			OrderID:  orderID,
-			GrossAmt: input.Amount,
-		},
-		CreditCard: &snap.CreditCardDetails{
-			Secure: true,
-		},
-		CustomerDetail: &midtrans.CustomerDetails{
-			FName: user.Username,
-			Email: user.Email,
-			Phone: user.Phone,
-		},
-		EnabledPayments: snap.AllSnapPaymentType,
+	plan, err := services.FindPlan(input.PlanID, input.Plan)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	// Request ke Midtrans Snap API
-	snapResp, err := s.CreateTransaction(req)
+	result, err := services.Checkout(user, plan, input.options())
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Midtrans Error: " + err.Message})
+		respondCheckoutError(ctx, err)
 		return
 	}
 
-	// Simpan transaksi ke database
-	payment := models.Payment{
-		UserID:        uid,
-		OrderID:       orderID,
-		Amount:        input.Amount,
-		Status:        "pending",
-		PaymentType:   input.Plan,
-		SnapURL:       snapResp.RedirectURL,
-		TransactionID: snapResp.Token,
+	response := gin.H{
+		"order_id": result.Payment.OrderID,
+		"status":   result.Payment.Status,
+		"quote":    result.Quote,
+	}
+	// Pembayaran yang lunas dengan kupon/kredit tidak punya halaman pembayaran
+	if result.Session != nil {
+		response["token"] = result.Session.Token
+		response["redirect_url"] = result.Session.RedirectURL
 	}
+	ctx.JSON(http.StatusOK, response)
+}
 
-	if err := config.DB.Create(&payment).Error; err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save payment"})
+// HandleNotification menangani webhook dari payment gateway
+func HandleNotification(ctx *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(ctx.Request.Body, 1<<20))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid body"})
 		return
 	}
+	respondNotification(ctx, body)
+}
 
-	ctx.JSON(http.StatusOK, gin.H{
-		"token":        snapResp.Token,
-		"redirect_url": snapResp.RedirectURL,
-		"order_id":     orderID,
-	})
+// respondNotification memproses notifikasi dan memetakan hasilnya ke status HTTP.
+// Status non-2xx membuat gateway mengirim ulang notifikasi nanti.
+func respondNotification(ctx *gin.Context, body []byte) {
+	changed, err := services.ProcessNotification(body, ctx.ClientIP())
+	switch {
+	case errors.Is(err, services.ErrInvalidNotification):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notification"})
+	case errors.Is(err, services.ErrInvalidSignature):
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Invalid signature"})
+	case errors.Is(err, services.ErrPaymentNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+	case errors.Is(err, services.ErrPaymentAmountMismatch):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Amount mismatch"})
+	case errors.Is(err, services.ErrGatewayUnavailable):
+		ctx.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process notification"})
+	case !changed:
+		ctx.JSON(http.StatusOK, gin.H{"message": "Notification already processed"})
+	default:
+		ctx.JSON(http.StatusOK, gin.H{"message": "Notification processed"})
+	}
 }
 
-// HandleNotification menangani webhook dari Midtrans
-func HandleNotification(ctx *gin.Context) {
-	InitMidtrans()
+// fakeGateway mengembalikan fake gateway jika sedang aktif (PAYMENT_GATEWAY=fake)
+func fakeGateway(ctx *gin.Context) (*services.FakeGateway, bool) {
+	fake, ok := services.Gateway().(*services.FakeGateway)
+	if !ok {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Fake gateway is not enabled"})
+	}
+	return fake, ok
+}
 
-	var notificationPayload map[string]interface{}
-	if err := ctx.ShouldBindJSON(&notificationPayload); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
+// GetFakePayment menampilkan status transaksi di fake gateway (halaman "pembayaran" lokal)
+func GetFakePayment(ctx *gin.Context) {
+	fake, ok := fakeGateway(ctx)
+	if !ok {
+		return
+	}
+	status, err := fake.CheckStatus(ctx.Param("orderId"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"order_id": status.OrderID,
+		"status":   status.Status,
+		"amount":   status.GrossAmount,
+		"events":   []string{"settlement", "pending", "deny", "cancel", "expire"},
+	})
+}
 
-	orderID, exists := notificationPayload["order_id"].(string)
-	if !exists {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Order ID missing"})
+// SimulateFakePayment mensimulasikan event pembayaran dan langsung memproses notifikasinya
+func SimulateFakePayment(ctx *gin.Context) {
+	fake, ok := fakeGateway(ctx)
+	if !ok {
 		return
 	}
 
-	transactionStatus, _ := notificationPayload["transaction_status"].(string)
-	fraudStatus, _ := notificationPayload["fraud_status"].(string)
+	var input struct {
+		Event string `json:"event" binding:"required"`
+	}
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
+	// Hanya pemilik order (atau admin) yang boleh mensimulasikan pembayarannya
+	uid, _ := currentUserID(ctx)
 	var payment models.Payment
-	if err := config.DB.Where("order_id = ?", orderID).First(&payment).Error; err != nil {
+	if err := config.DB.Where("order_id = ?", ctx.Param("orderId")).First(&payment).Error; err != nil {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
 		return
 	}
+	if payment.UserID != uid && !isAdminRole(ctx) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return
+	}
 
-	// Update status berdasarkan response Midtrans
-	if transactionStatus == "capture" {
-		if fraudStatus == "challenge" {
-			payment.Status = "challenge"
-		} else if fraudStatus == "accept" {
-			payment.Status = "success"
-		}
-	} else if transactionStatus == "settlement" {
-		payment.Status = "success"
-	} else if transactionStatus == "deny" {
-		payment.Status = "deny"
-	} else if transactionStatus == "cancel" || transactionStatus == "expire" {
-		payment.Status = "failure"
-	} else if transactionStatus == "pending" {
-		payment.Status = "pending"
-	}
-
-	// Simpan perubahan status
-	config.DB.Save(&payment)
-
-	// Jika sukses, update role user jadi 'pro' atau tambahkan masa aktif langganan (opsional)
-	if payment.Status == "success" {
-		// Update user role to 'pro'
-		if err := config.DB.Model(&models.User{}).Where("id = ?", payment.UserID).Update("role", "pro").Error; err != nil {
-			fmt.Printf("Failed to update user role: %v\n", err)
-		}
+	body, err := fake.Simulate(payment.OrderID, input.Event)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	respondNotification(ctx, body)
+}
+
+// GetPaymentNotifications mengembalikan log notifikasi payment gateway dengan pagination (bisa difilter ?orderId=)
+func GetPaymentNotifications(ctx *gin.Context) {
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	offset := (page - 1) * limit
+
+	query := config.DB.Model(&models.PaymentNotification{})
+	if orderID := ctx.Query("orderId"); orderID != "" {
+		query = query.Where("order_id = ?", orderID)
+	}
+	if result := ctx.Query("result"); result != "" {
+		query = query.Where("result = ?", result)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var notifications []models.PaymentNotification
+	if err := query.Order("created_at desc").Limit(limit).Offset(offset).Find(&notifications).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"message": "Notification processed"})
+	ctx.JSON(http.StatusOK, gin.H{
+		"data":  notifications,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
 }
 
 // GetPaymentStats mengembalikan statistik ringkasan pembayaran untuk dashboard admin
@@ -173,8 +261,10 @@ func GetPaymentStats(ctx *gin.Context) {
 	var pendingPayments int64
 	var successTransactions int64
 
-	// Hitung Total Revenue (hanya transaksi sukses)
-	config.DB.Model(&models.Payment{}).Where("status = ?", "success").Select("COALESCE(SUM(amount), 0)").Scan(&totalRevenue)
+	// Hitung Total Revenue bersih (transaksi lunas dikurangi refund)
+	config.DB.Model(&models.Payment{}).
+		Where("status IN ?", []string{services.PaymentSuccess, services.PaymentPartialRefund, services.PaymentRefund}).
+		Select("COALESCE(SUM(amount - refunded_amount), 0)").Scan(&totalRevenue)
 
 	// Hitung Transaksi Sukses
 	config.DB.Model(&models.Payment{}).Where("status = ?", "success").Count(&successTransactions)
@@ -182,9 +272,11 @@ func GetPaymentStats(ctx *gin.Context) {
 	// Hitung Pending Payments
 	config.DB.Model(&models.Payment{}).Where("status = ?", "pending").Count(&pendingPayments)
 
-	// Hitung Active Subs (Asumsi setiap user dengan transaksi sukses dihitung sebagai active sub sementara)
-	// Idealnya ada tabel subscription terpisah atau field di user
-	config.DB.Model(&models.Payment{}).Where("status = ?", "success").Distinct("user_id").Count(&activeSubs)
+	// Hitung Active Subs (user dengan langganan yang sedang berlaku)
+	now := time.Now()
+	config.DB.Model(&models.Subscription{}).
+		Where("status = ? AND starts_at <= ? AND ends_at > ?", services.SubscriptionActive, now, now).
+		Distinct("user_id").Count(&activeSubs)
 
 	ctx.JSON(http.StatusOK, gin.H{
 		"total_revenue":        totalRevenue,
@@ -194,14 +286,8 @@ func GetPaymentStats(ctx *gin.Context) {
 	})
 }
 
-// GetAllPayments mengembalikan daftar semua pembayaran dengan pagination dan filter
-func GetAllPayments(ctx *gin.Context) {
-	var payments []models.Payment
-	var total int64
-
-	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
-	offset := (page - 1) * limit
+// paymentListQuery menerapkan filter daftar pembayaran (search, status, from, to) yang dipakai daftar dan export CSV
+func paymentListQuery(ctx *gin.Context) (*gorm.DB, error) {
 	search := ctx.Query("search")
 	status := ctx.Query("status")
 
@@ -216,6 +302,32 @@ func GetAllPayments(ctx *gin.Context) {
 		query = query.Where("payments.status = ?", status)
 	}
 
+	// Rentang tanggal pembuatan transaksi (YYYY-MM-DD, inklusif)
+	if ctx.Query("from") != "" || ctx.Query("to") != "" {
+		dateRange, err := services.ParseRevenueRange(ctx.Query("from"), ctx.Query("to"))
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("payments.created_at >= ? AND payments.created_at < ?", dateRange.From, dateRange.To)
+	}
+	return query, nil
+}
+
+// GetAllPayments mengembalikan daftar semua pembayaran dengan pagination dan filter
+func GetAllPayments(ctx *gin.Context) {
+	var payments []models.Payment
+	var total int64
+
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	offset := (page - 1) * limit
+
+	query, err := paymentListQuery(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	query.Count(&total)
 
 	if err := query.Order("created_at desc").Limit(limit).Offset(offset).Find(&payments).Error; err != nil {
@@ -230,3 +342,60 @@ func GetAllPayments(ctx *gin.Context) {
 		"limit": limit,
 	})
 }
+
+// ExportPayments mengekspor hasil filter GetAllPayments (tanpa pagination) sebagai CSV untuk akuntansi
+func ExportPayments(ctx *gin.Context) {
+	query, err := paymentListQuery(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var payments []models.Payment
+	if err := query.Order("payments.created_at asc").Find(&payments).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payments"})
+		return
+	}
+
+	// Nomor invoice hanya diambil untuk pembayaran yang diekspor, per batch agar parameter query tidak berlebihan
+	invoiceNumbers := map[uint]string{}
+	for start := 0; start < len(payments); start += 1000 {
+		end := start + 1000
+		if end > len(payments) {
+			end = len(payments)
+		}
+		paymentIDs := make([]uint, 0, end-start)
+		for _, p := range payments[start:end] {
+			paymentIDs = append(paymentIDs, p.ID)
+		}
+		var invoices []models.Invoice
+		config.DB.Select("payment_id", "number").Where("payment_id IN ?", paymentIDs).Find(&invoices)
+		for _, invoice := range invoices {
+			invoiceNumbers[invoice.PaymentID] = invoice.Number
+		}
+	}
+
+	ctx.Header("Content-Type", "text/csv; charset=utf-8")
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="payments-%s.csv"`, time.Now().Format("20060102")))
+
+	w := csv.NewWriter(ctx.Writer)
+	w.Write([]string{
+		"order_id", "invoice_number", "created_at", "paid_at", "status", "username", "email",
+		"payment_type", "payment_method", "gateway", "coupon_code", "original_amount", "discount_amount",
+		"credit_used", "amount", "refunded_amount", "net_amount",
+	})
+	for _, p := range payments {
+		paidAt := ""
+		if p.PaidAt != nil {
+			paidAt = p.PaidAt.Format(time.RFC3339)
+		}
+		w.Write([]string{
+			p.OrderID, invoiceNumbers[p.ID], p.CreatedAt.Format(time.RFC3339), paidAt, p.Status, p.User.Username, p.User.Email,
+			p.PaymentType, p.PaymentMethod, p.Gateway, p.CouponCode,
+			strconv.FormatInt(p.OriginalAmount, 10), strconv.FormatInt(p.DiscountAmount, 10),
+			strconv.FormatInt(p.CreditUsed, 10), strconv.FormatInt(p.Amount, 10),
+			strconv.FormatInt(p.RefundedAmount, 10), strconv.FormatInt(p.Amount-p.RefundedAmount, 10),
+		})
+	}
+	w.Flush()
+}
@@ -0,0 +1,159 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/imam/backend-blog-kuis/config"
+	"github.com/imam/backend-blog-kuis/models"
+	"github.com/imam/backend-blog-kuis/services"
+)
+
+// GetMyPeerReviews - Daftar submission peserta lain yang ditugaskan untuk dinilai oleh user
+func GetMyPeerReviews(c *gin.Context) {
+	uid, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	lessonID := c.Query("lessonId")
+
+	// Lengkapi kuota penugasan jika sebelumnya belum ada cukup submission lain untuk dinilai
+	if lessonID != "" {
+		var quiz models.Quiz
+		var submitted int64
+		config.DB.Model(&models.UserProgress{}).
+			Where("user_id = ? AND quiz_id = ? AND (submission_file_url != '' OR submission_drive_link != '')", uid, lessonID).
+			Count(&submitted)
+		if submitted > 0 && config.DB.First(&quiz, lessonID).Error == nil {
+			services.AssignPeerReviews(uid, quiz)
+		}
+	}
+
+	type PeerReviewResponse struct {
+		models.PeerReview
+		LessonTitle         string                   `json:"lessonTitle"`
+		SubmissionFileURL   string                   `json:"submissionFileUrl"`
+		SubmissionDriveLink string                   `json:"submissionDriveLink"`
+		Rubric              []models.RubricCriterion `json:"rubric"`
+	}
+
+	var reviews []models.PeerReview
+	db := config.DB.Preload("Progress").Preload("Progress.Quiz").Where("reviewer_id = ?", uid)
+	if lessonID != "" {
+		db = db.Where("quiz_id = ?", lessonID)
+	}
+	db.Order("created_at DESC").Find(&reviews)
+
+	// Identitas pemilik submission tidak ditampilkan agar penilaian tetap anonim
+	results := []PeerReviewResponse{}
+	for _, r := range reviews {
+		response := PeerReviewResponse{
+			PeerReview:          r,
+			LessonTitle:         r.Progress.Quiz.Title,
+			SubmissionFileURL:   r.Progress.SubmissionFileURL,
+			SubmissionDriveLink: r.Progress.SubmissionDriveLink,
+		}
+		if r.Progress.Quiz.PeerReview != nil {
+			response.Rubric = r.Progress.Quiz.PeerReview.Rubric
+		}
+		results = append(results, response)
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+// SubmitPeerReview - Mengirim nilai rubrik untuk submission yang ditugaskan
+func SubmitPeerReview(c *gin.Context) {
+	uid, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var input struct {
+		Scores  []models.RubricScore `json:"scores" binding:"required"`
+		Comment string               `json:"comment"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var review models.PeerReview
+	if err := config.DB.Preload("Progress").Preload("Progress.Quiz").
+		Where("id = ? AND reviewer_id = ?", c.Param("id"), uid).First(&review).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Penugasan review tidak ditemukan"})
+		return
+	}
+
+	if review.Status == "completed" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Review sudah dikirim"})
+		return
+	}
+
+	cfg := review.Progress.Quiz.PeerReview
+	if cfg == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Peer review tidak aktif untuk modul ini"})
+		return
+	}
+
+	// Setiap kriteria rubrik wajib dinilai dalam rentang 0..MaxScore
+	scores := map[string]int{}
+	for _, s := range input.Scores {
+		scores[s.CriterionID] = s.Score
+	}
+
+	review.Scores = nil
+	review.TotalScore = 0
+	review.MaxScore = 0
+	for _, criterion := range cfg.Rubric {
+		score, exists := scores[criterion.ID]
+		if !exists || score < 0 || score > criterion.MaxScore {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Nilai untuk kriteria '" + criterion.Title + "' tidak valid"})
+			return
+		}
+		review.Scores = append(review.Scores, models.RubricScore{CriterionID: criterion.ID, Score: score})
+		review.TotalScore += score
+		review.MaxScore += criterion.MaxScore
+	}
+
+	now := time.Now()
+	review.Status = "completed"
+	review.Comment = input.Comment
+	review.CompletedAt = &now
+
+	if err := config.DB.Model(&review).Select("Status", "Scores", "TotalScore", "MaxScore", "Comment", "CompletedAt").
+		Updates(&review).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan review"})
+		return
+	}
+
+	services.AggregatePeerReviews(review.ProgressID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Review berhasil dikirim", "data": review})
+}
+
+// GetSubmissionPeerReviews - Daftar peer review untuk satu submission (Admin)
+func GetSubmissionPeerReviews(c *gin.Context) {
+	type ReviewDetail struct {
+		models.PeerReview
+		ReviewerName string `json:"reviewerName"`
+	}
+
+	var reviews []models.PeerReview
+	if err := config.DB.Preload("Reviewer").Where("progress_id = ?", c.Param("id")).
+		Order("created_at ASC").Find(&reviews).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil peer review"})
+		return
+	}
+
+	results := []ReviewDetail{}
+	for _, r := range reviews {
+		results = append(results, ReviewDetail{PeerReview: r, ReviewerName: r.Reviewer.Username})
+	}
+
+	c.JSON(http.StatusOK, results)
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/imam/backend-blog-kuis/config"
+	"github.com/imam/backend-blog-kuis/models"
+	"github.com/imam/backend-blog-kuis/services"
+)
+
+// GetPlans - Daftar paket langganan yang aktif (Public)
+func GetPlans(c *gin.Context) {
+	var plans []models.Plan
+	config.DB.Where("active = ?", true).Order("sort_order ASC, price ASC").Find(&plans)
+	c.JSON(http.StatusOK, plans)
+}
+
+// GetAllPlans - Daftar semua paket termasuk yang nonaktif (Super Admin)
+func GetAllPlans(c *gin.Context) {
+	var plans []models.Plan
+	config.DB.Order("sort_order ASC, price ASC").Find(&plans)
+	c.JSON(http.StatusOK, plans)
+}
+
+// CreatePlan - Membuat paket langganan baru (Super Admin)
+func CreatePlan(c *gin.Context) {
+	var plan models.Plan
+	if err := c.ShouldBindJSON(&plan); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validasi gagal: " + err.Error()})
+		return
+	}
+	plan.ID = 0
+
+	if err := config.DB.Create(&plan).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membuat paket, pastikan kode belum dipakai"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Paket berhasil dibuat", "data": plan})
+}
+
+// UpdatePlan - Memperbarui paket langganan; langganan yang sudah berjalan tidak berubah (Super Admin)
+func UpdatePlan(c *gin.Context) {
+	var plan models.Plan
+	if err := config.DB.First(&plan, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Paket tidak ditemukan"})
+		return
+	}
+
+	id := plan.ID
+	if err := c.ShouldBindJSON(&plan); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validasi gagal: " + err.Error()})
+		return
+	}
+	plan.ID = id
+
+	if err := config.DB.Save(&plan).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui paket"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Paket diperbarui", "data": plan})
+}
+
+// DeletePlan - Menonaktifkan paket agar riwayat pembayaran dan langganan tetap utuh (Super Admin)
+func DeletePlan(c *gin.Context) {
+	result := config.DB.Model(&models.Plan{}).Where("id = ?", c.Param("id")).Update("active", false)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menonaktifkan paket"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Paket tidak ditemukan"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Paket dinonaktifkan"})
+}
+
+// GetMySubscription - Langganan aktif dan riwayat langganan user yang sedang login
+func GetMySubscription(c *gin.Context) {
+	uid, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var history []models.Subscription
+	config.DB.Preload("Plan").Where("user_id = ?", uid).Order("starts_at DESC").Find(&history)
+
+	active := services.ActiveSubscription(uid)
+	var daysLeft int
+	if active != nil {
+		daysLeft = int(time.Until(active.EndsAt).Hours() / 24)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"active":   active,
+		"daysLeft": daysLeft,
+		"history":  history,
+	})
+}
@@ -1,23 +1,31 @@
 package controllers
 
 import (
-	"crypto/rand"
-	"encoding/hex"
-	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/imam/backend-blog-kuis/config"
 	"github.com/imam/backend-blog-kuis/models"
+	"github.com/imam/backend-blog-kuis/services"
 )
 
+// QuizCooldownMinutes adalah cooldown bawaan setelah gagal kuis, bisa diganti per kuis lewat Quiz.CooldownMinutes
 const QuizCooldownMinutes = 3
 
+// QuizPassingScore adalah skor minimum (persen) agar sebuah kuis dianggap lulus
+const QuizPassingScore = 80
+
 // CompleteLesson - Mark a lesson/module as completed
 func CompleteLesson(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
 	var input struct {
-		UserID              uint   `json:"userId" binding:"required"`
 		QuizID              uint   `json:"lessonId" binding:"required"`
 		SubmissionFileURL   string `json:"submissionFileUrl"`
 		SubmissionDriveLink string `json:"submissionDriveLink"`
@@ -30,20 +38,55 @@ func CompleteLesson(c *gin.Context) {
 
 	// Determine initial approval status for project types
 	var quizInfo models.Quiz
-	config.DB.First(&quizInfo, input.QuizID)
+	if err := config.DB.First(&quizInfo, input.QuizID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Materi tidak ditemukan"})
+		return
+	}
+
+	// Materi premium hanya bisa diselesaikan oleh user yang berlangganan
+	if !checkAccess(c, strconv.FormatUint(uint64(input.QuizID), 10)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Konten Premium. Silakan upgrade ke PRO."})
+		return
+	}
+
+	// Materi yang masih terkunci (prasyarat / mode berurutan) belum boleh diselesaikan
+	if rejectIfLocked(c, userID, quizInfo) {
+		return
+	}
+
+	// Kuis hanya bisa diselesaikan setelah lulus penilaian di server (POST /quizzes/:id/attempts)
+	if quizInfo.Type == "quiz" && !hasPassedAttempt(userID, input.QuizID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Kuis belum lulus. Kirim jawaban melalui endpoint attempts."})
+		return
+	}
+
+	// File submission harus diunggah oleh user ini sendiri
+	if input.SubmissionFileURL != "" && !services.OwnsUpload(userID, input.SubmissionFileURL) {
+		c.JSON(http.StatusForbidden, gin.H{"error": services.ErrUploadNotOwned.Error()})
+		return
+	}
+
 	approvalStatus := ""
 	if quizInfo.Type == "project" && (input.SubmissionFileURL != "" || input.SubmissionDriveLink != "") {
 		approvalStatus = "pending"
 	}
 
+	// Memulai materi otomatis mendaftarkan user ke learning path-nya
+	if _, found := services.FindEnrollment(userID, quizInfo.PathID); !found {
+		if _, err := services.EnrollUser(userID, quizInfo.PathID, nil); err != nil {
+			respondEnrollmentError(c, err)
+			return
+		}
+	}
+
 	// Build the progress struct for lookup
 	progress := models.UserProgress{
-		UserID: input.UserID,
+		UserID: userID,
 		QuizID: input.QuizID,
 	}
 
 	// Find or create the record (without Assign so FirstOrCreate works reliably)
-	if err := config.DB.Where("user_id = ? AND quiz_id = ?", input.UserID, input.QuizID).
+	if err := config.DB.Where("user_id = ? AND quiz_id = ?", userID, input.QuizID).
 		FirstOrCreate(&progress).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan progres"})
 		return
@@ -59,9 +102,15 @@ func CompleteLesson(c *gin.Context) {
 	if input.SubmissionDriveLink != "" {
 		updateFields["submission_drive_link"] = input.SubmissionDriveLink
 	}
+	isResubmission := progress.SubmissionFileURL != "" || progress.SubmissionDriveLink != ""
+	submissionStatus := progress.ApprovalStatus
 	if approvalStatus != "" && progress.ApprovalStatus != "approved" {
 		// Don't override an already-approved status
 		updateFields["approval_status"] = approvalStatus
+		updateFields["approval_overridden"] = false
+		// Catatan penolakan sebelumnya tetap tersimpan di riwayat Submission
+		updateFields["admin_note"] = ""
+		submissionStatus = approvalStatus
 	}
 
 	if err := config.DB.Model(&progress).Updates(updateFields).Error; err != nil {
@@ -69,59 +118,59 @@ func CompleteLesson(c *gin.Context) {
 		return
 	}
 
-	// CHECK FOR CERTIFICATE ISSUANCE
-	var quiz models.Quiz
-	if err := config.DB.First(&quiz, input.QuizID).Error; err != nil {
-		c.JSON(http.StatusOK, gin.H{"message": "Progres disimpan", "data": progress})
-		return
+	// Simpan setiap pengiriman sebagai versi baru agar riwayat tidak tertimpa
+	if approvalStatus != "" {
+		fileURL, driveLink := input.SubmissionFileURL, input.SubmissionDriveLink
+		if fileURL == "" {
+			fileURL = progress.SubmissionFileURL
+		}
+		if driveLink == "" {
+			driveLink = progress.SubmissionDriveLink
+		}
+		if _, err := services.RecordSubmission(progress, fileURL, driveLink, submissionStatus); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan riwayat submission"})
+			return
+		}
 	}
 
-	var totalQuizzes int64
-	config.DB.Model(&models.Quiz{}).Where("path_id = ?", quiz.PathID).Count(&totalQuizzes)
+	// Submission ZIP pada project dengan spec penilaian dinilai otomatis oleh worker
+	if quizInfo.Type == "project" && quizInfo.GradingSpec != nil && input.SubmissionFileURL != "" {
+		services.EnqueueGrading(progress.ID)
+	}
 
-	var completedQuizzes int64
-	config.DB.Model(&models.UserProgress{}).
-		Joins("JOIN quizzes ON quizzes.id = user_progresses.quiz_id").
-		Where("user_progresses.user_id = ? AND quizzes.path_id = ? AND user_progresses.completed = true", input.UserID, quiz.PathID).
-		Count(&completedQuizzes)
-
-	isComplete := totalQuizzes > 0 && completedQuizzes >= totalQuizzes
-
-	if isComplete {
-		var pendingProjects int64
-		config.DB.Model(&models.UserProgress{}).
-			Joins("JOIN quizzes ON quizzes.id = user_progress.quiz_id").
-			Where("user_progress.user_id = ? AND quizzes.path_id = ? AND quizzes.type = 'project' AND user_progress.approval_status != 'approved'", input.UserID, quiz.PathID).
-			Count(&pendingProjects)
-
-		if pendingProjects == 0 {
-			var certExists int64
-			config.DB.Model(&models.Certificate{}).Where("user_id = ? AND learning_path_id = ?", input.UserID, quiz.PathID).Count(&certExists)
-			if certExists == 0 {
-				b := make([]byte, 4)
-				rand.Read(b)
-				certID := fmt.Sprintf("AIOT-%d-%X", quiz.PathID, hex.EncodeToString(b))
-				config.DB.Create(&models.Certificate{
-					UserID:         input.UserID,
-					LearningPathID: quiz.PathID,
-					CertificateID:  certID,
-					IssuedAt:       time.Now(),
-				})
-			}
+	// Peer review: versi lama tidak dinilai lagi, lalu peserta mendapat submission lain untuk dinilai
+	if approvalStatus != "" && quizInfo.PeerReview != nil {
+		if isResubmission {
+			services.ResetPeerReviews(progress.ID)
 		}
+		services.AssignPeerReviews(userID, quizInfo)
 	}
 
+	// CHECK FOR CERTIFICATE ISSUANCE
+	if quizInfo.ID == 0 {
+		c.JSON(http.StatusOK, gin.H{"message": "Progres disimpan", "data": progress})
+		return
+	}
+
+	isComplete := services.CheckPathCompletion(userID, quizInfo.PathID)
+
 	c.JSON(http.StatusOK, gin.H{
 		"message":    "Progres disimpan",
 		"data":       progress,
 		"isComplete": isComplete,
+		"progress":   services.GetPathProgress(userID, quizInfo.PathID),
 	})
 }
 
 // RecordQuizFailed - Records a quiz failure timestamp for cooldown enforcement
 func RecordQuizFailed(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
 	var input struct {
-		UserID uint `json:"userId" binding:"required"`
 		QuizID uint `json:"lessonId" binding:"required"`
 	}
 
@@ -130,28 +179,61 @@ func RecordQuizFailed(c *gin.Context) {
 		return
 	}
 
+	var quiz models.Quiz
+	if err := config.DB.First(&quiz, input.QuizID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Kuis tidak ditemukan"})
+		return
+	}
+
+	cooldownEnd := recordQuizFailure(userID, quiz)
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Cooldown dimulai",
+		"cooldownEnd": cooldownEnd.Unix(),
+	})
+}
+
+// quizCooldown mengembalikan lama cooldown setelah gagal kuis (per kuis atau bawaan)
+func quizCooldown(quiz models.Quiz) time.Duration {
+	if quiz.CooldownMinutes != nil {
+		return time.Duration(*quiz.CooldownMinutes) * time.Minute
+	}
+	return QuizCooldownMinutes * time.Minute
+}
+
+// recordQuizFailure menyimpan waktu gagal kuis dan mengembalikan akhir masa cooldown
+func recordQuizFailure(userID uint, quiz models.Quiz) time.Time {
+	quizID := quiz.ID
 	now := time.Now()
 	progress := models.UserProgress{
-		UserID: input.UserID,
-		QuizID: input.QuizID,
+		UserID: userID,
+		QuizID: quizID,
 	}
 
-	config.DB.Where("user_id = ? AND quiz_id = ?", input.UserID, input.QuizID).
+	config.DB.Where("user_id = ? AND quiz_id = ?", userID, quizID).
 		Assign(models.UserProgress{QuizFailedAt: &now, Completed: false}).
 		FirstOrCreate(&progress)
 
 	config.DB.Model(&progress).Update("quiz_failed_at", now)
 
-	cooldownEnd := now.Add(QuizCooldownMinutes * time.Minute)
-	c.JSON(http.StatusOK, gin.H{
-		"message":     "Cooldown dimulai",
-		"cooldownEnd": cooldownEnd.Unix(),
-	})
+	return now.Add(quizCooldown(quiz))
+}
+
+// hasPassedAttempt memeriksa apakah user memiliki QuizAttempt yang lulus untuk kuis tersebut
+func hasPassedAttempt(userID, quizID uint) bool {
+	var count int64
+	config.DB.Model(&models.QuizAttempt{}).
+		Where("user_id = ? AND quiz_id = ? AND passed = ?", userID, quizID, true).
+		Count(&count)
+	return count > 0
 }
 
 // CheckCooldown - Check remaining cooldown seconds for a user's quiz
 func CheckCooldown(c *gin.Context) {
-	userID := c.Query("userId")
+	userID, ok := targetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
 	lessonID := c.Query("lessonId")
 
 	var progress models.UserProgress
@@ -165,7 +247,10 @@ func CheckCooldown(c *gin.Context) {
 		return
 	}
 
-	cooldownEnd := progress.QuizFailedAt.Add(QuizCooldownMinutes * time.Minute)
+	var quiz models.Quiz
+	config.DB.First(&quiz, progress.QuizID)
+
+	cooldownEnd := progress.QuizFailedAt.Add(quizCooldown(quiz))
 	remaining := time.Until(cooldownEnd).Seconds()
 
 	if remaining <= 0 {
@@ -175,7 +260,8 @@ func CheckCooldown(c *gin.Context) {
 	}
 }
 
-// GetSubmissions - List all project submissions for admin review
+// GetSubmissions - List all project submissions for admin review.
+// Menampilkan versi terbaru; tambahkan ?history=true untuk menyertakan seluruh riwayat.
 func GetSubmissions(c *gin.Context) {
 	type SubmissionResponse struct {
 		ID                  uint   `json:"id"`
@@ -190,7 +276,20 @@ func GetSubmissions(c *gin.Context) {
 		SubmissionDriveLink string `json:"submissionDriveLink"`
 		ApprovalStatus      string `json:"approvalStatus"`
 		AdminNote           string `json:"adminNote"`
-		CreatedAt           string `json:"createdAt"`
+		AutoGradeStatus     string `json:"autoGradeStatus"`
+		AutoGradeScore      int    `json:"autoGradeScore"`
+		AutoGradeLog        string `json:"autoGradeLog"`
+		AutoApprove         bool   `json:"autoApprove"`
+		PeerReviewRequired  int    `json:"peerReviewRequired"`
+		PeerReviewAssigned  int    `json:"peerReviewAssigned"`
+		PeerReviewCompleted int    `json:"peerReviewCompleted"`
+		PeerReviewScore     int    `json:"peerReviewScore"`
+		ApprovalOverridden  bool   `json:"approvalOverridden"`
+		// Riwayat pengiriman
+		LatestSubmission *models.Submission  `json:"latestSubmission"`
+		AttemptCount     int                 `json:"attemptCount"`
+		History          []models.Submission `json:"history,omitempty"`
+		CreatedAt        string              `json:"createdAt"`
 	}
 
 	var progresses []models.UserProgress
@@ -200,12 +299,56 @@ func GetSubmissions(c *gin.Context) {
 		Order("user_progresses.created_at DESC").
 		Find(&progresses)
 
+	// Hitung penugasan dan penyelesaian peer review per submission dalam satu query
+	type reviewCount struct {
+		ProgressID uint
+		Assigned   int
+		Completed  int
+	}
+	var counts []reviewCount
+	config.DB.Model(&models.PeerReview{}).
+		Select("progress_id, COUNT(*) AS assigned, COUNT(*) FILTER (WHERE status = 'completed') AS completed").
+		Group("progress_id").
+		Scan(&counts)
+	reviewCounts := map[uint]reviewCount{}
+	for _, rc := range counts {
+		reviewCounts[rc.ProgressID] = rc
+	}
+
+	// Ambil seluruh versi submission sekaligus, urut dari yang terbaru
+	progressIDs := []uint{}
+	for _, p := range progresses {
+		progressIDs = append(progressIDs, p.ID)
+	}
+	var submissions []models.Submission
+	if len(progressIDs) > 0 {
+		config.DB.Where("progress_id IN ?", progressIDs).Order("attempt DESC").Find(&submissions)
+	}
+	history := map[uint][]models.Submission{}
+	for _, s := range submissions {
+		history[s.ProgressID] = append(history[s.ProgressID], s)
+	}
+	includeHistory := c.Query("history") == "true"
+
 	results := []SubmissionResponse{}
 	for _, p := range progresses {
 		pathTitle := ""
 		if p.Quiz.LearningPath != nil {
 			pathTitle = p.Quiz.LearningPath.Title
 		}
+		autoApprove := p.Quiz.GradingSpec != nil && p.Quiz.GradingSpec.AutoApprove
+		peerReviewRequired := 0
+		if p.Quiz.PeerReview != nil {
+			peerReviewRequired = p.Quiz.PeerReview.Reviewers
+		}
+		var latest *models.Submission
+		if versions := history[p.ID]; len(versions) > 0 {
+			latest = &versions[0]
+		}
+		var versions []models.Submission
+		if includeHistory {
+			versions = history[p.ID]
+		}
 		results = append(results, SubmissionResponse{
 			ID:                  p.ID,
 			UserID:              p.UserID,
@@ -219,6 +362,18 @@ func GetSubmissions(c *gin.Context) {
 			SubmissionDriveLink: p.SubmissionDriveLink,
 			ApprovalStatus:      p.ApprovalStatus,
 			AdminNote:           p.AdminNote,
+			AutoGradeStatus:     p.AutoGradeStatus,
+			AutoGradeScore:      p.AutoGradeScore,
+			AutoGradeLog:        p.AutoGradeLog,
+			AutoApprove:         autoApprove,
+			PeerReviewRequired:  peerReviewRequired,
+			PeerReviewAssigned:  reviewCounts[p.ID].Assigned,
+			PeerReviewCompleted: reviewCounts[p.ID].Completed,
+			PeerReviewScore:     p.PeerReviewScore,
+			ApprovalOverridden:  p.ApprovalOverridden,
+			LatestSubmission:    latest,
+			AttemptCount:        len(history[p.ID]),
+			History:             versions,
 			CreatedAt:           p.CreatedAt.Format("2006-01-02 15:04"),
 		})
 	}
@@ -237,44 +392,40 @@ func ApproveSubmission(c *gin.Context) {
 	}
 
 	config.DB.Model(&progress).Updates(map[string]interface{}{
-		"approval_status": "approved",
-		"admin_note":      "",
+		"approval_status":     "approved",
+		"admin_note":          "",
+		"approval_overridden": true,
 	})
+	services.ReviewLatestSubmission(progress.ID, "approved", reviewerID(c), "")
 
 	// Try to issue certificate
 	var quiz models.Quiz
 	if err := config.DB.First(&quiz, progress.QuizID).Error; err == nil {
-		var totalQuizzes, completedQuizzes, pendingProjects int64
-		config.DB.Model(&models.Quiz{}).Where("path_id = ?", quiz.PathID).Count(&totalQuizzes)
-		config.DB.Model(&models.UserProgress{}).
-			Joins("JOIN quizzes ON quizzes.id = user_progresses.quiz_id").
-			Where("user_progresses.user_id = ? AND quizzes.path_id = ? AND user_progresses.completed = true", progress.UserID, quiz.PathID).
-			Count(&completedQuizzes)
-		config.DB.Model(&models.UserProgress{}).
-			Joins("JOIN quizzes ON quizzes.id = user_progresses.quiz_id").
-			Where("user_progresses.user_id = ? AND quizzes.path_id = ? AND quizzes.type = 'project' AND user_progresses.approval_status != 'approved'", progress.UserID, quiz.PathID).
-			Count(&pendingProjects)
-
-		if totalQuizzes > 0 && completedQuizzes >= totalQuizzes && pendingProjects == 0 {
-			var certExists int64
-			config.DB.Model(&models.Certificate{}).Where("user_id = ? AND learning_path_id = ?", progress.UserID, quiz.PathID).Count(&certExists)
-			if certExists == 0 {
-				b := make([]byte, 4)
-				rand.Read(b)
-				certID := fmt.Sprintf("AIOT-%d-%X", quiz.PathID, hex.EncodeToString(b))
-				config.DB.Create(&models.Certificate{
-					UserID:         progress.UserID,
-					LearningPathID: quiz.PathID,
-					CertificateID:  certID,
-					IssuedAt:       time.Now(),
-				})
-			}
-		}
+		services.CheckPathCompletion(progress.UserID, quiz.PathID)
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Submission disetujui"})
 }
 
+// RegradeSubmission - Menjalankan ulang penilaian otomatis untuk sebuah submission ZIP
+func RegradeSubmission(c *gin.Context) {
+	id := c.Param("id")
+
+	var progress models.UserProgress
+	if err := config.DB.Preload("Quiz").First(&progress, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Submission tidak ditemukan"})
+		return
+	}
+
+	if progress.Quiz.GradingSpec == nil || progress.SubmissionFileURL == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Submission ini tidak memiliki penilaian otomatis"})
+		return
+	}
+
+	services.EnqueueGrading(progress.ID)
+	c.JSON(http.StatusOK, gin.H{"message": "Submission masuk antrean penilaian"})
+}
+
 // RejectSubmission - Reject a project submission with optional admin note
 func RejectSubmission(c *gin.Context) {
 	id := c.Param("id")
@@ -291,52 +442,50 @@ func RejectSubmission(c *gin.Context) {
 	}
 
 	config.DB.Model(&progress).Updates(map[string]interface{}{
-		"approval_status": "rejected",
-		"admin_note":      input.AdminNote,
+		"approval_status":     "rejected",
+		"admin_note":          input.AdminNote,
+		"approval_overridden": true,
 	})
+	services.ReviewLatestSubmission(progress.ID, "rejected", reviewerID(c), input.AdminNote)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Submission ditolak"})
 }
 
 // GetPathProgress - Get progress percentage for a specific path
 func GetPathProgress(c *gin.Context) {
-	userID := c.Query("userId")
-	pathID := c.Query("pathId")
-
-	if userID == "" || pathID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "userId and pathId are required"})
+	userID, ok := targetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
+	pathID, errPath := strconv.ParseUint(c.Query("pathId"), 10, 64)
 
-	var totalQuizzes int64
-	config.DB.Model(&models.Quiz{}).Where("path_id = ?", pathID).Count(&totalQuizzes)
-
-	var completedQuizzes int64
-	config.DB.Model(&models.UserProgress{}).
-		Joins("JOIN quizzes ON quizzes.id = user_progresses.quiz_id").
-		Where("user_progresses.user_id = ? AND quizzes.path_id = ?", userID, pathID).
-		Count(&completedQuizzes)
-
-	var percentage int = 0
-	if totalQuizzes > 0 {
-		percentage = int((float64(completedQuizzes) / float64(totalQuizzes)) * 100)
+	if errPath != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "pathId is required"})
+		return
 	}
 
+	progress := services.GetPathProgress(userID, uint(pathID))
+
 	c.JSON(http.StatusOK, gin.H{
-		"pathId":    pathID,
-		"completed": completedQuizzes,
-		"total":     totalQuizzes,
-		"progress":  percentage,
+		"pathId":    c.Query("pathId"),
+		"completed": progress.Completed,
+		"total":     progress.Total,
+		"progress":  progress.Percentage(),
 	})
 }
 
 // GetUserProgress - Get detailed progress status for a user in a path
 func GetUserProgress(c *gin.Context) {
-	userID := c.Query("userId")
+	userID, ok := targetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
 	pathID := c.Query("pathId")
 
-	if userID == "" || pathID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "userId and pathId are required"})
+	if pathID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "pathId is required"})
 		return
 	}
 
@@ -366,7 +515,11 @@ func GetUserProgress(c *gin.Context) {
 
 // GetLessonProgress - Get detailed progress record for a specific user and lesson
 func GetLessonProgress(c *gin.Context) {
-	userID := c.Query("userId")
+	userID, ok := targetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
 	quizID := c.Query("lessonId")
 
 	var progress models.UserProgress
@@ -380,9 +533,9 @@ func GetLessonProgress(c *gin.Context) {
 
 // GetUserSummaryStats - Get aggregate stats for a user's profile page
 func GetUserSummaryStats(c *gin.Context) {
-	userID := c.Query("userId")
-	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "userId is required"})
+	userID, ok := targetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
@@ -400,9 +553,9 @@ func GetUserSummaryStats(c *gin.Context) {
 
 // GetAccessibleMaterials - Get all lesson PDFs from paths the user has joined
 func GetAccessibleMaterials(c *gin.Context) {
-	userID := c.Query("userId")
-	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "userId is required"})
+	userID, ok := targetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
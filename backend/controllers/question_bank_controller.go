@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/imam/backend-blog-kuis/config"
+	"github.com/imam/backend-blog-kuis/models"
+	"github.com/imam/backend-blog-kuis/services"
+)
+
+// GetQuestionBanks - Daftar bank soal (filter opsional berdasarkan topik)
+func GetQuestionBanks(c *gin.Context) {
+	var banks []models.QuestionBank
+	db := config.DB
+	if topic := c.Query("topic"); topic != "" {
+		db = db.Where("topic = ?", topic)
+	}
+
+	if err := db.Order("title ASC").Find(&banks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil bank soal"})
+		return
+	}
+	c.JSON(http.StatusOK, banks)
+}
+
+// GetQuestionBank - Detail bank soal beserta seluruh soalnya
+func GetQuestionBank(c *gin.Context) {
+	id := c.Param("id")
+	var bank models.QuestionBank
+	if err := config.DB.Preload("Questions").First(&bank, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Bank soal tidak ditemukan"})
+		return
+	}
+	c.JSON(http.StatusOK, bank)
+}
+
+// CreateQuestionBank - Membuat bank soal baru
+func CreateQuestionBank(c *gin.Context) {
+	var bank models.QuestionBank
+	if err := c.ShouldBindJSON(&bank); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validasi gagal: " + err.Error()})
+		return
+	}
+
+	if err := validateQuestions(bank.Questions); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validasi soal gagal: " + err.Error()})
+		return
+	}
+
+	if err := config.DB.Create(&bank).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan bank soal"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Bank soal berhasil dibuat", "data": bank})
+}
+
+// UpdateQuestionBank - Memperbarui judul, topik, dan deskripsi bank soal
+func UpdateQuestionBank(c *gin.Context) {
+	id := c.Param("id")
+	var bank models.QuestionBank
+	if err := config.DB.First(&bank, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Bank soal tidak ditemukan"})
+		return
+	}
+
+	var input models.QuestionBank
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validasi gagal: " + err.Error()})
+		return
+	}
+
+	bank.Title = input.Title
+	bank.Topic = input.Topic
+	bank.Description = input.Description
+
+	if err := config.DB.Save(&bank).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui bank soal"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Bank soal diperbarui", "data": bank})
+}
+
+// DeleteQuestionBank - Menghapus bank soal beserta soal di dalamnya
+func DeleteQuestionBank(c *gin.Context) {
+	id := c.Param("id")
+	if err := config.DB.Delete(&models.QuestionBank{}, id).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghapus bank soal"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Bank soal berhasil dihapus"})
+}
+
+// CreateBankQuestion - Menambahkan soal ke bank soal
+func CreateBankQuestion(c *gin.Context) {
+	id := c.Param("id")
+	var bank models.QuestionBank
+	if err := config.DB.First(&bank, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Bank soal tidak ditemukan"})
+		return
+	}
+
+	var question models.Question
+	if err := c.ShouldBindJSON(&question); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validasi gagal: " + err.Error()})
+		return
+	}
+
+	if err := services.ValidateQuestion(&question); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validasi soal gagal: " + err.Error()})
+		return
+	}
+
+	question.ID = 0
+	question.QuizID = nil
+	question.BankID = &bank.ID
+
+	if err := config.DB.Create(&question).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan soal"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Soal berhasil ditambahkan", "data": question})
+}
+
+// UpdateBankQuestion - Memperbarui soal di dalam bank soal
+func UpdateBankQuestion(c *gin.Context) {
+	id := c.Param("questionId")
+	var question models.Question
+	if err := config.DB.Where("bank_id = ?", c.Param("id")).First(&question, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Soal tidak ditemukan"})
+		return
+	}
+
+	bankID := question.BankID
+	if err := c.ShouldBindJSON(&question); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validasi gagal: " + err.Error()})
+		return
+	}
+
+	if err := services.ValidateQuestion(&question); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validasi soal gagal: " + err.Error()})
+		return
+	}
+
+	question.QuizID = nil
+	question.BankID = bankID
+
+	if err := config.DB.Save(&question).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui soal"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Soal diperbarui", "data": question})
+}
+
+// DeleteBankQuestion - Menghapus soal dari bank soal
+func DeleteBankQuestion(c *gin.Context) {
+	if err := config.DB.Where("bank_id = ?", c.Param("id")).Delete(&models.Question{}, c.Param("questionId")).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghapus soal"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Soal berhasil dihapus"})
+}
@@ -0,0 +1,483 @@
+package controllers
+
+import (
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/imam/backend-blog-kuis/config"
+	"github.com/imam/backend-blog-kuis/models"
+	"github.com/imam/backend-blog-kuis/services"
+)
+
+// toPublicQuestions membuang kunci jawaban sebelum soal dikirim ke peserta
+func toPublicQuestions(questions []models.Question) []models.PublicQuestion {
+	results := []models.PublicQuestion{}
+	for _, q := range questions {
+		options := append([]models.QuestionOption{}, q.Options...)
+		matches := append([]models.QuestionOption{}, q.Matches...)
+
+		// Urutan tersimpan untuk soal ordering/matching adalah kunci jawaban, jadi harus diacak
+		if q.Kind == models.QuestionKindOrdering || q.Kind == models.QuestionKindMatching {
+			rand.Shuffle(len(options), func(i, j int) { options[i], options[j] = options[j], options[i] })
+			rand.Shuffle(len(matches), func(i, j int) { matches[i], matches[j] = matches[j], matches[i] })
+		}
+
+		results = append(results, models.PublicQuestion{
+			ID:           q.ID,
+			Kind:         q.Kind,
+			QuestionText: q.QuestionText,
+			Options:      options,
+			Matches:      matches,
+			Points:       q.Points,
+		})
+	}
+	return results
+}
+
+// toPublicAttemptQuestions menyusun soal sebuah attempt sesuai urutan opsi yang diundi saat attempt dimulai
+func toPublicAttemptQuestions(questions []models.AttemptQuestion) []models.PublicQuestion {
+	results := []models.PublicQuestion{}
+	for _, aq := range questions {
+		results = append(results, models.PublicQuestion{
+			ID:           aq.Question.ID,
+			Kind:         aq.Question.Kind,
+			QuestionText: aq.Question.QuestionText,
+			Options:      orderOptions(aq.Question.Options, aq.OptionOrder),
+			Matches:      orderOptions(aq.Question.Matches, aq.MatchOrder),
+			Points:       aq.Question.Points,
+		})
+	}
+	return results
+}
+
+// orderOptions mengurutkan opsi berdasarkan daftar ID; opsi yang tidak ada di daftar diletakkan di akhir
+func orderOptions(options []models.QuestionOption, order []string) []models.QuestionOption {
+	byID := map[string]models.QuestionOption{}
+	for _, o := range options {
+		byID[o.ID] = o
+	}
+
+	results := []models.QuestionOption{}
+	for _, id := range order {
+		if o, ok := byID[id]; ok {
+			results = append(results, o)
+			delete(byID, id)
+		}
+	}
+	for _, o := range options {
+		if _, ok := byID[o.ID]; ok {
+			results = append(results, o)
+		}
+	}
+	return results
+}
+
+// ExamGracePeriod adalah toleransi keterlambatan pengiriman jawaban setelah deadline ujian
+const ExamGracePeriod = 30 * time.Second
+
+// cooldownRemaining mengembalikan sisa detik cooldown kuis untuk user (0 jika tidak sedang cooldown)
+func cooldownRemaining(userID uint, quiz models.Quiz) int {
+	var progress models.UserProgress
+	if err := config.DB.Where("user_id = ? AND quiz_id = ?", userID, quiz.ID).First(&progress).Error; err != nil {
+		return 0
+	}
+	if progress.QuizFailedAt == nil || progress.Completed {
+		return 0
+	}
+
+	remaining := time.Until(progress.QuizFailedAt.Add(quizCooldown(quiz))).Seconds()
+	if remaining <= 0 {
+		return 0
+	}
+	return int(remaining)
+}
+
+// loadQuizForAttempt mengambil kuis beserta soalnya dan memastikan user boleh mengerjakannya
+func loadQuizForAttempt(c *gin.Context) (*models.Quiz, bool) {
+	id := c.Param("id")
+
+	var quiz models.Quiz
+	if err := config.DB.Preload("Questions").First(&quiz, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Kuis tidak ditemukan"})
+		return nil, false
+	}
+
+	if quiz.Type != "quiz" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Modul ini bukan kuis"})
+		return nil, false
+	}
+
+	if !checkAccess(c, id) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Konten Premium. Silakan upgrade ke PRO."})
+		return nil, false
+	}
+
+	uid, _ := currentUserID(c)
+	if rejectIfLocked(c, uid, quiz) {
+		return nil, false
+	}
+
+	return &quiz, true
+}
+
+// rejectOnCooldown menolak attempt baru selama masa cooldown masih berjalan
+func rejectOnCooldown(c *gin.Context, uid uint, quiz models.Quiz) bool {
+	remaining := cooldownRemaining(uid, quiz)
+	if remaining <= 0 {
+		return false
+	}
+
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":            "Kuis sedang dalam masa cooldown",
+		"remainingSeconds": remaining,
+	})
+	return true
+}
+
+// findActiveAttempt mengambil attempt yang belum dikirim milik user untuk kuis tertentu
+func findActiveAttempt(uid, quizID uint) (*models.QuizAttempt, bool) {
+	var attempt models.QuizAttempt
+	if err := config.DB.Where("user_id = ? AND quiz_id = ? AND status = ?", uid, quizID, "in_progress").
+		Order("created_at DESC").First(&attempt).Error; err != nil {
+		return nil, false
+	}
+	return &attempt, true
+}
+
+// isAttemptOverdue memeriksa apakah deadline ujian (ditambah grace period) sudah lewat
+func isAttemptOverdue(attempt models.QuizAttempt, now time.Time) bool {
+	return attempt.Deadline != nil && now.After(attempt.Deadline.Add(ExamGracePeriod))
+}
+
+// attemptResponse menyusun data attempt yang sedang berjalan untuk dilanjutkan di client
+func attemptResponse(attempt models.QuizAttempt) gin.H {
+	response := gin.H{
+		"data":      attempt,
+		"questions": toPublicAttemptQuestions(attempt.Questions),
+	}
+	if attempt.Deadline != nil && attempt.Status == "in_progress" {
+		remaining := int(time.Until(*attempt.Deadline).Seconds())
+		if remaining < 0 {
+			remaining = 0
+		}
+		response["remainingSeconds"] = remaining
+	}
+	return response
+}
+
+// finishAttempt menilai attempt, menyimpannya, lalu menandai progres selesai atau memulai cooldown
+func finishAttempt(attempt *models.QuizAttempt, quiz models.Quiz, answers []models.QuestionSubmission, now time.Time) (gin.H, error) {
+	byQuestion := map[uint]models.QuestionSubmission{}
+	for _, a := range answers {
+		byQuestion[a.QuestionID] = a
+	}
+
+	// Nilai setiap soal terhadap salinan soal yang tersimpan di attempt
+	results := []models.QuizQuestionResult{}
+	correctCount, earnedPoints, totalPoints := 0, 0, 0
+	for _, aq := range attempt.Questions {
+		result := services.GradeQuestion(aq.Question, byQuestion[aq.Question.ID])
+		if result.Correct {
+			correctCount++
+		}
+		earnedPoints += result.EarnedPoints
+		totalPoints += result.Points
+		results = append(results, result)
+	}
+
+	score := 100
+	if totalPoints > 0 {
+		score = earnedPoints * 100 / totalPoints
+	}
+
+	attempt.Status = "submitted"
+	attempt.Score = score
+	attempt.CorrectCount = correctCount
+	attempt.TotalQuestions = len(attempt.Questions)
+	attempt.EarnedPoints = earnedPoints
+	attempt.TotalPoints = totalPoints
+	attempt.Passed = score >= QuizPassingScore
+	attempt.Results = results
+	attempt.Answers = nil
+	attempt.SubmittedAt = &now
+	if attempt.StartedAt != nil {
+		end := now
+		if attempt.Deadline != nil && end.After(*attempt.Deadline) {
+			end = *attempt.Deadline
+		}
+		attempt.DurationSeconds = int(end.Sub(*attempt.StartedAt).Seconds())
+	}
+
+	if err := config.DB.Save(attempt).Error; err != nil {
+		return nil, err
+	}
+
+	if !attempt.Passed {
+		cooldownEnd := recordQuizFailure(attempt.UserID, quiz)
+		return gin.H{
+			"message":     "Kuis belum lulus, cooldown dimulai",
+			"data":        attempt,
+			"cooldownEnd": cooldownEnd.Unix(),
+		}, nil
+	}
+
+	progress := models.UserProgress{UserID: attempt.UserID, QuizID: quiz.ID}
+	if err := config.DB.Where("user_id = ? AND quiz_id = ?", attempt.UserID, quiz.ID).
+		FirstOrCreate(&progress).Error; err != nil {
+		return nil, err
+	}
+
+	if err := config.DB.Model(&progress).Updates(map[string]interface{}{
+		"completed":      true,
+		"quiz_failed_at": nil,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	return gin.H{
+		"message":    "Kuis lulus",
+		"data":       attempt,
+		"isComplete": services.CheckPathCompletion(attempt.UserID, quiz.PathID),
+	}, nil
+}
+
+// autoSubmitOverdue mengirim otomatis attempt yang melewati deadline dengan jawaban terakhir yang tersimpan
+func autoSubmitOverdue(c *gin.Context, attempt *models.QuizAttempt, quiz models.Quiz) {
+	attempt.AutoSubmitted = true
+	result, err := finishAttempt(attempt, quiz, attempt.Answers, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan hasil kuis"})
+		return
+	}
+
+	result["error"] = "Waktu ujian habis. Jawaban terakhir yang tersimpan telah dikirim otomatis."
+	c.JSON(http.StatusConflict, result)
+}
+
+// StartQuizAttempt - Mengundi soal untuk satu attempt baru (atau melanjutkan attempt yang belum dikirim)
+func StartQuizAttempt(c *gin.Context) {
+	uid, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	quiz, ok := loadQuizForAttempt(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	if attempt, found := findActiveAttempt(uid, quiz.ID); found {
+		if isAttemptOverdue(*attempt, now) {
+			autoSubmitOverdue(c, attempt, *quiz)
+			return
+		}
+		c.JSON(http.StatusOK, attemptResponse(*attempt))
+		return
+	}
+
+	if rejectOnCooldown(c, uid, *quiz) {
+		return
+	}
+
+	questions, err := services.DrawQuestions(*quiz)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Gagal menyusun soal: " + err.Error()})
+		return
+	}
+
+	attempt := models.QuizAttempt{
+		UserID:         uid,
+		QuizID:         quiz.ID,
+		Status:         "in_progress",
+		TotalQuestions: len(questions),
+		Questions:      questions,
+		StartedAt:      &now,
+	}
+	if quiz.ExamMode && quiz.Duration > 0 {
+		deadline := now.Add(time.Duration(quiz.Duration) * time.Minute)
+		attempt.Deadline = &deadline
+	}
+
+	if err := config.DB.Create(&attempt).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memulai kuis"})
+		return
+	}
+
+	c.JSON(http.StatusOK, attemptResponse(attempt))
+}
+
+// ResumeQuizAttempt - Melanjutkan attempt yang sedang berjalan setelah halaman dimuat ulang
+func ResumeQuizAttempt(c *gin.Context) {
+	uid, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	quiz, ok := loadQuizForAttempt(c)
+	if !ok {
+		return
+	}
+
+	attempt, found := findActiveAttempt(uid, quiz.ID)
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tidak ada kuis yang sedang berjalan"})
+		return
+	}
+
+	if isAttemptOverdue(*attempt, time.Now()) {
+		autoSubmitOverdue(c, attempt, *quiz)
+		return
+	}
+
+	c.JSON(http.StatusOK, attemptResponse(*attempt))
+}
+
+// SaveQuizAttemptAnswers - Menyimpan jawaban sementara agar bisa dilanjutkan atau dikirim otomatis saat waktu habis
+func SaveQuizAttemptAnswers(c *gin.Context) {
+	var input struct {
+		Answers []models.QuestionSubmission `json:"answers" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	uid, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var attempt models.QuizAttempt
+	if err := config.DB.Where("id = ? AND user_id = ? AND status = ?", c.Param("attemptId"), uid, "in_progress").
+		First(&attempt).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sesi kuis tidak ditemukan atau sudah dikirim"})
+		return
+	}
+
+	if isAttemptOverdue(attempt, time.Now()) {
+		var quiz models.Quiz
+		config.DB.First(&quiz, attempt.QuizID)
+		autoSubmitOverdue(c, &attempt, quiz)
+		return
+	}
+
+	attempt.Answers = input.Answers
+	if err := config.DB.Model(&attempt).Select("Answers").Updates(&attempt).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan jawaban"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Jawaban disimpan"})
+}
+
+// SubmitQuizAttempt - Menilai jawaban kuis di server dan menyimpan hasilnya sebagai QuizAttempt
+func SubmitQuizAttempt(c *gin.Context) {
+	var input struct {
+		AttemptID uint                        `json:"attemptId"`
+		Answers   []models.QuestionSubmission `json:"answers" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	uid, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	quiz, ok := loadQuizForAttempt(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	var attempt models.QuizAttempt
+	if input.AttemptID != 0 {
+		if err := config.DB.Where("id = ? AND user_id = ? AND quiz_id = ? AND status = ?", input.AttemptID, uid, quiz.ID, "in_progress").
+			First(&attempt).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Sesi kuis tidak ditemukan atau sudah dikirim"})
+			return
+		}
+
+		// Jawaban yang datang setelah deadline + grace period ditolak
+		if isAttemptOverdue(attempt, now) {
+			autoSubmitOverdue(c, &attempt, *quiz)
+			return
+		}
+	} else {
+		// Kuis dengan soal acak atau mode ujian harus dimulai lewat /attempts/start
+		if len(quiz.DrawRules) > 0 || quiz.ExamMode {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Mulai kuis terlebih dahulu melalui endpoint attempts/start"})
+			return
+		}
+		if rejectOnCooldown(c, uid, *quiz) {
+			return
+		}
+		attempt = models.QuizAttempt{UserID: uid, QuizID: quiz.ID}
+		for _, q := range quiz.Questions {
+			attempt.Questions = append(attempt.Questions, models.AttemptQuestion{Question: q})
+		}
+	}
+
+	result, err := finishAttempt(&attempt, *quiz, input.Answers, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan hasil kuis"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetQuizAttempts - Riwayat pengerjaan kuis milik user yang sedang login
+func GetQuizAttempts(c *gin.Context) {
+	uid, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	db := config.DB.Where("user_id = ?", uid)
+	if id := c.Param("id"); id != "" {
+		db = db.Where("quiz_id = ?", id)
+	} else if lessonID := c.Query("lessonId"); lessonID != "" {
+		db = db.Where("quiz_id = ?", lessonID)
+	}
+
+	attempts := []models.QuizAttempt{}
+	if err := db.Order("created_at DESC").Find(&attempts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil riwayat kuis"})
+		return
+	}
+
+	c.JSON(http.StatusOK, attempts)
+}
+
+// GetQuizAttempt - Detail satu attempt beserta soal yang diundi untuk review
+func GetQuizAttempt(c *gin.Context) {
+	uid, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var attempt models.QuizAttempt
+	if err := config.DB.Where("id = ? AND user_id = ?", c.Param("attemptId"), uid).First(&attempt).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attempt tidak ditemukan"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":      attempt,
+		"questions": toPublicAttemptQuestions(attempt.Questions),
+	})
+}
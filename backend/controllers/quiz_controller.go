@@ -1,19 +1,24 @@
 package controllers
 
 import (
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/imam/backend-blog-kuis/config"
 	"github.com/imam/backend-blog-kuis/models"
+	"github.com/imam/backend-blog-kuis/services"
 	"github.com/imam/backend-blog-kuis/utils"
 	"gorm.io/gorm"
 )
 
-// GetLearningPaths - Mengambil semua learning path dengan filter joined
+// GetLearningPaths - Mengambil semua learning path dengan filter joined (berdasarkan enrollment)
 func GetLearningPaths(c *gin.Context) {
 	joined := c.Query("joined")
-	userID := c.Query("userId") // Bisa dari query atau nanti dari token di middleware
+	// User diambil dari token (OptionalAuth); tanpa login semua path tampil sebagai belum diikuti
+	uid, _ := currentUserID(c)
+	enrollments := services.EnrolledPaths(uid)
 
 	var paths []models.LearningPath
 	db := config.DB.Preload("Category")
@@ -30,42 +35,35 @@ func GetLearningPaths(c *gin.Context) {
 
 	type PathWithProgress struct {
 		models.LearningPath
-		Progress int `json:"progress"`
+		Progress   int        `json:"progress"`
+		Enrolled   bool       `json:"enrolled"`
+		EnrolledAt *time.Time `json:"enrolledAt"`
 	}
 
+	// Progres seluruh path dihitung dalam satu query agregat
+	pathIDs := []uint{}
+	for _, p := range paths {
+		pathIDs = append(pathIDs, p.ID)
+	}
+	progresses := services.PathProgresses(uid, pathIDs)
+
 	// Initialize as empty slice instead of nil to avoid null in JSON
 	results := []PathWithProgress{}
 	for _, p := range paths {
-		var totalQuizzes int64
-		config.DB.Model(&models.Quiz{}).Where("path_id = ?", p.ID).Count(&totalQuizzes)
-
-		var completedQuizzes int64
-		if userID != "" {
-			config.DB.Model(&models.UserProgress{}).
-				Joins("JOIN quizzes ON quizzes.id = user_progress.quiz_id").
-				Where("user_progress.user_id = ? AND quizzes.path_id = ?", userID, p.ID).
-				Count(&completedQuizzes)
+		item := PathWithProgress{
+			LearningPath: p,
+			Progress:     progresses[p.ID].Percentage(),
 		}
-
-		progress := 0
-		if totalQuizzes > 0 {
-			progress = int((float64(completedQuizzes) / float64(totalQuizzes)) * 100)
+		if enrollment, enrolled := enrollments[p.ID]; enrolled {
+			item.Enrolled = true
+			item.EnrolledAt = &enrollment.EnrolledAt
 		}
 
-		// Jika joined=true, hanya masukkan yang progress > 0
-		if joined == "true" {
-			if progress > 0 {
-				results = append(results, PathWithProgress{
-					LearningPath: p,
-					Progress:     progress,
-				})
-			}
-		} else {
-			results = append(results, PathWithProgress{
-				LearningPath: p,
-				Progress:     progress,
-			})
+		// Jika joined=true, hanya masukkan path yang sudah didaftari user
+		if joined == "true" && !item.Enrolled {
+			continue
 		}
+		results = append(results, item)
 	}
 
 	c.JSON(http.StatusOK, results)
@@ -86,10 +84,102 @@ func GetLearningPath(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Learning path tidak ditemukan"})
 		return
 	}
-	c.JSON(http.StatusOK, path)
+
+	type LessonWithLock struct {
+		models.Quiz
+		models.LessonLock
+		RequiredLessonIDs []uint `json:"requiredLessonIds"`
+	}
+	type ChapterWithLocks struct {
+		models.Chapter
+		Lessons []LessonWithLock `json:"lessons"`
+	}
+
+	// Status kunci dihitung untuk user yang login (atau pengunjung jika tanpa token)
+	uid, _ := currentUserID(c)
+	pathLock, locks := services.PathLocks(uid, path)
+	if isAdminRole(c) {
+		pathLock, locks = models.LessonLock{}, map[uint]models.LessonLock{}
+	}
+	entitled := services.HasEntitlement(uid, path)
+
+	lessonIDs := []uint{}
+	for _, ch := range path.Chapters {
+		for _, l := range ch.Lessons {
+			lessonIDs = append(lessonIDs, l.ID)
+		}
+	}
+	prerequisites := services.LessonPrerequisiteIDs(lessonIDs)
+
+	chapters := []ChapterWithLocks{}
+	for _, ch := range path.Chapters {
+		chapter := ChapterWithLocks{Chapter: ch, Lessons: []LessonWithLock{}}
+		for _, l := range ch.Lessons {
+			required := prerequisites[l.ID]
+			if required == nil {
+				required = []uint{}
+			}
+			if locks[l.ID].Locked || !entitled {
+				hideLessonContent(&l)
+			}
+			chapter.Lessons = append(chapter.Lessons, LessonWithLock{
+				Quiz:              l,
+				LessonLock:        locks[l.ID],
+				RequiredLessonIDs: required,
+			})
+		}
+		chapters = append(chapters, chapter)
+	}
+
+	var enrollment *models.Enrollment
+	if e, found := services.FindEnrollment(uid, path.ID); found {
+		enrollment = &e
+	}
+
+	c.JSON(http.StatusOK, struct {
+		models.LearningPath
+		Chapters        []ChapterWithLocks `json:"chapters"`
+		Locked          bool               `json:"locked"`
+		LockReason      string             `json:"lockReason,omitempty"`
+		RequiredPathIDs []uint             `json:"requiredPathIds"`
+		Enrollment      *models.Enrollment `json:"enrollment"`
+	}{path, chapters, pathLock.Locked, pathLock.Reason, services.PathPrerequisiteIDs(path.ID), enrollment})
+}
+
+// lessonLockFor menghitung status kunci materi untuk user; admin tidak pernah terkunci
+func lessonLockFor(c *gin.Context, userID uint, quiz models.Quiz) models.LessonLock {
+	if isAdminRole(c) {
+		return models.LessonLock{}
+	}
+	return services.LessonLockFor(userID, quiz)
+}
+
+// rejectIfLocked menolak akses ke materi yang prasyaratnya belum terpenuhi
+func rejectIfLocked(c *gin.Context, userID uint, quiz models.Quiz) bool {
+	lock := lessonLockFor(c, userID, quiz)
+	if !lock.Locked {
+		return false
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": lock.Reason, "locked": true})
+	return true
+}
+
+// hideLessonContent mengosongkan isi materi yang terkunci atau premium agar hanya
+// judul dan metadata yang terkirim; isi lengkap hanya bisa diambil lewat GetQuiz
+func hideLessonContent(quiz *models.Quiz) {
+	quiz.Content = ""
+	quiz.VideoURL = ""
+	quiz.PdfURL = ""
+	quiz.ProjectFileURL = ""
+	quiz.ProjectDriveLink = ""
+	quiz.GradingSpec = nil
+	quiz.PeerReview = nil
+	quiz.DrawRules = nil
+	quiz.Questions = nil
 }
 
 // GetQuizzes - (Module list for a path)
+// Isi modul yang terkunci atau premium tanpa langganan dikosongkan.
 func GetQuizzes(c *gin.Context) {
 	pathID := c.Query("pathId")
 	var quizzes []models.Quiz
@@ -104,20 +194,57 @@ func GetQuizzes(c *gin.Context) {
 		return
 	}
 
+	if !isAdminRole(c) {
+		uid, _ := currentUserID(c)
+		type pathAccess struct {
+			entitled bool
+			locks    map[uint]models.LessonLock
+		}
+		access := map[uint]pathAccess{}
+		for i := range quizzes {
+			pa, ok := access[quizzes[i].PathID]
+			if !ok {
+				var path models.LearningPath
+				if err := config.DB.First(&path, quizzes[i].PathID).Error; err == nil {
+					_, locks := services.PathLocks(uid, path)
+					pa = pathAccess{entitled: services.HasEntitlement(uid, path), locks: locks}
+				}
+				access[quizzes[i].PathID] = pa
+			}
+			if !pa.entitled || pa.locks[quizzes[i].ID].Locked {
+				hideLessonContent(&quizzes[i])
+			}
+		}
+	}
+
 	c.JSON(http.StatusOK, quizzes)
 }
 
-// GetQuizQuestions - Mengambil soal berdasarkan ID Kuis (Module)
+// GetQuizQuestions - Mengambil soal berdasarkan ID Kuis (Module) tanpa kunci jawaban
 func GetQuizQuestions(c *gin.Context) {
 	id := c.Param("id")
 	var questions []models.Question
 
+	var quiz models.Quiz
+	if err := config.DB.First(&quiz, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Kuis tidak ditemukan"})
+		return
+	}
+	if !checkAccess(c, id) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Konten Premium. Silakan upgrade ke PRO."})
+		return
+	}
+	uid, _ := currentUserID(c)
+	if rejectIfLocked(c, uid, quiz) {
+		return
+	}
+
 	if err := config.DB.Where("quiz_id = ?", id).Find(&questions).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Soal tidak ditemukan"})
 		return
 	}
 
-	c.JSON(http.StatusOK, questions)
+	c.JSON(http.StatusOK, toPublicQuestions(questions))
 }
 
 // checkAccess memeriksa apakah user boleh mengakses materi (premium check)
@@ -132,32 +259,45 @@ func checkAccess(c *gin.Context, quizID string) bool {
 		return true
 	}
 
-	// Cek Role User
-	userIDRaw, exists := c.Get("user_id")
-	if !exists {
-		return false
-	}
+	uid, _ := currentUserID(c)
+	return services.HasEntitlement(uid, *quiz.LearningPath)
+}
 
-	var uid uint
-	switch v := userIDRaw.(type) {
-	case float64:
-		uid = uint(v)
-	case uint:
-		uid = v
-	default:
-		return false
+// validateQuestions memvalidasi seluruh soal sebelum kuis disimpan
+func validateQuestions(questions []models.Question) error {
+	for i := range questions {
+		if err := services.ValidateQuestion(&questions[i]); err != nil {
+			return fmt.Errorf("soal #%d: %v", i+1, err)
+		}
 	}
+	return nil
+}
 
-	var user models.User
-	if err := config.DB.First(&user, uid).Error; err != nil {
-		return false
+// validateDrawRules memvalidasi aturan undian bank soal; hanya berlaku untuk modul bertipe quiz
+func validateDrawRules(quiz *models.Quiz) error {
+	if quiz.Type != "quiz" {
+		quiz.DrawRules = nil
+		return nil
 	}
+	return services.ValidateDrawRules(quiz.DrawRules)
+}
 
-	if user.Role == "admin" || user.Role == "pro" {
-		return true
+// validateGradingSpec memvalidasi spec penilaian otomatis; hanya berlaku untuk project dengan pengumpulan ZIP
+func validateGradingSpec(quiz *models.Quiz) error {
+	if quiz.Type != "project" || !quiz.AllowZipSubmission {
+		quiz.GradingSpec = nil
+		return nil
 	}
+	return services.ValidateGradingSpec(quiz.GradingSpec)
+}
 
-	return false
+// validatePeerReview memvalidasi konfigurasi peer review; hanya berlaku untuk modul project
+func validatePeerReview(quiz *models.Quiz) error {
+	if quiz.Type != "project" {
+		quiz.PeerReview = nil
+		return nil
+	}
+	return services.ValidatePeerReviewConfig(quiz.PeerReview)
 }
 
 // CreateQuiz - Admin membuat modul baru
@@ -172,6 +312,26 @@ func CreateQuiz(c *gin.Context) {
 	quiz.Content = utils.SanitizeHTML(quiz.Content)
 	quiz.Description = utils.SanitizeHTML(quiz.Description)
 
+	if err := validateQuestions(quiz.Questions); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validasi soal gagal: " + err.Error()})
+		return
+	}
+
+	if err := validateDrawRules(&quiz); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Aturan soal acak tidak valid: " + err.Error()})
+		return
+	}
+
+	if err := validateGradingSpec(&quiz); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Spec penilaian otomatis tidak valid: " + err.Error()})
+		return
+	}
+
+	if err := validatePeerReview(&quiz); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Konfigurasi peer review tidak valid: " + err.Error()})
+		return
+	}
+
 	if err := config.DB.Create(&quiz).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan modul"})
 		return
@@ -190,7 +350,7 @@ func DeleteQuiz(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Kuis berhasil dihapus"})
 }
 
-// GetQuiz - Mengambil detail kuis beserta pertanyaannya (Admin/Public)
+// GetQuiz - Mengambil detail kuis beserta pertanyaannya tanpa kunci jawaban (Public)
 func GetQuiz(c *gin.Context) {
 	id := c.Param("id")
 	var quiz models.Quiz
@@ -207,11 +367,43 @@ func GetQuiz(c *gin.Context) {
 		return
 	}
 
+	// Cek prasyarat & mode berurutan
+	uid, _ := currentUserID(c)
+	if rejectIfLocked(c, uid, quiz) {
+		return
+	}
+
+	c.JSON(http.StatusOK, struct {
+		models.Quiz
+		Questions []models.PublicQuestion `json:"questions,omitempty"`
+	}{quiz, toPublicQuestions(quiz.Questions)})
+}
+
+// AdminGetQuiz - Mengambil detail kuis lengkap dengan kunci jawaban (Admin)
+func AdminGetQuiz(c *gin.Context) {
+	id := c.Param("id")
+	var quiz models.Quiz
+
+	if err := config.DB.Preload("Questions").First(&quiz, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Kuis tidak ditemukan"})
+		return
+	}
+
 	c.JSON(http.StatusOK, quiz)
 }
 
 // --- Learning Path Admin Controllers ---
 
+// certificateTemplateExists memeriksa template sertifikat yang di-assign ke path (nil = template bawaan)
+func certificateTemplateExists(id *uint) bool {
+	if id == nil {
+		return true
+	}
+	var count int64
+	config.DB.Model(&models.CertificateTemplate{}).Where("id = ?", *id).Count(&count)
+	return count > 0
+}
+
 func CreateLearningPath(c *gin.Context) {
 	var path models.LearningPath
 	if err := c.ShouldBindJSON(&path); err != nil {
@@ -219,6 +411,11 @@ func CreateLearningPath(c *gin.Context) {
 		return
 	}
 
+	if !certificateTemplateExists(path.CertificateTemplateID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Template sertifikat tidak ditemukan"})
+		return
+	}
+
 	// Sanitize
 	path.Description = utils.SanitizeHTML(path.Description)
 
@@ -241,6 +438,11 @@ func UpdateLearningPath(c *gin.Context) {
 		return
 	}
 
+	if !certificateTemplateExists(path.CertificateTemplateID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Template sertifikat tidak ditemukan"})
+		return
+	}
+
 	// Sanitize
 	path.Description = utils.SanitizeHTML(path.Description)
 
@@ -251,6 +453,29 @@ func UpdateLearningPath(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Learning path diperbarui", "data": path})
 }
 
+// SetPathPrerequisites - Mengatur learning path yang harus diselesaikan sebelum path ini terbuka
+func SetPathPrerequisites(c *gin.Context) {
+	var path models.LearningPath
+	if err := config.DB.First(&path, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Learning path tidak ditemukan"})
+		return
+	}
+
+	var input struct {
+		RequiredPathIDs []uint `json:"requiredPathIds"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.SetPathPrerequisites(path.ID, input.RequiredPathIDs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Prasyarat tidak valid: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Prasyarat learning path diperbarui", "data": services.PathPrerequisiteIDs(path.ID)})
+}
+
 func DeleteLearningPath(c *gin.Context) {
 	id := c.Param("id")
 	if err := config.DB.Delete(&models.LearningPath{}, id).Error; err != nil {
@@ -302,6 +527,29 @@ func DeleteChapter(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Bab berhasil dihapus"})
 }
 
+// SetLessonPrerequisites - Mengatur materi yang harus diselesaikan sebelum materi ini terbuka
+func SetLessonPrerequisites(c *gin.Context) {
+	var quiz models.Quiz
+	if err := config.DB.First(&quiz, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Kuis/Materi tidak ditemukan"})
+		return
+	}
+
+	var input struct {
+		RequiredLessonIDs []uint `json:"requiredLessonIds"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.SetLessonPrerequisites(quiz.ID, input.RequiredLessonIDs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Prasyarat tidak valid: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Prasyarat materi diperbarui", "data": services.LessonPrerequisiteIDs([]uint{quiz.ID})[quiz.ID]})
+}
+
 func UpdateQuiz(c *gin.Context) {
 	id := c.Param("id")
 	var quiz models.Quiz
@@ -318,6 +566,26 @@ func UpdateQuiz(c *gin.Context) {
 	quiz.Content = utils.SanitizeHTML(quiz.Content)
 	quiz.Description = utils.SanitizeHTML(quiz.Description)
 
+	if err := validateQuestions(quiz.Questions); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validasi soal gagal: " + err.Error()})
+		return
+	}
+
+	if err := validateDrawRules(&quiz); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Aturan soal acak tidak valid: " + err.Error()})
+		return
+	}
+
+	if err := validateGradingSpec(&quiz); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Spec penilaian otomatis tidak valid: " + err.Error()})
+		return
+	}
+
+	if err := validatePeerReview(&quiz); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Konfigurasi peer review tidak valid: " + err.Error()})
+		return
+	}
+
 	if err := config.DB.Save(&quiz).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui modul"})
 		return
@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/imam/backend-blog-kuis/config"
+	"github.com/imam/backend-blog-kuis/models"
+	"github.com/imam/backend-blog-kuis/services"
+	"github.com/imam/backend-blog-kuis/utils"
+)
+
+// GetMyReferral - Kode referral, tautan undangan, saldo kredit, dan riwayat kredit user yang sedang login
+func GetMyReferral(c *gin.Context) {
+	uid, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	code, err := services.EnsureReferralCode(uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membuat kode referral"})
+		return
+	}
+
+	var referred, rewarded int64
+	config.DB.Model(&models.User{}).Where("referred_by_id = ?", uid).Count(&referred)
+	config.DB.Model(&models.CreditTransaction{}).Where("user_id = ? AND reason = ?", uid, services.CreditReferralReward).Count(&rewarded)
+
+	var history []models.CreditTransaction
+	config.DB.Where("user_id = ?", uid).Order("created_at DESC").Limit(50).Find(&history)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":          code,
+		"link":          utils.FrontendURL() + "/register?ref=" + code,
+		"creditBalance": services.CreditBalance(config.DB, uid),
+		"referredCount": referred,
+		"rewardedCount": rewarded,
+		"history":       history,
+	})
+}
@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/imam/backend-blog-kuis/services"
+)
+
+// GetRevenueAnalytics - Pendapatan per periode, MRR, churn, ARPU, funnel checkout, dan breakdown
+// untuk rentang ?from=YYYY-MM-DD&to=YYYY-MM-DD (bawaan 30 hari terakhir, UTC) (Super Admin)
+func GetRevenueAnalytics(c *gin.Context) {
+	dateRange, err := services.ParseRevenueRange(c.Query("from"), c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	series, err := services.RevenueSeries(dateRange, c.DefaultQuery("interval", "day"))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidInterval) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghitung pendapatan"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":     dateRange.From.Format("2006-01-02"),
+		"to":       dateRange.To.AddDate(0, 0, -1).Format("2006-01-02"),
+		"interval": c.DefaultQuery("interval", "day"),
+		"summary":  services.GetRevenueSummary(dateRange),
+		"series":   series,
+		"funnel":   services.GetCheckoutFunnel(dateRange),
+		"breakdown": gin.H{
+			"plan":           services.GetRevenueBreakdown(dateRange, "plan"),
+			"payment_method": services.GetRevenueBreakdown(dateRange, "payment_method"),
+			"gateway":        services.GetRevenueBreakdown(dateRange, "gateway"),
+		},
+	})
+}
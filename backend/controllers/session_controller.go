@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/imam/backend-blog-kuis/config"
+	"github.com/imam/backend-blog-kuis/models"
+	"github.com/imam/backend-blog-kuis/services"
+)
+
+// sessionClient - Informasi perangkat dari request untuk dicatat di sesi
+func sessionClient(c *gin.Context) services.SessionClient {
+	return services.SessionClient{IPAddress: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+}
+
+// RefreshToken - Menukar refresh token dengan access token baru; refresh token ikut dirotasi
+func RefreshToken(c *gin.Context) {
+	var input struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Refresh token wajib diisi"})
+		return
+	}
+
+	tokens, err := services.RefreshSession(input.RefreshToken, sessionClient(c))
+	if err != nil {
+		if errors.Is(err, services.ErrSessionInvalid) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui token"})
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Logout - Mencabut sesi dari refresh token di body, atau dari access token jika body kosong
+func Logout(c *gin.Context) {
+	var input struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	c.ShouldBindJSON(&input)
+
+	if input.RefreshToken != "" {
+		services.RevokeSessionByRefreshToken(input.RefreshToken)
+	} else if sid := currentSessionID(c); sid != 0 {
+		if uid, ok := currentUserID(c); ok {
+			services.RevokeUserSession(uid, sid, services.SessionLogout)
+		}
+	}
+
+	// Selalu sukses agar klien tetap bisa membersihkan token lokal
+	c.JSON(http.StatusOK, gin.H{"message": "Logout berhasil"})
+}
+
+// GetMySessions - Daftar sesi aktif user yang sedang login, sesi saat ini ditandai current
+func GetMySessions(c *gin.Context) {
+	uid, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var sessions []models.UserSession
+	config.DB.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", uid, time.Now()).
+		Order("last_used_at DESC").Find(&sessions)
+
+	current := currentSessionID(c)
+	data := make([]gin.H, len(sessions))
+	for i, s := range sessions {
+		data[i] = gin.H{
+			"id":         s.ID,
+			"device":     s.Device,
+			"userAgent":  s.UserAgent,
+			"ipAddress":  s.IPAddress,
+			"lastUsedAt": s.LastUsedAt,
+			"createdAt":  s.CreatedAt,
+			"expiresAt":  s.ExpiresAt,
+			"current":    s.ID == current,
+		}
+	}
+	c.JSON(http.StatusOK, data)
+}
+
+// RevokeMySession - Mencabut salah satu sesi milik user (logout dari perangkat lain)
+func RevokeMySession(c *gin.Context) {
+	uid, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id, _ := strconv.Atoi(c.Param("id"))
+	if err := services.RevokeUserSession(uid, uint(id), services.SessionRevoked); err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mencabut sesi"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Sesi berhasil dicabut"})
+}
+
+// RevokeOtherSessions - Mencabut semua sesi user selain sesi yang sedang dipakai
+func RevokeOtherSessions(c *gin.Context) {
+	uid, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	revoked, err := services.RevokeUserSessions(uid, currentSessionID(c), services.SessionRevoked)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mencabut sesi"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Sesi lain berhasil dicabut", "revoked": revoked})
+}
@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/imam/backend-blog-kuis/config"
+	"github.com/imam/backend-blog-kuis/models"
+)
+
+// reviewerID mengembalikan ID admin yang sedang login untuk dicatat sebagai reviewer
+func reviewerID(c *gin.Context) *uint {
+	uid, ok := currentUserID(c)
+	if !ok {
+		return nil
+	}
+	return &uid
+}
+
+// loadSubmissionForThread mengambil submission yang boleh diakses user (pemilik atau admin)
+func loadSubmissionForThread(c *gin.Context) (models.Submission, bool) {
+	var submission models.Submission
+	uid, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return submission, false
+	}
+
+	if err := config.DB.First(&submission, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Submission tidak ditemukan"})
+		return submission, false
+	}
+
+	if submission.UserID != uid && !isAdminRole(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Anda tidak memiliki akses ke submission ini"})
+		return submission, false
+	}
+
+	return submission, true
+}
+
+// toSubmissionComments mengubah komentar submission menjadi response beserta info penulis
+func toSubmissionComments(comments []models.SubmissionComment) []models.SubmissionCommentWithUser {
+	results := []models.SubmissionCommentWithUser{}
+	for _, comment := range comments {
+		results = append(results, models.SubmissionCommentWithUser{
+			ID:           comment.ID,
+			SubmissionID: comment.SubmissionID,
+			UserID:       comment.UserID,
+			Username:     comment.User.Username,
+			Role:         comment.User.Role,
+			Content:      comment.Content,
+			CreatedAt:    comment.CreatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+	return results
+}
+
+// GetMySubmissions - Riwayat submission project milik user untuk sebuah modul
+func GetMySubmissions(c *gin.Context) {
+	uid, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var submissions []models.Submission
+	db := config.DB.Where("user_id = ?", uid)
+	if lessonID := c.Query("lessonId"); lessonID != "" {
+		db = db.Where("quiz_id = ?", lessonID)
+	}
+	db.Order("quiz_id ASC, attempt DESC").Find(&submissions)
+
+	c.JSON(http.StatusOK, submissions)
+}
+
+// GetSubmissionHistory - Seluruh versi submission untuk satu progres project (Admin)
+func GetSubmissionHistory(c *gin.Context) {
+	var submissions []models.Submission
+	config.DB.Where("progress_id = ?", c.Param("id")).
+		Order("attempt DESC").
+		Find(&submissions)
+
+	c.JSON(http.StatusOK, submissions)
+}
+
+// GetSubmissionComments - Thread diskusi pada satu versi submission
+func GetSubmissionComments(c *gin.Context) {
+	submission, ok := loadSubmissionForThread(c)
+	if !ok {
+		return
+	}
+
+	var comments []models.SubmissionComment
+	config.DB.Preload("User").
+		Where("submission_id = ?", submission.ID).
+		Order("created_at ASC").
+		Find(&comments)
+
+	c.JSON(http.StatusOK, toSubmissionComments(comments))
+}
+
+// CreateSubmissionComment - Menambahkan pesan ke thread diskusi submission (pemilik atau admin)
+func CreateSubmissionComment(c *gin.Context) {
+	submission, ok := loadSubmissionForThread(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Content string `json:"content" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || strings.TrimSpace(input.Content) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Isi komentar diperlukan"})
+		return
+	}
+
+	uid, _ := currentUserID(c)
+	comment := models.SubmissionComment{
+		SubmissionID: submission.ID,
+		UserID:       uid,
+		Content:      strings.TrimSpace(input.Content),
+	}
+	if err := config.DB.Create(&comment).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan komentar"})
+		return
+	}
+
+	config.DB.Preload("User").First(&comment, comment.ID)
+	c.JSON(http.StatusCreated, toSubmissionComments([]models.SubmissionComment{comment})[0])
+}
@@ -138,6 +138,6 @@ func buildNewsletterEmail(post models.Post) string {
 }
 
 func frontendURL() string {
-	// Uses the same domain for the link in email — adjust if needed
-	return "https://aiotchain.vercel.app"
+	// Uses the same domain for the link in email — configurable via FRONTEND_URL
+	return utils.FrontendURL()
 }
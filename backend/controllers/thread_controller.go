@@ -6,6 +6,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/imam/backend-blog-kuis/config"
+	"github.com/imam/backend-blog-kuis/middleware"
 	"github.com/imam/backend-blog-kuis/models"
 	"github.com/imam/backend-blog-kuis/utils"
 )
@@ -51,23 +52,12 @@ func GetThreadByID(c *gin.Context) {
 
 // Create Thread
 func CreateThread(c *gin.Context) {
-	userIDRaw, exists := c.Get("user_id")
-	if !exists {
+	userID, ok := currentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	var userID uint
-	switch v := userIDRaw.(type) {
-	case float64:
-		userID = uint(v)
-	case uint:
-		userID = v
-	default:
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
-		return
-	}
-
 	var input struct {
 		Title    string `json:"title" binding:"required,min=5,max=255"`
 		Content  string `json:"content" binding:"required"`
@@ -105,15 +95,7 @@ func CreateThread(c *gin.Context) {
 // Delete Thread
 func DeleteThread(c *gin.Context) {
 	id := c.Param("id")
-	userIDRaw, _ := c.Get("user_id")
-	userRole, _ := c.Get("role")
-
-	var userID uint
-	if v, ok := userIDRaw.(float64); ok {
-		userID = uint(v)
-	} else if v, ok := userIDRaw.(uint); ok {
-		userID = v
-	}
+	user, _ := middleware.CurrentUser(c)
 
 	var thread models.Thread
 	if err := config.DB.First(&thread, id).Error; err != nil {
@@ -122,7 +104,7 @@ func DeleteThread(c *gin.Context) {
 	}
 
 	// Only owner or admin can delete
-	if userRole != "admin" && thread.UserID != userID {
+	if user.Role != "admin" && thread.UserID != user.ID {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Anda tidak memiliki izin untuk menghapus diskusi ini"})
 		return
 	}
@@ -143,15 +125,7 @@ func DeleteThread(c *gin.Context) {
 // Update Thread
 func UpdateThread(c *gin.Context) {
 	id := c.Param("id")
-	userIDRaw, _ := c.Get("user_id")
-	userRole, _ := c.Get("role")
-
-	var userID uint
-	if v, ok := userIDRaw.(float64); ok {
-		userID = uint(v)
-	} else if v, ok := userIDRaw.(uint); ok {
-		userID = v
-	}
+	user, _ := middleware.CurrentUser(c)
 
 	var thread models.Thread
 	if err := config.DB.First(&thread, id).Error; err != nil {
@@ -160,7 +134,7 @@ func UpdateThread(c *gin.Context) {
 	}
 
 	// Only owner or admin can update
-	if userRole != "admin" && thread.UserID != userID {
+	if user.Role != "admin" && thread.UserID != user.ID {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Anda tidak memiliki izin untuk mengubah diskusi ini"})
 		return
 	}
@@ -3,10 +3,12 @@ package controllers
 import (
 	"fmt"
 	"net/http"
+	"os"
 	"path/filepath"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/imam/backend-blog-kuis/services"
 )
 
 func UploadImage(c *gin.Context) {
@@ -29,6 +31,9 @@ func UploadImage(c *gin.Context) {
 
 	// Return the relative path to the file
 	fileUrl := "/uploads/" + newFileName
+	if !recordUpload(c, fileUrl, file.Filename, file.Size) {
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"url":     fileUrl,
 		"message": "Image uploaded successfully",
@@ -50,9 +55,23 @@ func UploadFile(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to save the file"})
 		return
 	}
+	if !recordUpload(c, "/uploads/"+newFileName, file.Filename, file.Size) {
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"url":     "/uploads/" + newFileName,
 		"message": "File uploaded successfully",
 	})
 }
+
+// recordUpload mencatat pengunggah file agar submission tidak bisa memakai file milik user lain
+func recordUpload(c *gin.Context, url, originalName string, size int64) bool {
+	uid, _ := currentUserID(c)
+	if err := services.RecordUpload(uid, url, originalName, size); err != nil {
+		os.Remove("." + url)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to save the file"})
+		return false
+	}
+	return true
+}
@@ -2,10 +2,13 @@ package controllers
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/imam/backend-blog-kuis/config"
 	"github.com/imam/backend-blog-kuis/models"
+	"github.com/imam/backend-blog-kuis/services"
+	"gorm.io/gorm"
 )
 
 // GetAllUsers - Mengambil semua data pengguna untuk admin
@@ -37,7 +40,11 @@ func UpdateUserRole(c *gin.Context) {
 		return
 	}
 
-	if err := config.DB.Model(&models.User{}).Where("id = ?", id).Update("role", input.Role).Error; err != nil {
+	// token_version dinaikkan agar access token dengan role lama langsung tidak berlaku
+	if err := config.DB.Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"role":          input.Role,
+		"token_version": gorm.Expr("token_version + 1"),
+	}).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui role pengguna"})
 		return
 	}
@@ -52,5 +59,11 @@ func DeleteUser(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghapus pengguna"})
 		return
 	}
+
+	// Pengguna yang dihapus langsung kehilangan semua sesi login dan access token
+	if uid, err := strconv.Atoi(id); err == nil {
+		services.BumpTokenVersion(uint(uid))
+		services.RevokeUserSessions(uint(uid), 0, services.SessionUserDeleted)
+	}
 	c.JSON(http.StatusOK, gin.H{"message": "Pengguna berhasil dihapus"})
 }
@@ -8,6 +8,7 @@ import (
 	// Pastikan path import ini sesuai dengan nama module di go.mod Anda
 	"github.com/imam/backend-blog-kuis/config"
 	"github.com/imam/backend-blog-kuis/routes"
+	"github.com/imam/backend-blog-kuis/services"
 	"github.com/joho/godotenv"
 )
 
@@ -31,6 +32,28 @@ func main() {
 	}
 	defer sqlDB.Close()
 
+	// Lengkapi tanda tangan sertifikat yang diterbitkan sebelum fitur verifikasi ada
+	services.SignUnsignedCertificates()
+
+	// Terbitkan invoice untuk pembayaran sukses yang dibuat sebelum fitur invoice ada
+	services.IssueMissingInvoices()
+
+	// Perintah admin: go run . backfill-certificates
+	if len(os.Args) > 1 && os.Args[1] == "backfill-certificates" {
+		result := services.BackfillCertificates()
+		fmt.Printf("Backfill sertifikat selesai: %d diperiksa, %d diterbitkan, %d gagal\n", result.Checked, result.Issued, result.Failed)
+		return
+	}
+
+	// Email pemberitahuan sertifikat baru (tidak aktif saat perintah backfill)
+	services.RegisterCertificateNotifications()
+
+	// Worker penilaian otomatis untuk submission ZIP project
+	services.StartGradingWorker()
+
+	// Job expiry langganan dan pengingat perpanjangan
+	services.StartSubscriptionScheduler()
+
 	fmt.Println("Server mencoba berjalan di port :8080...")
 
 	// 2. Setup Router dari package routes
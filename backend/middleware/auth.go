@@ -5,8 +5,7 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
-	"github.com/imam/backend-blog-kuis/utils"
+	"github.com/imam/backend-blog-kuis/services"
 )
 
 func AuthMiddleware() gin.HandlerFunc {
@@ -27,38 +26,50 @@ func AuthMiddleware() gin.HandlerFunc {
 			tokenString = strings.TrimPrefix(authHeader, "Bearer ")
 		}
 
-		// 3. Parse dan Validasi Token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			// Validasi metode signing (HS256)
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrSignatureInvalid
-			}
-			return utils.GetJWTSecret(), nil
-		})
-
-		// 4. Cek apakah token valid
-		if err != nil || !token.Valid {
+		// 3. Parse dan Validasi Token (tanda tangan HS256 dan masa berlaku)
+		claims, err := services.ParseAccessToken(tokenString)
+		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token tidak valid atau kadaluwarsa"})
 			c.Abort()
 			return
 		}
 
-		// 5. Ambil data dari claims (opsional, untuk dipakai di controller)
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			// Simpan data username dan role ke context agar bisa diakses di handler/controller
-			c.Set("username", claims["username"])
-			c.Set("user_id", claims["user_id"])
-			c.Set("role", claims["role"])
+		// 4. Tolak token yang sesinya sudah dicabut atau versinya sudah usang (role diubah, user dihapus)
+		if err := services.ValidateTokenClaims(claims); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Sesi sudah berakhir, silakan login ulang"})
+			c.Abort()
+			return
 		}
 
+		// 5. Simpan user ke context untuk dipakai di controller (lihat CurrentUser)
+		setCurrentUser(c, claims)
+
 		c.Next() // Lanjut ke handler berikutnya
 	}
 }
 
+// OptionalAuth middleware - seperti AuthMiddleware tetapi tidak menolak request tanpa token.
+// Dipakai pada route publik yang menampilkan data berbeda untuk user yang login.
+func OptionalAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenString := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if tokenString == "" {
+			c.Next()
+			return
+		}
+
+		if claims, err := services.ParseAccessToken(tokenString); err == nil && services.ValidateTokenClaims(claims) == nil {
+			setCurrentUser(c, claims)
+		}
+
+		c.Next()
+	}
+}
+
 // AdminOnly middleware - mengizinkan user dengan role 'admin' atau 'super_admin'
 func AdminOnly() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		role, exists := c.Get("role")
+		user, exists := CurrentUser(c)
 
 		if !exists {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Role tidak ditemukan"})
@@ -67,7 +78,7 @@ func AdminOnly() gin.HandlerFunc {
 		}
 
 		// Izinkan admin dan super_admin
-		if role != "admin" && role != "super_admin" {
+		if !user.IsAdmin() {
 			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden: Hanya admin yang dapat mengakses"})
 			c.Abort()
 			return
@@ -80,7 +91,7 @@ func AdminOnly() gin.HandlerFunc {
 // SuperAdminOnly middleware - hanya mengizinkan user dengan role 'super_admin'
 func SuperAdminOnly() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		role, exists := c.Get("role")
+		user, exists := CurrentUser(c)
 
 		if !exists {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Role tidak ditemukan"})
@@ -88,7 +99,7 @@ func SuperAdminOnly() gin.HandlerFunc {
 			return
 		}
 
-		if role != "super_admin" {
+		if user.Role != "super_admin" {
 			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden: Hanya Super Admin yang dapat mengakses"})
 			c.Abort()
 			return
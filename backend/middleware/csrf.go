@@ -19,8 +19,12 @@ var exemptCSRFPaths = map[string]bool{
 	"/api/auth/google":          true,
 	"/api/auth/forgot-password": true,
 	"/api/auth/reset-password":  true,
+	"/api/auth/refresh":         true,
+	"/api/auth/logout":          true,
 	"/api/contact":              true,
 	"/api/subscribe":            true,
+	// Webhook server-to-server dari payment gateway, diamankan dengan signature notifikasi
+	"/api/payments/notification": true,
 }
 
 func CSRFMiddleware() gin.HandlerFunc {
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/imam/backend-blog-kuis/services"
+)
+
+// currentUserKey adalah key context untuk user yang sedang login
+const currentUserKey = "current_user"
+
+// AuthUser adalah user yang sedang login sesuai claims access token
+type AuthUser struct {
+	ID        uint
+	Username  string
+	Role      string
+	SessionID uint
+}
+
+// IsAdmin menandakan user adalah admin atau super_admin
+func (u AuthUser) IsAdmin() bool {
+	return u.Role == "admin" || u.Role == "super_admin"
+}
+
+// setCurrentUser menyimpan user dari claims ke context agar bisa diakses di handler/controller
+func setCurrentUser(c *gin.Context, claims *services.TokenClaims) {
+	c.Set(currentUserKey, AuthUser{
+		ID:        claims.UserID,
+		Username:  claims.Username,
+		Role:      claims.Role,
+		SessionID: claims.SessionID,
+	})
+}
+
+// CurrentUser mengambil user yang sedang login dari context (diset oleh AuthMiddleware/OptionalAuth)
+func CurrentUser(c *gin.Context) (AuthUser, bool) {
+	value, exists := c.Get(currentUserKey)
+	if !exists {
+		return AuthUser{}, false
+	}
+	user, ok := value.(AuthUser)
+	return user, ok
+}
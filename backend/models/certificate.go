@@ -4,12 +4,25 @@ import "time"
 
 type Certificate struct {
 	ID             uint      `gorm:"primaryKey" json:"id"`
-	UserID         uint      `gorm:"not null;index" json:"userId"`
-	LearningPathID uint      `gorm:"not null;index" json:"learningPathId"`
+	UserID         uint      `gorm:"not null;uniqueIndex:idx_certificate_user_path" json:"userId"`
+	LearningPathID uint      `gorm:"not null;uniqueIndex:idx_certificate_user_path;index" json:"learningPathId"`
 	CertificateID  string    `gorm:"type:varchar(100);unique;not null" json:"certificateId"`
 	IssuedAt       time.Time `json:"issuedAt"`
+	// Tanda tangan Ed25519 atas Payload (JSON) agar sertifikat bisa diverifikasi offline
+	Payload   string `gorm:"type:text" json:"payload"`
+	Signature string `gorm:"type:varchar(128)" json:"signature"`
+	// Pencabutan bersifat soft: baris tetap disimpan sebagai jejak audit
+	RevokedAt     *time.Time `gorm:"index" json:"revokedAt"`
+	RevokedReason string     `gorm:"type:text" json:"revokedReason"`
+	RevokedByID   *uint      `json:"revokedById"`
 
 	// Relationships
 	User         User         `gorm:"foreignKey:UserID" json:"-"`
 	LearningPath LearningPath `gorm:"foreignKey:LearningPathID" json:"-"`
+	RevokedBy    *User        `gorm:"foreignKey:RevokedByID" json:"-"`
+}
+
+// IsRevoked memeriksa apakah sertifikat sudah dicabut
+func (c Certificate) IsRevoked() bool {
+	return c.RevokedAt != nil
 }
@@ -2,18 +2,51 @@ package models
 
 import "time"
 
+// Jenis field yang bisa diletakkan pada template sertifikat
+const (
+	CertFieldName                = "name"
+	CertFieldDate                = "date"
+	CertFieldID                  = "id"
+	CertFieldPathTitle           = "path_title"
+	CertFieldInstructorSignature = "instructor_signature"
+	CertFieldScore               = "score"
+)
+
+// CertificateTemplate adalah desain sertifikat yang bisa dipakai oleh satu atau lebih learning path.
+// Template dengan Active = true menjadi template bawaan untuk path yang belum diberi template.
 type CertificateTemplate struct {
-	ID              uint      `gorm:"primaryKey" json:"id"`
-	BackgroundImage string    `gorm:"type:text" json:"backgroundImage"`
-	PrimaryColor    string    `gorm:"type:varchar(50);default:'#2563eb'" json:"primaryColor"` // default blue-600
-	CertPdfURL      string    `gorm:"type:text" json:"certPdfUrl"`
-	CertNameX       float64   `gorm:"default:0" json:"certNameX"`
-	CertNameY       float64   `gorm:"default:0" json:"certNameY"`
-	CertDateX       float64   `gorm:"default:0" json:"certDateX"`
-	CertDateY       float64   `gorm:"default:0" json:"certDateY"`
-	CertIdX         float64   `gorm:"default:0" json:"certIdX"`
-	CertIdY         float64   `gorm:"default:0" json:"certIdY"`
-	CertFontSize    int       `gorm:"default:30" json:"certFontSize"`
-	Active          bool      `gorm:"default:true" json:"active"`
-	UpdatedAt       time.Time `json:"updatedAt"`
+	ID              uint               `gorm:"primaryKey" json:"id"`
+	Name            string             `gorm:"type:varchar(255);not null;default:''" json:"name"`
+	BackgroundImage string             `gorm:"type:text" json:"backgroundImage"`
+	PrimaryColor    string             `gorm:"type:varchar(50);default:'#2563eb'" json:"primaryColor"` // default blue-600
+	CertPdfURL      string             `gorm:"type:text" json:"certPdfUrl"`
+	CertFontSize    int                `gorm:"default:30" json:"certFontSize"`
+	Fields          []CertificateField `gorm:"type:text;serializer:json" json:"fields"`
+	Active          bool               `gorm:"default:false" json:"active"`
+	CreatedAt       time.Time          `json:"createdAt"`
+	UpdatedAt       time.Time          `json:"updatedAt"`
+}
+
+// CertificateField adalah satu elemen yang diposisikan pada sertifikat.
+// Koordinat memakai sistem PDF (titik asal di kiri bawah, satuan point).
+type CertificateField struct {
+	Type     string  `json:"type" binding:"required,oneof=name date id path_title instructor_signature score"`
+	X        float64 `json:"x"`
+	Y        float64 `json:"y"`
+	FontSize int     `json:"fontSize,omitempty"` // 0 = ukuran bawaan template
+	Color    string  `json:"color,omitempty"`    // Kosong = hitam (warna utama untuk path_title)
+	Bold     bool    `json:"bold,omitempty"`
+	Label    string  `json:"label,omitempty"`    // Teks awalan, atau nama instruktur untuk tanda tangan
+	ImageURL string  `json:"imageUrl,omitempty"` // Gambar tanda tangan instruktur (PNG/JPG)
+	Width    float64 `json:"width,omitempty"`    // Lebar gambar tanda tangan
+}
+
+// Field mengembalikan field pertama dengan jenis tertentu
+func (t CertificateTemplate) Field(fieldType string) (CertificateField, bool) {
+	for _, f := range t.Fields {
+		if f.Type == fieldType {
+			return f, true
+		}
+	}
+	return CertificateField{}, false
 }
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Cohort adalah kelas angkatan pada sebuah learning path. Bab dibuka bertahap
+// berdasarkan StartDate ditambah Chapter.UnlockOffsetDays.
+type Cohort struct {
+	ID                 uint       `gorm:"primaryKey" json:"id"`
+	LearningPathID     uint       `gorm:"not null;index" json:"learningPathId" binding:"required"`
+	Name               string     `gorm:"type:varchar(255);not null" json:"name" binding:"required,min=3,max=255"`
+	StartDate          time.Time  `gorm:"not null" json:"startDate" binding:"required"`
+	EnrollmentOpensAt  *time.Time `json:"enrollmentOpensAt"`
+	EnrollmentClosesAt *time.Time `json:"enrollmentClosesAt"`
+	Capacity           int        `gorm:"default:0" json:"capacity" binding:"min=0"` // 0 berarti tanpa batas
+	CreatedAt          time.Time  `json:"createdAt"`
+	UpdatedAt          time.Time  `json:"updatedAt"`
+
+	// Relationships
+	LearningPath LearningPath `gorm:"foreignKey:LearningPathID;constraint:OnDelete:CASCADE" json:"-"`
+	Instructors  []User       `gorm:"many2many:cohort_instructors;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// CohortInstructor adalah ringkasan instruktur untuk response API
+type CohortInstructor struct {
+	ID             uint   `json:"id"`
+	Username       string `json:"username"`
+	ProfilePicture string `json:"profilePicture"`
+}
+
+// EnrollmentOpen memeriksa apakah pendaftaran cohort sedang dibuka pada waktu now
+func (c Cohort) EnrollmentOpen(now time.Time) bool {
+	if c.EnrollmentOpensAt != nil && now.Before(*c.EnrollmentOpensAt) {
+		return false
+	}
+	if c.EnrollmentClosesAt != nil && now.After(*c.EnrollmentClosesAt) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// Coupon adalah kode promo yang memberi potongan harga saat checkout
+type Coupon struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	Code           string     `gorm:"type:varchar(50);uniqueIndex;not null" json:"code" binding:"required,max=50"`
+	Description    string     `gorm:"type:text" json:"description"`
+	DiscountType   string     `gorm:"type:varchar(20);not null" json:"discountType" binding:"required,oneof=percent fixed"`
+	DiscountValue  int64      `gorm:"not null" json:"discountValue" binding:"required,min=1"` // Persen (1-100) atau rupiah
+	MaxDiscount    int64      `gorm:"default:0" json:"maxDiscount"`                           // Batas potongan untuk tipe percent, 0 = tanpa batas
+	ValidFrom      *time.Time `json:"validFrom"`
+	ValidUntil     *time.Time `json:"validUntil"`
+	MaxRedemptions int        `gorm:"default:0" json:"maxRedemptions"`          // 0 = tanpa batas
+	PerUserLimit   int        `gorm:"default:1" json:"perUserLimit"`            // 0 = tanpa batas
+	PlanIDs        []uint     `gorm:"type:text;serializer:json" json:"planIds"` // Kosong = berlaku untuk semua paket
+	Active         bool       `gorm:"default:true" json:"active"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+}
+
+// CouponRedemption mencatat pemakaian kupon pada sebuah pembayaran.
+// Status pending selama pembayaran belum selesai, redeemed saat sukses, released saat gagal, refunded saat direfund penuh.
+type CouponRedemption struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CouponID  uint      `gorm:"not null;index" json:"couponId"`
+	UserID    uint      `gorm:"not null;index" json:"userId"`
+	PaymentID uint      `gorm:"not null;uniqueIndex" json:"paymentId"`
+	Code      string    `gorm:"type:varchar(50)" json:"code"`
+	Discount  int64     `json:"discount"`
+	Status    string    `gorm:"type:varchar(20);default:'pending';index" json:"status"` // pending, redeemed, released, refunded
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+
+	// Relationships
+	User    User    `gorm:"foreignKey:UserID" json:"-"`
+	Payment Payment `gorm:"foreignKey:PaymentID" json:"-"`
+}
+
+// CreditTransaction adalah mutasi saldo kredit user (positif = masuk, negatif = dipakai).
+// Saldo adalah jumlah seluruh mutasi milik user.
+type CreditTransaction struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	UserID    uint   `gorm:"not null;index" json:"userId"`
+	Amount    int64  `gorm:"not null" json:"amount"`
+	Reason    string `gorm:"type:varchar(30);not null" json:"reason"` // referral_reward, referral_reversal, checkout, checkout_release, refund_release
+	PaymentID *uint  `gorm:"index" json:"paymentId"`
+	// ReferredUserID hanya diisi untuk referral_reward; unique agar satu user hanya memberi satu reward
+	ReferredUserID *uint     `gorm:"uniqueIndex" json:"referredUserId"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
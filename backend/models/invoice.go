@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// Invoice adalah kuitansi bernomor urut yang diterbitkan saat pembayaran sukses.
+// Data pembeli dan harga disalin saat terbit agar kuitansi tidak berubah jika user/paket diubah.
+type Invoice struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	Number         string     `gorm:"type:varchar(30);uniqueIndex;not null" json:"number"` // INV-2026-000001
+	PaymentID      uint       `gorm:"not null;uniqueIndex" json:"payment_id"`
+	UserID         uint       `gorm:"not null;index" json:"user_id"`
+	BilledName     string     `json:"billed_name"`
+	BilledEmail    string     `json:"billed_email"`
+	Description    string     `json:"description"`
+	OriginalAmount int64      `json:"original_amount"`
+	DiscountAmount int64      `json:"discount_amount"`
+	CreditUsed     int64      `json:"credit_used"`
+	Amount         int64      `json:"amount"`
+	CouponCode     string     `gorm:"type:varchar(50)" json:"coupon_code"`
+	IssuedAt       time.Time  `gorm:"index" json:"issued_at"`
+	EmailedAt      *time.Time `json:"emailed_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+
+	// Relationships
+	User    User    `gorm:"foreignKey:UserID" json:"-"`
+	Payment Payment `gorm:"foreignKey:PaymentID" json:"payment,omitempty"`
+}
+
+// InvoiceCounter menyimpan nomor invoice terakhir per tahun agar penomoran berurutan tanpa celah
+type InvoiceCounter struct {
+	Year       int `gorm:"primaryKey;autoIncrement:false"`
+	LastNumber int `gorm:"not null;default:0"`
+}
+
+// PaymentRefund mencatat pengembalian dana (sebagian atau penuh) atas sebuah pembayaran
+type PaymentRefund struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	PaymentID uint      `gorm:"not null;index" json:"payment_id"`
+	Amount    int64     `gorm:"not null" json:"amount"`
+	Reason    string    `gorm:"type:text" json:"reason"`
+	Status    string    `gorm:"size:20;not null;default:completed;index" json:"status"`
+	AdminID   *uint     `json:"admin_id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	Admin *User `gorm:"foreignKey:AdminID" json:"admin,omitempty"`
+}
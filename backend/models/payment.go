@@ -2,6 +2,8 @@
 package models
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
@@ -10,9 +12,23 @@ type Payment struct {
 	UserID        uint   `json:"user_id"`
 	User          User   `gorm:"foreignKey:UserID" json:"user"`
 	OrderID       string `gorm:"uniqueIndex" json:"order_id"`
-	Amount        int64  `json:"amount"`
-	Status        string `gorm:"default:'pending'" json:"status"` // pending, settlement, cancel, deny, expire
+	Amount        int64  `json:"amount"`                          // Nominal yang ditagihkan setelah diskon dan kredit
+	Status        string `gorm:"default:'pending'" json:"status"` // pending, success, challenge, deny, failure, partial_refund, refund
 	PaymentType   string `json:"payment_type"`
+	PlanID        *uint  `gorm:"index" json:"plan_id"`
+	Gateway       string `gorm:"type:varchar(20);default:'midtrans'" json:"gateway"`
 	SnapURL       string `json:"snap_url"`
 	TransactionID string `json:"transaction_id"`
+	// Rincian harga: OriginalAmount - DiscountAmount - CreditUsed = Amount
+	OriginalAmount int64  `json:"original_amount"`
+	DiscountAmount int64  `json:"discount_amount"`
+	CreditUsed     int64  `json:"credit_used"`
+	CouponCode     string `gorm:"type:varchar(50)" json:"coupon_code"`
+	// PaidAt diisi saat pembayaran pertama kali berstatus success
+	PaidAt *time.Time `json:"paid_at"`
+	// RefundedAmount adalah total dana yang sudah dikembalikan
+	RefundedAmount int64 `gorm:"default:0" json:"refunded_amount"`
+	// PaymentMethod adalah metode yang dipakai user di gateway (mis. bank_transfer, gopay),
+	// diisi dari notifikasi atau pengecekan status; PaymentType berisi kode paket
+	PaymentMethod string `gorm:"type:varchar(50);index" json:"payment_method"`
 }
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// PaymentNotification menyimpan setiap notifikasi mentah dari payment gateway beserta hasil pemrosesannya
+type PaymentNotification struct {
+	ID                uint      `gorm:"primaryKey" json:"id"`
+	OrderID           string    `gorm:"type:varchar(100);index" json:"order_id"`
+	TransactionID     string    `gorm:"type:varchar(100)" json:"transaction_id"`
+	TransactionStatus string    `gorm:"type:varchar(50)" json:"transaction_status"`
+	StatusCode        string    `gorm:"type:varchar(10)" json:"status_code"`
+	GrossAmount       string    `gorm:"type:varchar(50)" json:"gross_amount"`
+	SignatureValid    bool      `gorm:"default:false" json:"signature_valid"`
+	Result            string    `gorm:"type:varchar(30);index" json:"result"` // processed, duplicate, rejected, error
+	Message           string    `gorm:"type:text" json:"message"`
+	RawBody           string    `gorm:"type:text" json:"raw_body"`
+	RemoteIP          string    `gorm:"type:varchar(64)" json:"remote_ip"`
+	CreatedAt         time.Time `json:"created_at"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// PeerReviewConfig mengaktifkan penilaian antar peserta pada modul project
+type PeerReviewConfig struct {
+	Reviewers    int               `json:"reviewers"`    // Jumlah submission yang dinilai tiap peserta sekaligus jumlah review per submission
+	PassingScore int               `json:"passingScore"` // Persentase rata-rata rubrik minimum agar disetujui
+	Rubric       []RubricCriterion `json:"rubric"`
+}
+
+// RubricCriterion adalah satu kriteria penilaian pada rubrik peer review
+type RubricCriterion struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	MaxScore    int    `json:"maxScore"`
+}
+
+// RubricScore adalah nilai yang diberikan reviewer untuk satu kriteria
+type RubricScore struct {
+	CriterionID string `json:"criterionId"`
+	Score       int    `json:"score"`
+}
+
+// PeerReview adalah penugasan seorang peserta untuk menilai submission peserta lain
+type PeerReview struct {
+	ID          uint          `gorm:"primaryKey" json:"id"`
+	ProgressID  uint          `gorm:"not null;uniqueIndex:idx_review_reviewer" json:"submissionId"` // UserProgress yang dinilai
+	ReviewerID  uint          `gorm:"not null;uniqueIndex:idx_review_reviewer;index" json:"reviewerId"`
+	QuizID      uint          `gorm:"not null;index" json:"lessonId"`
+	Status      string        `gorm:"type:varchar(20);default:'assigned'" json:"status"` // assigned, completed
+	Scores      []RubricScore `gorm:"type:text;serializer:json" json:"scores"`
+	TotalScore  int           `gorm:"default:0" json:"totalScore"`
+	MaxScore    int           `gorm:"default:0" json:"maxScore"`
+	Comment     string        `gorm:"type:text" json:"comment"`
+	CompletedAt *time.Time    `json:"completedAt"`
+	CreatedAt   time.Time     `json:"createdAt"`
+
+	// Relationships
+	Progress UserProgress `gorm:"foreignKey:ProgressID;constraint:OnDelete:CASCADE;" json:"-"`
+	Reviewer User         `gorm:"foreignKey:ReviewerID" json:"-"`
+}
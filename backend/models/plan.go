@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Plan adalah paket langganan yang bisa dibeli (harga dalam rupiah, durasi dalam hari)
+type Plan struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	Code         string    `gorm:"type:varchar(50);uniqueIndex;not null" json:"code" binding:"required,max=50"`
+	Name         string    `gorm:"type:varchar(100);not null" json:"name" binding:"required,max=100"`
+	Description  string    `gorm:"type:text" json:"description"`
+	Price        int64     `gorm:"not null" json:"price" binding:"required,min=1"`
+	DurationDays int       `gorm:"not null" json:"durationDays" binding:"required,min=1"`
+	Features     []string  `gorm:"type:text;serializer:json" json:"features"`
+	Active       bool      `gorm:"default:true" json:"active"`
+	SortOrder    int       `gorm:"default:0" json:"sortOrder"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// LessonPrerequisite menyatakan bahwa QuizID baru terbuka setelah RequiredQuizID selesai
+type LessonPrerequisite struct {
+	ID             uint `gorm:"primaryKey" json:"id"`
+	QuizID         uint `gorm:"not null;uniqueIndex:idx_lesson_prerequisite" json:"lessonId"`
+	RequiredQuizID uint `gorm:"not null;uniqueIndex:idx_lesson_prerequisite" json:"requiredLessonId"`
+
+	// Relationships
+	Quiz         Quiz `gorm:"foreignKey:QuizID;constraint:OnDelete:CASCADE" json:"-"`
+	RequiredQuiz Quiz `gorm:"foreignKey:RequiredQuizID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// PathPrerequisite menyatakan bahwa PathID baru terbuka setelah RequiredPathID diselesaikan
+type PathPrerequisite struct {
+	ID             uint `gorm:"primaryKey" json:"id"`
+	PathID         uint `gorm:"not null;uniqueIndex:idx_path_prerequisite" json:"pathId"`
+	RequiredPathID uint `gorm:"not null;uniqueIndex:idx_path_prerequisite" json:"requiredPathId"`
+
+	// Relationships
+	Path         LearningPath `gorm:"foreignKey:PathID;constraint:OnDelete:CASCADE" json:"-"`
+	RequiredPath LearningPath `gorm:"foreignKey:RequiredPathID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// LessonLock adalah status kunci sebuah materi untuk user tertentu
+type LessonLock struct {
+	Locked   bool       `json:"locked"`
+	Reason   string     `json:"lockReason,omitempty"`
+	UnlockAt *time.Time `json:"unlockAt,omitempty"` // Diisi jika materi terbuka otomatis sesuai jadwal cohort
+}
@@ -0,0 +1,13 @@
+package models
+
+// ProjectGradingSpec adalah aturan penilaian otomatis untuk pengumpulan ZIP pada modul project
+type ProjectGradingSpec struct {
+	RequiredFiles   []string `json:"requiredFiles,omitempty"`   // Path file yang wajib ada di dalam ZIP
+	FilePatterns    []string `json:"filePatterns,omitempty"`    // Pola nama file (glob), masing-masing harus cocok minimal 1 file
+	MaxSizeMB       int      `json:"maxSizeMb,omitempty"`       // Ukuran maksimum file ZIP
+	ManifestFile    string   `json:"manifestFile,omitempty"`    // Mis. "manifest.json"
+	ManifestEntries []string `json:"manifestEntries,omitempty"` // Key (JSON, boleh bertitik) atau teks yang wajib ada di manifest
+	Command         string   `json:"command,omitempty"`         // Perintah yang dijalankan di dalam sandbox terhadap isi ZIP
+	TimeoutSeconds  int      `json:"timeoutSeconds,omitempty"`
+	AutoApprove     bool     `json:"autoApprove"` // Setujui otomatis jika semua pemeriksaan lulus
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// QuestionBank adalah kumpulan soal yang bisa dipakai ulang oleh banyak kuis
+type QuestionBank struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	Title       string     `gorm:"type:varchar(255);not null" json:"title" binding:"required,min=3,max=255"`
+	Topic       string     `gorm:"type:varchar(100);index" json:"topic" binding:"required"`
+	Description string     `gorm:"type:text" json:"description"`
+	Questions   []Question `gorm:"foreignKey:BankID;constraint:OnDelete:CASCADE;" json:"questions,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+// QuizDrawRule menentukan berapa soal yang diambil acak dari sebuah bank (mis. 5 soal Mudah dari bank X)
+type QuizDrawRule struct {
+	BankID     uint   `json:"bankId"`
+	Difficulty string `json:"difficulty,omitempty"` // Kosong berarti semua tingkat kesulitan
+	Count      int    `json:"count"`
+}
+
+// AttemptQuestion adalah salinan soal yang diundi untuk satu QuizAttempt beserta urutan opsinya
+type AttemptQuestion struct {
+	Question    Question `json:"question"`
+	OptionOrder []string `json:"optionOrder,omitempty"`
+	MatchOrder  []string `json:"matchOrder,omitempty"`
+}
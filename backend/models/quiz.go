@@ -4,36 +4,81 @@ import "time"
 
 // Quiz representasi tabel kuis
 type Quiz struct {
-	ID                   uint          `gorm:"primaryKey" json:"id"`
-	PathID               uint          `json:"pathId" binding:"required"`
-	LearningPath         *LearningPath `gorm:"foreignKey:PathID" json:"learningPath,omitempty"`
-	ChapterID            uint          `json:"chapterId" binding:"required"`
-	Title                string        `gorm:"type:varchar(255);not null" json:"title" binding:"required,min=3,max=255"`
-	Description          string        `gorm:"type:text" json:"description"`
-	Type                 string        `gorm:"type:varchar(50);default:'quiz'" json:"type" binding:"required,oneof=material quiz project"` // material, quiz, project
-	Order                int           `gorm:"default:0" json:"order"`
-	Content              string        `gorm:"type:text" json:"content" binding:"required_if=Type material,required_if=Type project"` // Deskripsi/Instruksi
-	VideoURL             string        `gorm:"type:varchar(255)" json:"videoUrl"`                                                     // Link YouTube
-	Difficulty           string        `gorm:"type:varchar(50);default:'Sedang'" json:"difficulty"`
-	ProjectFileURL       string        `gorm:"type:varchar(255)" json:"projectFileUrl"`   // Link ke file ZIP
-	ProjectDriveLink     string        `gorm:"type:varchar(255)" json:"projectDriveLink"` // Link Google Drive
-	AllowZipSubmission   bool          `gorm:"default:false" json:"allowZipSubmission"`   // Izinkan pengumpulan file ZIP
-	AllowDriveSubmission bool          `gorm:"default:false" json:"allowDriveSubmission"` // Izinkan pengumpulan link Google Drive
-	PdfURL               string        `gorm:"type:text" json:"pdfUrl"`                   // Opsional upload PDF untuk materi
-
-	Duration  int        `json:"duration"`
-	Questions []Question `gorm:"foreignKey:QuizID;constraint:OnDelete:CASCADE;" json:"questions,omitempty"`
-	CreatedAt time.Time  `json:"createdAt"`
-	UpdatedAt time.Time  `json:"updatedAt"`
+	ID                   uint                `gorm:"primaryKey" json:"id"`
+	PathID               uint                `json:"pathId" binding:"required"`
+	LearningPath         *LearningPath       `gorm:"foreignKey:PathID" json:"learningPath,omitempty"`
+	ChapterID            uint                `json:"chapterId" binding:"required"`
+	Title                string              `gorm:"type:varchar(255);not null" json:"title" binding:"required,min=3,max=255"`
+	Description          string              `gorm:"type:text" json:"description"`
+	Type                 string              `gorm:"type:varchar(50);default:'quiz'" json:"type" binding:"required,oneof=material quiz project"` // material, quiz, project
+	Order                int                 `gorm:"default:0" json:"order"`
+	Content              string              `gorm:"type:text" json:"content" binding:"required_if=Type material,required_if=Type project"` // Deskripsi/Instruksi
+	VideoURL             string              `gorm:"type:varchar(255)" json:"videoUrl"`                                                     // Link YouTube
+	Difficulty           string              `gorm:"type:varchar(50);default:'Sedang'" json:"difficulty"`
+	ProjectFileURL       string              `gorm:"type:varchar(255)" json:"projectFileUrl"`                // Link ke file ZIP
+	ProjectDriveLink     string              `gorm:"type:varchar(255)" json:"projectDriveLink"`              // Link Google Drive
+	AllowZipSubmission   bool                `gorm:"default:false" json:"allowZipSubmission"`                // Izinkan pengumpulan file ZIP
+	AllowDriveSubmission bool                `gorm:"default:false" json:"allowDriveSubmission"`              // Izinkan pengumpulan link Google Drive
+	PdfURL               string              `gorm:"type:text" json:"pdfUrl"`                                // Opsional upload PDF untuk materi
+	GradingSpec          *ProjectGradingSpec `gorm:"type:text;serializer:json" json:"gradingSpec,omitempty"` // Penilaian otomatis ZIP (opsional)
+	PeerReview           *PeerReviewConfig   `gorm:"type:text;serializer:json" json:"peerReview,omitempty"`  // Penilaian antar peserta (opsional)
+
+	Duration        int            `json:"duration"`                      // Durasi pengerjaan dalam menit
+	ExamMode        bool           `gorm:"default:false" json:"examMode"` // Batas waktu Duration ditegakkan di server
+	CooldownMinutes *int           `json:"cooldownMinutes"`               // Kosong berarti memakai cooldown bawaan
+	Questions       []Question     `gorm:"foreignKey:QuizID;constraint:OnDelete:CASCADE;" json:"questions,omitempty"`
+	DrawRules       []QuizDrawRule `gorm:"type:text;serializer:json" json:"drawRules,omitempty"` // Soal acak dari bank soal
+	CreatedAt       time.Time      `json:"createdAt"`
+	UpdatedAt       time.Time      `json:"updatedAt"`
 }
 
+// Tingkat kesulitan soal
+var QuestionDifficulties = []string{"Mudah", "Sedang", "Sulit"}
+
+// Jenis soal yang didukung
+const (
+	QuestionKindSingleChoice   = "single_choice"
+	QuestionKindMultipleChoice = "multiple_choice"
+	QuestionKindTrueFalse      = "true_false"
+	QuestionKindShortText      = "short_text"
+	QuestionKindNumeric        = "numeric"
+	QuestionKindOrdering       = "ordering"
+	QuestionKindMatching       = "matching"
+)
+
 // Question representasi tabel pertanyaan
 type Question struct {
 	ID           uint   `gorm:"primaryKey" json:"id"`
-	QuizID       uint   `json:"quizId" binding:"required"`
+	QuizID       *uint  `gorm:"index" json:"quizId"` // Kosong jika soal berada di bank soal
+	BankID       *uint  `gorm:"index" json:"bankId"`
+	Difficulty   string `gorm:"type:varchar(20);default:'Sedang'" json:"difficulty"`
+	Kind         string `gorm:"type:varchar(30);default:'single_choice'" json:"kind"`
 	QuestionText string `gorm:"type:text;not null" json:"questionText" binding:"required"`
-	// Kita simpan options sebagai JSON atau string dipisahkan koma
-	Options       string    `gorm:"type:text" json:"options" binding:"required"`
-	CorrectAnswer string    `gorm:"type:varchar(255);not null" json:"correctAnswer" binding:"required"`
-	CreatedAt     time.Time `json:"createdAt"`
+	// Options disimpan sebagai JSON dengan ID yang stabil (kolom "choices")
+	Options     []QuestionOption `gorm:"column:choices;type:text;serializer:json" json:"options"`
+	Matches     []QuestionOption `gorm:"type:text;serializer:json" json:"matches,omitempty"` // Sisi kanan untuk soal matching
+	Answer      QuestionAnswer   `gorm:"type:text;serializer:json" json:"answer"`
+	Points      int              `gorm:"default:1" json:"points"`
+	Explanation string           `gorm:"type:text" json:"explanation"`
+
+	// Kolom lama (opsi dipisahkan koma), hanya dibaca saat migrasi
+	LegacyOptions       string `gorm:"column:options;type:text" json:"-"`
+	LegacyCorrectAnswer string `gorm:"column:correct_answer;type:varchar(255)" json:"-"`
+
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// QuestionOption adalah satu pilihan jawaban dengan ID yang stabil
+type QuestionOption struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+}
+
+// QuestionAnswer menyimpan kunci jawaban sesuai jenis soal
+type QuestionAnswer struct {
+	OptionIDs []string          `json:"optionIds,omitempty"` // single/multiple choice, true_false, urutan benar untuk ordering
+	Patterns  []string          `json:"patterns,omitempty"`  // short_text: pola regex yang diterima (case-insensitive)
+	Number    *float64          `json:"number,omitempty"`    // numeric: nilai yang benar
+	Tolerance float64           `json:"tolerance,omitempty"` // numeric: selisih yang masih diterima
+	Pairs     map[string]string `json:"pairs,omitempty"`     // matching: ID option -> ID match
 }
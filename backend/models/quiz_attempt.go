@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// QuizAttempt menyimpan satu kali pengerjaan kuis yang dinilai di server
+type QuizAttempt struct {
+	ID              uint                 `gorm:"primaryKey" json:"id"`
+	UserID          uint                 `gorm:"not null;index" json:"userId"`
+	QuizID          uint                 `gorm:"not null;index" json:"lessonId"`
+	Status          string               `gorm:"type:varchar(20);default:'submitted';index" json:"status"` // in_progress, submitted
+	Score           int                  `gorm:"default:0" json:"score"`                                   // Persentase 0-100
+	CorrectCount    int                  `gorm:"default:0" json:"correctCount"`
+	TotalQuestions  int                  `gorm:"default:0" json:"totalQuestions"`
+	EarnedPoints    int                  `gorm:"default:0" json:"earnedPoints"`
+	TotalPoints     int                  `gorm:"default:0" json:"totalPoints"`
+	Passed          bool                 `gorm:"default:false" json:"passed"`
+	DurationSeconds int                  `gorm:"default:0" json:"durationSeconds"`
+	Results         []QuizQuestionResult `gorm:"type:text;serializer:json" json:"results"`
+	Questions       []AttemptQuestion    `gorm:"type:text;serializer:json" json:"-"`                 // Soal yang diundi untuk attempt ini
+	Answers         []QuestionSubmission `gorm:"type:text;serializer:json" json:"answers,omitempty"` // Jawaban sementara selama attempt berjalan
+	StartedAt       *time.Time           `json:"startedAt"`
+	Deadline        *time.Time           `json:"deadline"` // Hanya untuk kuis ExamMode
+	SubmittedAt     *time.Time           `json:"submittedAt"`
+	AutoSubmitted   bool                 `gorm:"default:false" json:"autoSubmitted"` // Dikirim otomatis karena melewati deadline
+	CreatedAt       time.Time            `json:"createdAt"`
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID" json:"-"`
+	Quiz Quiz `gorm:"foreignKey:QuizID" json:"-"`
+}
+
+// QuestionSubmission adalah jawaban peserta untuk satu soal
+type QuestionSubmission struct {
+	QuestionID uint              `json:"questionId" binding:"required"`
+	Answer     string            `json:"answer,omitempty"`    // single_choice/true_false (ID option), short_text, numeric
+	OptionIDs  []string          `json:"optionIds,omitempty"` // multiple_choice, ordering
+	Pairs      map[string]string `json:"pairs,omitempty"`     // matching: ID option -> ID match
+}
+
+// QuizQuestionResult adalah hasil penilaian per soal di dalam QuizAttempt
+type QuizQuestionResult struct {
+	QuestionSubmission
+	Correct      bool   `json:"correct"`
+	Points       int    `json:"points"`
+	EarnedPoints int    `json:"earnedPoints"`
+	Explanation  string `json:"explanation,omitempty"`
+}
+
+// PublicQuestion adalah bentuk Question tanpa kunci jawaban untuk endpoint publik
+type PublicQuestion struct {
+	ID           uint             `json:"id"`
+	Kind         string           `json:"kind"`
+	QuestionText string           `json:"questionText"`
+	Options      []QuestionOption `json:"options"`
+	Matches      []QuestionOption `json:"matches,omitempty"`
+	Points       int              `json:"points"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Submission menyimpan satu versi pengiriman project. Setiap kirim ulang menambah baris baru
+// sehingga file, link, dan catatan reviewer dari versi sebelumnya tetap tersimpan.
+type Submission struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	ProgressID uint       `gorm:"not null;index" json:"progressId"`
+	UserID     uint       `gorm:"not null;index" json:"userId"`
+	QuizID     uint       `gorm:"not null;index" json:"lessonId"`
+	Attempt    int        `gorm:"not null;default:1" json:"attempt"` // Nomor urut pengiriman (1, 2, ...)
+	FileURL    string     `gorm:"type:varchar(255)" json:"fileUrl"`
+	DriveLink  string     `gorm:"type:varchar(255)" json:"driveLink"`
+	Status     string     `gorm:"type:varchar(20);default:'pending';index" json:"status"` // pending, approved, rejected, superseded
+	ReviewerID *uint      `json:"reviewerId"`                                             // Kosong jika diputuskan oleh sistem (auto-grade / peer review)
+	ReviewNote string     `gorm:"type:text" json:"reviewNote"`
+	ReviewedAt *time.Time `json:"reviewedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+
+	// Relationships
+	Progress UserProgress        `gorm:"foreignKey:ProgressID;constraint:OnDelete:CASCADE" json:"-"`
+	Reviewer *User               `gorm:"foreignKey:ReviewerID" json:"-"`
+	Comments []SubmissionComment `gorm:"foreignKey:SubmissionID;constraint:OnDelete:CASCADE" json:"comments,omitempty"`
+}
+
+// SubmissionComment adalah pesan dalam thread diskusi antara reviewer dan peserta pada satu submission
+type SubmissionComment struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	SubmissionID uint      `gorm:"not null;index" json:"submissionId"`
+	UserID       uint      `gorm:"not null" json:"userId"`
+	Content      string    `gorm:"type:text;not null" json:"content"`
+	CreatedAt    time.Time `json:"createdAt"`
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
+
+// SubmissionCommentWithUser is used for API responses with author info
+type SubmissionCommentWithUser struct {
+	ID           uint   `json:"id"`
+	SubmissionID uint   `json:"submissionId"`
+	UserID       uint   `json:"userId"`
+	Username     string `json:"username"`
+	Role         string `json:"role"`
+	Content      string `json:"content"`
+	CreatedAt    string `json:"createdAt"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Subscription adalah masa aktif langganan user yang dibuat saat pembayaran berhasil
+type Subscription struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	UserID         uint       `gorm:"not null;index" json:"userId"`
+	PlanID         *uint      `gorm:"index" json:"planId"`
+	PaymentID      *uint      `gorm:"uniqueIndex" json:"paymentId"`                          // Satu pembayaran hanya menghasilkan satu langganan
+	Status         string     `gorm:"type:varchar(20);default:'active';index" json:"status"` // active, expired, cancelled
+	StartsAt       time.Time  `json:"startsAt"`
+	EndsAt         time.Time  `gorm:"index" json:"endsAt"`
+	ReminderSentAt *time.Time `json:"reminderSentAt"`
+	CancelledAt    *time.Time `json:"cancelledAt"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+
+	// Relationships
+	User User  `gorm:"foreignKey:UserID" json:"-"`
+	Plan *Plan `gorm:"foreignKey:PlanID;constraint:OnDelete:SET NULL" json:"plan,omitempty"`
+}
+
+// IsActive memeriksa apakah langganan masih berlaku pada waktu tertentu
+func (s Subscription) IsActive(now time.Time) bool {
+	return s.Status == "active" && !now.Before(s.StartsAt) && now.Before(s.EndsAt)
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Upload mencatat pemilik setiap file yang diunggah ke folder uploads sehingga file
+// submission hanya bisa dipakai (dan dinilai) atas nama user yang mengunggahnya
+type Upload struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	UserID       uint      `gorm:"not null;index" json:"userId"`
+	URL          string    `gorm:"type:varchar(255);uniqueIndex;not null" json:"url"`
+	OriginalName string    `gorm:"type:varchar(255)" json:"originalName"`
+	Size         int64     `json:"size"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
@@ -22,6 +22,13 @@ type User struct {
 	ReferralSource       string     `json:"referral_source"`
 	ResetPasswordToken   string     `json:"reset_password_token"`
 	ResetPasswordExpires *time.Time `json:"reset_password_expires"`
+	// Referral: kode milik user dan pengundangnya
+	ReferralCode *string `gorm:"type:varchar(20);uniqueIndex" json:"referral_code"`
+	ReferredByID *uint   `gorm:"index" json:"referred_by_id"`
+	// ReferredByCode hanya dipakai saat registrasi untuk mencatat kode referral pengundang
+	ReferredByCode string `gorm:"-" json:"referred_by_code,omitempty"`
+	// TokenVersion dinaikkan untuk membatalkan semua access token yang sudah terbit
+	TokenVersion int `gorm:"not null;default:0" json:"-"`
 }
 
 type LoginRequest struct {
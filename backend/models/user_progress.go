@@ -12,6 +12,16 @@ type UserProgress struct {
 	// Project Approval
 	ApprovalStatus string `gorm:"type:varchar(20);default:'pending'" json:"approvalStatus"`
 	AdminNote      string `gorm:"type:text" json:"adminNote"`
+	// ApprovalOverridden bernilai true jika admin sudah memutuskan, sehingga hasil peer review tidak mengubah status
+	ApprovalOverridden bool `gorm:"default:false" json:"approvalOverridden"`
+	// Peer Review
+	PeerReviewScore int `gorm:"default:0" json:"peerReviewScore"` // Rata-rata persentase rubrik
+	PeerReviewCount int `gorm:"default:0" json:"peerReviewCount"`
+	// Automated Grading (ZIP)
+	AutoGradeStatus string     `gorm:"type:varchar(20)" json:"autoGradeStatus"` // queued, running, passed, failed, manual_review, error
+	AutoGradeScore  int        `gorm:"default:0" json:"autoGradeScore"`
+	AutoGradeLog    string     `gorm:"type:text" json:"autoGradeLog"`
+	AutoGradedAt    *time.Time `json:"autoGradedAt"`
 	// Quiz Cooldown
 	QuizFailedAt *time.Time `json:"quizFailedAt"`
 	CreatedAt    time.Time  `json:"createdAt"`
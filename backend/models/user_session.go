@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// UserSession adalah sesi login (satu per perangkat) yang menyimpan hash refresh token.
+// Refresh token dirotasi setiap dipakai; hash sebelumnya disimpan untuk mendeteksi token yang dicuri.
+type UserSession struct {
+	ID                uint       `gorm:"primaryKey" json:"id"`
+	UserID            uint       `gorm:"not null;index" json:"userId"`
+	RefreshTokenHash  string     `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+	PreviousTokenHash string     `gorm:"type:varchar(64);index" json:"-"`
+	Device            string     `gorm:"type:varchar(100)" json:"device"`
+	UserAgent         string     `gorm:"type:text" json:"userAgent"`
+	IPAddress         string     `gorm:"type:varchar(45)" json:"ipAddress"`
+	LastUsedAt        time.Time  `json:"lastUsedAt"`
+	ExpiresAt         time.Time  `gorm:"index" json:"expiresAt"`
+	RevokedAt         *time.Time `gorm:"index" json:"revokedAt"`
+	RevokedReason     string     `gorm:"type:varchar(50)" json:"revokedReason,omitempty"` // logout, revoked, reuse_detected, password_reset, user_deleted
+	CreatedAt         time.Time  `json:"createdAt"`
+	UpdatedAt         time.Time  `json:"updatedAt"`
+}
+
+// IsActive menandakan sesi belum dicabut dan refresh token belum kedaluwarsa
+func (s UserSession) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
@@ -26,6 +26,9 @@ func SetupRouter() *gin.Engine {
 	// --- SERVE STATIC FILES ---
 	r.Static("/uploads", "./uploads")
 
+	// Kunci publik untuk verifikasi sertifikat offline
+	r.GET("/.well-known/certificate-keys.json", controllers.GetCertificatePublicKey)
+
 	// 2. DEFINISIKAN GROUP API
 	// --- PUBLIC ROUTES ---
 	public := r.Group("/api")
@@ -39,6 +42,11 @@ func SetupRouter() *gin.Engine {
 			auth.POST("/forgot-password", controllers.ForgotPassword)
 			auth.POST("/reset-password", controllers.ResetPassword)
 			auth.GET("/captcha", controllers.GetCaptcha)
+			auth.POST("/refresh", controllers.RefreshToken)
+			auth.POST("/logout", middleware.OptionalAuth(), controllers.Logout)
+			auth.GET("/sessions", middleware.AuthMiddleware(), controllers.GetMySessions)
+			auth.DELETE("/sessions", middleware.AuthMiddleware(), controllers.RevokeOtherSessions)
+			auth.DELETE("/sessions/:id", middleware.AuthMiddleware(), controllers.RevokeMySession)
 		}
 
 		// Endpoint untuk inisialisasi cookie CSRF
@@ -58,13 +66,25 @@ func SetupRouter() *gin.Engine {
 		public.GET("/posts/:id/comments", controllers.GetCommentsByPost)
 
 		// Learning Path & Modules (Quizzes)
-		public.GET("/learning-paths", controllers.GetLearningPaths)
-		public.GET("/learning-paths/:id", controllers.GetLearningPath)
+		public.GET("/learning-paths", middleware.OptionalAuth(), controllers.GetLearningPaths)
+		public.GET("/learning-paths/:id", middleware.OptionalAuth(), controllers.GetLearningPath)
+		public.GET("/learning-paths/:id/cohorts", controllers.GetPathCohorts)
 		public.GET("/path-categories", controllers.GetPathCategories)
 		public.GET("/certificates/:id", controllers.GetCertificateByID)
-		public.GET("/quizzes", controllers.GetQuizzes)
-		public.GET("/quizzes/:id", controllers.GetQuiz)
-		public.GET("/quizzes/:id/questions", controllers.GetQuizQuestions)
+		public.GET("/certificates/:id/pdf", controllers.GetCertificatePDF)
+		public.GET("/certificates/verify", controllers.VerifyCertificate)
+		public.POST("/certificates/verify", controllers.VerifyCertificate)
+		public.GET("/certificates/revoked", controllers.GetRevokedCertificates)
+
+		// Open Badges 3.0
+		public.GET("/certificates/:id/badge", controllers.GetCertificateBadge)
+		public.GET("/certificates/:id/badge/baked", controllers.GetCertificateBakedBadge)
+		public.GET("/badges/issuer", controllers.GetBadgeIssuer)
+		public.GET("/badges/achievements/:pathId", controllers.GetBadgeAchievement)
+		public.GET("/badges/achievements/:pathId/image", controllers.GetBadgeAchievementImage)
+		public.GET("/quizzes", middleware.OptionalAuth(), controllers.GetQuizzes)
+		public.GET("/quizzes/:id", middleware.OptionalAuth(), controllers.GetQuiz)
+		public.GET("/quizzes/:id/questions", middleware.OptionalAuth(), controllers.GetQuizQuestions)
 
 		// Assets Public
 		public.GET("/assets", controllers.GetAssets)
@@ -83,6 +103,10 @@ func SetupRouter() *gin.Engine {
 
 		// Payment Notification (Webhook)
 		public.POST("/payments/notification", controllers.HandleNotification)
+		public.GET("/payments/fake/:orderId", controllers.GetFakePayment) // Hanya aktif jika PAYMENT_GATEWAY=fake
+
+		// Subscription Plans (Public)
+		public.GET("/plans", controllers.GetPlans)
 
 		// Community Threads
 		public.GET("/threads", controllers.GetThreads)
@@ -120,6 +144,30 @@ func SetupRouter() *gin.Engine {
 			authGroup.POST("/progress/quiz-failed", controllers.RecordQuizFailed)
 			authGroup.GET("/progress/materials", controllers.GetAccessibleMaterials)
 
+			// Quiz Attempts (server-side grading)
+			authGroup.POST("/quizzes/:id/attempts/start", controllers.StartQuizAttempt)
+			authGroup.GET("/quizzes/:id/attempts/current", controllers.ResumeQuizAttempt)
+			authGroup.POST("/quizzes/:id/attempts", controllers.SubmitQuizAttempt)
+			authGroup.GET("/quizzes/:id/attempts", controllers.GetQuizAttempts)
+			authGroup.GET("/quiz-attempts", controllers.GetQuizAttempts)
+			authGroup.GET("/quiz-attempts/:attemptId", controllers.GetQuizAttempt)
+			authGroup.PUT("/quiz-attempts/:attemptId/answers", controllers.SaveQuizAttemptAnswers)
+
+			// Enrollments & Cohorts
+			authGroup.GET("/enrollments", controllers.GetMyEnrollments)
+			authGroup.POST("/learning-paths/:id/enroll", controllers.EnrollPath)
+			authGroup.DELETE("/learning-paths/:id/enroll", controllers.UnenrollPath)
+			authGroup.POST("/cohorts/:id/enroll", controllers.EnrollCohort)
+
+			// Peer Review
+			authGroup.GET("/peer-reviews", controllers.GetMyPeerReviews)
+			authGroup.POST("/peer-reviews/:id", controllers.SubmitPeerReview)
+
+			// Submission History & Discussion
+			authGroup.GET("/submissions", controllers.GetMySubmissions)
+			authGroup.GET("/submissions/:id/comments", controllers.GetSubmissionComments)
+			authGroup.POST("/submissions/:id/comments", controllers.CreateSubmissionComment)
+
 			// File Upload for Students (project submission)
 			authGroup.POST("/upload-file", controllers.UploadFile)
 
@@ -132,6 +180,13 @@ func SetupRouter() *gin.Engine {
 
 			// Payments
 			authGroup.POST("/payments/create", controllers.CreateTransaction)
+			authGroup.POST("/payments/quote", controllers.QuoteTransaction)
+			authGroup.POST("/payments/fake/:orderId/simulate", controllers.SimulateFakePayment)
+			authGroup.GET("/subscription", controllers.GetMySubscription)
+			authGroup.GET("/referral", controllers.GetMyReferral)
+			authGroup.GET("/invoices", controllers.GetMyInvoices)
+			authGroup.GET("/invoices/:id", controllers.GetInvoice)
+			authGroup.GET("/invoices/:id/download", controllers.DownloadInvoice)
 		}
 	}
 
@@ -150,6 +205,14 @@ func SetupRouter() *gin.Engine {
 		adminGroup.POST("/learning-paths", controllers.CreateLearningPath)
 		adminGroup.PUT("/learning-paths/:id", controllers.UpdateLearningPath)
 		adminGroup.DELETE("/learning-paths/:id", controllers.DeleteLearningPath)
+		adminGroup.PUT("/learning-paths/:id/prerequisites", controllers.SetPathPrerequisites)
+
+		// Cohort Management
+		adminGroup.GET("/cohorts", controllers.GetCohorts)
+		adminGroup.GET("/cohorts/:id", controllers.GetCohort)
+		adminGroup.POST("/cohorts", controllers.CreateCohort)
+		adminGroup.PUT("/cohorts/:id", controllers.UpdateCohort)
+		adminGroup.DELETE("/cohorts/:id", controllers.DeleteCohort)
 
 		// Chapter Management
 		adminGroup.POST("/chapters", controllers.CreateChapter)
@@ -160,8 +223,19 @@ func SetupRouter() *gin.Engine {
 		adminGroup.POST("/quizzes", controllers.CreateQuiz)
 		adminGroup.PUT("/quizzes/:id", controllers.UpdateQuiz)
 		adminGroup.GET("/quizzes", controllers.GetQuizzes)
-		adminGroup.GET("/quizzes/:id", controllers.GetQuiz)
+		adminGroup.GET("/quizzes/:id", controllers.AdminGetQuiz)
 		adminGroup.DELETE("/quizzes/:id", controllers.DeleteQuiz)
+		adminGroup.PUT("/quizzes/:id/prerequisites", controllers.SetLessonPrerequisites)
+
+		// Question Bank Management
+		adminGroup.GET("/question-banks", controllers.GetQuestionBanks)
+		adminGroup.POST("/question-banks", controllers.CreateQuestionBank)
+		adminGroup.GET("/question-banks/:id", controllers.GetQuestionBank)
+		adminGroup.PUT("/question-banks/:id", controllers.UpdateQuestionBank)
+		adminGroup.DELETE("/question-banks/:id", controllers.DeleteQuestionBank)
+		adminGroup.POST("/question-banks/:id/questions", controllers.CreateBankQuestion)
+		adminGroup.PUT("/question-banks/:id/questions/:questionId", controllers.UpdateBankQuestion)
+		adminGroup.DELETE("/question-banks/:id/questions/:questionId", controllers.DeleteBankQuestion)
 
 		// Image & File Upload
 		adminGroup.POST("/upload", controllers.UploadImage)
@@ -174,6 +248,9 @@ func SetupRouter() *gin.Engine {
 		adminGroup.GET("/submissions", controllers.GetSubmissions)
 		adminGroup.PUT("/submissions/:id/approve", controllers.ApproveSubmission)
 		adminGroup.PUT("/submissions/:id/reject", controllers.RejectSubmission)
+		adminGroup.PUT("/submissions/:id/regrade", controllers.RegradeSubmission)
+		adminGroup.GET("/submissions/:id/peer-reviews", controllers.GetSubmissionPeerReviews)
+		adminGroup.GET("/submissions/:id/history", controllers.GetSubmissionHistory)
 
 		// --- SUPER ADMIN ONLY ROUTES ---
 		super := adminGroup.Group("") // Avoid double slash
@@ -202,9 +279,21 @@ func SetupRouter() *gin.Engine {
 
 			// Certificate Management
 			super.GET("/certificates", controllers.GetAllCertificates)
+			super.POST("/certificates/backfill", controllers.BackfillCertificates)
 			super.DELETE("/certificates/:id", controllers.RevokeCertificate)
+			super.POST("/certificates/:id/reinstate", controllers.ReinstateCertificate)
 			super.PUT("/certificates/template", controllers.UpdateCertificateTemplate)
 
+			// Certificate Template Library
+			super.GET("/certificate-templates", controllers.GetCertificateTemplates)
+			super.POST("/certificate-templates", controllers.CreateCertificateTemplate)
+			super.POST("/certificate-templates/preview", controllers.PreviewCertificateTemplate)
+			super.GET("/certificate-templates/:id", controllers.GetCertificateTemplateByID)
+			super.PUT("/certificate-templates/:id", controllers.UpdateCertificateTemplateByID)
+			super.DELETE("/certificate-templates/:id", controllers.DeleteCertificateTemplate)
+			super.PUT("/certificate-templates/:id/paths", controllers.AssignCertificateTemplate)
+			super.GET("/certificate-templates/:id/preview", controllers.PreviewCertificateTemplate)
+
 			// Contact Management
 			super.GET("/contacts", controllers.GetContacts)
 			super.GET("/contacts/:id", controllers.GetContactByID)
@@ -214,6 +303,26 @@ func SetupRouter() *gin.Engine {
 			// Payment Management
 			super.GET("/payments", controllers.GetAllPayments)
 			super.GET("/payments/stats", controllers.GetPaymentStats)
+			super.GET("/payments/notifications", controllers.GetPaymentNotifications)
+			super.GET("/payments/analytics", controllers.GetRevenueAnalytics)
+			super.GET("/payments/export", controllers.ExportPayments)
+			super.POST("/payments/:id/refund", controllers.RefundPayment)
+			super.GET("/payments/:id/refunds", controllers.GetPaymentRefunds)
+			super.GET("/invoices", controllers.GetAllInvoices)
+			super.POST("/invoices/:id/resend", controllers.ResendInvoice)
+
+			// Subscription Plan Management
+			super.GET("/plans", controllers.GetAllPlans)
+			super.POST("/plans", controllers.CreatePlan)
+			super.PUT("/plans/:id", controllers.UpdatePlan)
+			super.DELETE("/plans/:id", controllers.DeletePlan)
+
+			// Coupon Management
+			super.GET("/coupons", controllers.GetAllCoupons)
+			super.POST("/coupons", controllers.CreateCoupon)
+			super.PUT("/coupons/:id", controllers.UpdateCoupon)
+			super.DELETE("/coupons/:id", controllers.DeleteCoupon)
+			super.GET("/coupons/:id/redemptions", controllers.GetCouponRedemptions)
 
 			// User Management
 			super.GET("/users", controllers.GetAllUsers)
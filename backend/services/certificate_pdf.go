@@ -0,0 +1,378 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/imam/backend-blog-kuis/config"
+	"github.com/imam/backend-blog-kuis/models"
+	"github.com/imam/backend-blog-kuis/utils"
+	"github.com/jung-kurt/gofpdf"
+	"github.com/jung-kurt/gofpdf/contrib/gofpdi"
+	"github.com/skip2/go-qrcode"
+)
+
+// Ukuran halaman sertifikat (A4 landscape dalam point), sama dengan koordinat editor template
+const (
+	certPageWidth   = 841.89
+	certPageHeight  = 595.28
+	maxBackgroundMB = 10
+)
+
+// DefaultCertificateFields adalah posisi bawaan untuk template yang belum mengatur field
+func DefaultCertificateFields() []models.CertificateField {
+	return []models.CertificateField{
+		{Type: models.CertFieldName, X: 100, Y: 400, Bold: true},
+		{Type: models.CertFieldPathTitle, X: 100, Y: 358, FontSize: 18, Bold: true},
+		{Type: models.CertFieldDate, X: 100, Y: 120, FontSize: 12},
+		{Type: models.CertFieldID, X: certPageWidth - 260, Y: 60, FontSize: 12, Label: "ID: "},
+	}
+}
+
+// ResolveCertificateTemplate memilih template yang di-assign ke learning path, template bawaan
+// (Active), atau desain standar jika belum ada template sama sekali
+func ResolveCertificateTemplate(path models.LearningPath) models.CertificateTemplate {
+	var template models.CertificateTemplate
+	found := false
+	if path.CertificateTemplateID != nil {
+		found = config.DB.First(&template, *path.CertificateTemplateID).Error == nil
+	}
+	if !found {
+		found = config.DB.Where("active = ?", true).First(&template).Error == nil
+	}
+	if !found {
+		template = models.CertificateTemplate{Name: "Standar", PrimaryColor: "#2563eb", CertFontSize: 30}
+	}
+	if len(template.Fields) == 0 {
+		template.Fields = DefaultCertificateFields()
+	}
+	return template
+}
+
+// CertificateScore menghitung rata-rata nilai terbaik kuis user pada sebuah path (nil jika tidak ada kuis)
+func CertificateScore(userID, pathID uint) *float64 {
+	var result struct {
+		Score *float64
+	}
+	config.DB.Raw(`
+		SELECT AVG(best) AS score FROM (
+			SELECT MAX(quiz_attempts.score) AS best
+			FROM quiz_attempts
+			JOIN quizzes ON quizzes.id = quiz_attempts.quiz_id
+			WHERE quiz_attempts.user_id = ? AND quizzes.path_id = ? AND quiz_attempts.status = 'submitted'
+			GROUP BY quiz_attempts.quiz_id
+		) best_scores`, userID, pathID).Scan(&result)
+	return result.Score
+}
+
+// certificateCacheDir mengembalikan folder cache PDF sertifikat (bisa diatur lewat CERTIFICATE_CACHE_DIR)
+func certificateCacheDir() string {
+	if dir := os.Getenv("CERTIFICATE_CACHE_DIR"); dir != "" {
+		return dir
+	}
+	return filepath.Join("storage", "certificates")
+}
+
+// CertificatePDF mengembalikan path file PDF sertifikat, merender ulang jika belum ada di cache.
+// Nama file memuat hash template dan data sehingga perubahan template otomatis membuat cache baru.
+func CertificatePDF(cert models.Certificate) (string, error) {
+	template := ResolveCertificateTemplate(cert.LearningPath)
+	score := CertificateScore(cert.UserID, cert.LearningPathID)
+
+	h := sha1.New()
+	fmt.Fprintf(h, "%+v|%s|%s|%s|%d|%s|%s", template, cert.User.Username, cert.LearningPath.Title, cert.CertificateID, cert.IssuedAt.Unix(), cert.Signature, utils.FrontendURL())
+	if score != nil {
+		fmt.Fprintf(h, "|%.1f", *score)
+	}
+	fileName := fmt.Sprintf("%s-%s.pdf", cert.CertificateID, hex.EncodeToString(h.Sum(nil))[:12])
+	filePath := filepath.Join(certificateCacheDir(), filepath.Base(fileName))
+
+	if _, err := os.Stat(filePath); err == nil {
+		return filePath, nil
+	}
+
+	if err := os.MkdirAll(certificateCacheDir(), 0o755); err != nil {
+		return "", err
+	}
+
+	data, err := RenderCertificatePDF(cert, template, score)
+	if err != nil {
+		return "", err
+	}
+
+	// Tulis ke file sementara dulu agar request bersamaan tidak membaca file setengah jadi
+	tmp, err := os.CreateTemp(certificateCacheDir(), "render-*.pdf")
+	if err != nil {
+		return "", err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	tmp.Close()
+	if err := os.Rename(tmp.Name(), filePath); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return filePath, nil
+}
+
+// RenderCertificatePDF merender sertifikat menjadi PDF satu halaman sesuai field template.
+// score boleh nil; field nilai dilewati jika path tidak memiliki kuis.
+func RenderCertificatePDF(cert models.Certificate, template models.CertificateTemplate, score *float64) ([]byte, error) {
+	pdf := gofpdf.New("L", "pt", "A4", "")
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.AddPage()
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	r, g, b := parseHexColor(template.PrimaryColor)
+
+	// Latar: halaman pertama template PDF atau gambar PNG/JPG memenuhi halaman.
+	// Jika latar tidak bisa dimuat, dipakai bingkai sederhana dengan warna utama.
+	background := template.BackgroundImage
+	if background == "" {
+		background = template.CertPdfURL
+	}
+	drewBackground := false
+	if background != "" {
+		if data, kind, err := loadCertificateBackground(background); err == nil {
+			if kind == "PDF" {
+				drewBackground = drawPDFBackground(pdf, data)
+			} else {
+				opts := gofpdf.ImageOptions{ImageType: kind, ReadDpi: false}
+				pdf.RegisterImageOptionsReader("background", opts, bytes.NewReader(data))
+				if pdf.Ok() {
+					pdf.ImageOptions("background", 0, 0, certPageWidth, certPageHeight, false, opts, 0, "")
+					drewBackground = pdf.Ok()
+				}
+			}
+			if !drewBackground {
+				pdf.ClearError()
+			}
+		}
+	}
+	if !drewBackground {
+		pdf.SetDrawColor(r, g, b)
+		pdf.SetLineWidth(6)
+		pdf.Rect(24, 24, certPageWidth-48, certPageHeight-48, "D")
+		pdf.SetLineWidth(1.5)
+		pdf.Rect(36, 36, certPageWidth-72, certPageHeight-72, "D")
+	}
+
+	baseFontSize := template.CertFontSize
+	if baseFontSize <= 0 {
+		baseFontSize = 30
+	}
+
+	for i, field := range template.Fields {
+		if field.Type == models.CertFieldInstructorSignature {
+			drawSignatureField(pdf, tr, fmt.Sprintf("signature-%d", i), field)
+			continue
+		}
+
+		text, ok := certificateFieldText(field, cert, score)
+		if !ok {
+			continue
+		}
+
+		fontSize := float64(field.FontSize)
+		if fontSize <= 0 {
+			fontSize = 12
+			if field.Type == models.CertFieldName {
+				fontSize = float64(baseFontSize)
+			}
+		}
+		style := ""
+		if field.Bold {
+			style = "B"
+		}
+		switch {
+		case field.Color != "":
+			pdf.SetTextColor(parseHexColor(field.Color))
+		case field.Type == models.CertFieldPathTitle:
+			pdf.SetTextColor(r, g, b)
+		default:
+			pdf.SetTextColor(0, 0, 0)
+		}
+		pdf.SetFont("Helvetica", style, fontSize)
+		drawCertificateText(pdf, field.X, field.Y, tr(text))
+	}
+
+	// QR code menuju halaman verifikasi (memuat payload bertanda tangan untuk verifikasi offline)
+	if qr, err := qrcode.Encode(CertificateVerifyURL(cert), qrcode.Medium, 256); err == nil {
+		opts := gofpdf.ImageOptions{ImageType: "PNG"}
+		pdf.RegisterImageOptionsReader("verify-qr", opts, bytes.NewReader(qr))
+		pdf.ImageOptions("verify-qr", certPageWidth-140, certPageHeight-150, 96, 96, false, opts, 0, "")
+		pdf.SetFont("Helvetica", "", 7)
+		pdf.SetTextColor(90, 90, 90)
+		pdf.Text(certPageWidth-140, certPageHeight-46, "Pindai untuk verifikasi")
+	}
+
+	pdf.SetTitle(tr("Sertifikat "+cert.LearningPath.Title), false)
+	pdf.SetCreationDate(cert.IssuedAt)
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// certificateFieldText mengembalikan teks sebuah field; false jika field tidak perlu digambar
+func certificateFieldText(field models.CertificateField, cert models.Certificate, score *float64) (string, bool) {
+	switch field.Type {
+	case models.CertFieldName:
+		return field.Label + cert.User.Username, true
+	case models.CertFieldPathTitle:
+		return field.Label + cert.LearningPath.Title, true
+	case models.CertFieldDate:
+		return field.Label + cert.IssuedAt.Format("02 January 2006"), true
+	case models.CertFieldID:
+		return field.Label + cert.CertificateID, true
+	case models.CertFieldScore:
+		if score == nil {
+			return "", false
+		}
+		label := field.Label
+		if label == "" {
+			label = "Nilai: "
+		}
+		return fmt.Sprintf("%s%.0f", label, *score), true
+	}
+	return "", false
+}
+
+// drawSignatureField menggambar tanda tangan instruktur dengan dasar gambar di koordinat field,
+// lalu nama instruktur (Label) di bawahnya
+func drawSignatureField(pdf *gofpdf.Fpdf, tr func(string) string, name string, field models.CertificateField) {
+	width := field.Width
+	if width <= 0 {
+		width = 120
+	}
+
+	if field.ImageURL != "" {
+		if img, imageType, err := loadCertificateImage(field.ImageURL); err == nil {
+			opts := gofpdf.ImageOptions{ImageType: imageType}
+			info := pdf.RegisterImageOptionsReader(name, opts, bytes.NewReader(img))
+			if pdf.Ok() && info != nil && info.Width() > 0 {
+				height := width * info.Height() / info.Width()
+				pdf.ImageOptions(name, field.X, certPageHeight-field.Y-height, width, height, false, opts, 0, "")
+			}
+			if !pdf.Ok() {
+				pdf.ClearError()
+			}
+		}
+	}
+
+	pdf.SetDrawColor(60, 60, 60)
+	pdf.SetLineWidth(0.75)
+	pdf.Line(field.X, certPageHeight-field.Y, field.X+width, certPageHeight-field.Y)
+	if field.Label != "" {
+		fontSize := float64(field.FontSize)
+		if fontSize <= 0 {
+			fontSize = 11
+		}
+		pdf.SetTextColor(0, 0, 0)
+		pdf.SetFont("Helvetica", "", fontSize)
+		drawCertificateText(pdf, field.X, field.Y-fontSize-4, tr(field.Label))
+	}
+}
+
+// drawCertificateText menulis teks pada koordinat PDF (titik asal kiri bawah, y = baseline)
+func drawCertificateText(pdf *gofpdf.Fpdf, x, y float64, text string) {
+	pdf.Text(x, certPageHeight-y, text)
+}
+
+// readCertificateAsset membaca file dari folder uploads atau dari URL eksternal
+func readCertificateAsset(url string) ([]byte, error) {
+	if strings.HasPrefix(url, "/uploads/") {
+		return os.ReadFile(filepath.Join("uploads", filepath.Base(url)))
+	}
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return nil, errors.New("lokasi gambar latar tidak didukung")
+	}
+
+	client := http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("gagal mengunduh latar: status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxBackgroundMB<<20))
+}
+
+// loadCertificateImage membaca gambar PNG/JPG dari folder uploads atau dari URL eksternal
+func loadCertificateImage(url string) ([]byte, string, error) {
+	data, kind, err := loadCertificateBackground(url)
+	if err == nil && kind == "PDF" {
+		return nil, "", errors.New("gambar harus berupa PNG atau JPG")
+	}
+	return data, kind, err
+}
+
+// loadCertificateBackground membaca latar sertifikat; selain PNG/JPG, file PDF juga diterima
+func loadCertificateBackground(url string) ([]byte, string, error) {
+	data, err := readCertificateAsset(url)
+	if err != nil {
+		return nil, "", err
+	}
+	switch http.DetectContentType(data) {
+	case "image/png":
+		return data, "PNG", nil
+	case "image/jpeg":
+		return data, "JPG", nil
+	case "application/pdf":
+		return data, "PDF", nil
+	default:
+		return nil, "", errors.New("latar harus berupa PDF atau gambar PNG/JPG")
+	}
+}
+
+// drawPDFBackground mengimpor halaman pertama template PDF sebagai latar selebar halaman.
+// gofpdi panic untuk PDF yang rusak, sehingga panic diubah menjadi kegagalan biasa.
+func drawPDFBackground(pdf *gofpdf.Fpdf, data []byte) (ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[Certificate] gagal mengimpor latar PDF: %v", r)
+			ok = false
+		}
+	}()
+
+	importer := gofpdi.NewImporter()
+	rs := io.ReadSeeker(bytes.NewReader(data))
+	tpl := importer.ImportPageFromStream(pdf, &rs, 1, "/MediaBox")
+	if !pdf.Ok() {
+		return false
+	}
+	importer.UseImportedTemplate(pdf, tpl, 0, 0, certPageWidth, certPageHeight)
+	return pdf.Ok()
+}
+
+// parseHexColor mengubah warna "#rrggbb" menjadi komponen RGB (bawaan biru #2563eb)
+func parseHexColor(color string) (int, int, int) {
+	color = strings.TrimPrefix(color, "#")
+	if len(color) == 3 {
+		color = string([]byte{color[0], color[0], color[1], color[1], color[2], color[2]})
+	}
+	value, err := strconv.ParseUint(color, 16, 32)
+	if len(color) != 6 || err != nil {
+		return 0x25, 0x63, 0xeb
+	}
+	return int(value >> 16 & 0xff), int(value >> 8 & 0xff), int(value & 0xff)
+}
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/imam/backend-blog-kuis/config"
+	"github.com/imam/backend-blog-kuis/models"
+	"github.com/imam/backend-blog-kuis/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrCertificateNotFound       = errors.New("sertifikat tidak ditemukan")
+	ErrCertificateAlreadyRevoked = errors.New("sertifikat sudah dicabut")
+	ErrCertificateNotRevoked     = errors.New("sertifikat tidak dalam status dicabut")
+)
+
+// CertificateIssuedHandler dipanggil setiap kali sertifikat baru diterbitkan
+type CertificateIssuedHandler func(cert models.Certificate)
+
+var (
+	certificateHandlersMu sync.RWMutex
+	certificateHandlers   []CertificateIssuedHandler
+)
+
+// OnCertificateIssued mendaftarkan handler untuk event "issued"
+func OnCertificateIssued(handler CertificateIssuedHandler) {
+	certificateHandlersMu.Lock()
+	defer certificateHandlersMu.Unlock()
+	certificateHandlers = append(certificateHandlers, handler)
+}
+
+// RegisterCertificateNotifications mendaftarkan email pemberitahuan untuk setiap sertifikat baru.
+// Dipanggil saat server dijalankan, sehingga perintah backfill tidak mengirim email massal.
+func RegisterCertificateNotifications() {
+	OnCertificateIssued(sendCertificateIssuedEmail)
+}
+
+// sendCertificateIssuedEmail memberi tahu penerima bahwa sertifikatnya sudah terbit
+func sendCertificateIssuedEmail(cert models.Certificate) {
+	var user models.User
+	var path models.LearningPath
+	if err := config.DB.First(&user, cert.UserID).Error; err != nil || user.Email == "" {
+		return
+	}
+	config.DB.Select("id, title").First(&path, cert.LearningPathID)
+
+	link := fmt.Sprintf("%s/certificates/%s", utils.FrontendURL(), url.PathEscape(cert.CertificateID))
+	body := fmt.Sprintf(`<p>Halo %s,</p>
+<p>Selamat! Anda telah menyelesaikan learning path <b>%s</b> dan sertifikat Anda sudah terbit.</p>
+<p>Nomor sertifikat: <b>%s</b></p>
+<p>Lihat dan unduh sertifikat: <a href="%s">%s</a></p>`,
+		html.EscapeString(user.Username), html.EscapeString(path.Title), cert.CertificateID, link, link)
+
+	if err := utils.SendEmail(user.Email, "Sertifikat Anda sudah terbit - AIOT", body); err != nil {
+		log.Printf("[Certificate] gagal mengirim email sertifikat %s: %v", cert.CertificateID, err)
+	}
+}
+
+// emitCertificateIssued menjalankan semua handler setelah transaksi penerbitan selesai
+func emitCertificateIssued(cert models.Certificate) {
+	log.Printf("[Certificate] issued %s (user #%d, path #%d)", cert.CertificateID, cert.UserID, cert.LearningPathID)
+
+	certificateHandlersMu.RLock()
+	handlers := append([]CertificateIssuedHandler{}, certificateHandlers...)
+	certificateHandlersMu.RUnlock()
+
+	for _, handler := range handlers {
+		go handler(cert)
+	}
+}
+
+// newCertificateID membuat nomor sertifikat acak dengan format AIOT-<pathID>-<HEX>
+func newCertificateID(pathID uint) string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return fmt.Sprintf("AIOT-%d-%s", pathID, strings.ToUpper(hex.EncodeToString(b)))
+}
+
+// IssueCertificate menerbitkan sertifikat jika user memenuhi syarat path.
+// Pemeriksaan progres dan insert berjalan dalam satu transaksi; unique index (user, path)
+// membuat pemanggilan berulang atau bersamaan tidak menghasilkan sertifikat ganda.
+// Mengembalikan sertifikat (baru atau yang sudah ada) dan issued = true jika baru diterbitkan.
+func IssueCertificate(userID, pathID uint) (*models.Certificate, bool, error) {
+	var cert models.Certificate
+	issued := false
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		progress := pathProgresses(tx, userID, []uint{pathID})[pathID]
+		if !progress.IsComplete() {
+			return nil
+		}
+
+		cert = models.Certificate{
+			UserID:         userID,
+			LearningPathID: pathID,
+			CertificateID:  newCertificateID(pathID),
+			IssuedAt:       time.Now().Truncate(time.Second),
+		}
+
+		var user models.User
+		var path models.LearningPath
+		tx.First(&user, userID)
+		tx.First(&path, pathID)
+		if err := signCertificate(&cert, user.Username, path.Title); err != nil {
+			// Sertifikat tetap diterbitkan; tanda tangan dilengkapi oleh SignUnsignedCertificates
+			log.Printf("[Certificate] gagal menandatangani sertifikat user #%d path #%d: %v", userID, pathID, err)
+		}
+
+		result := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "learning_path_id"}},
+			DoNothing: true,
+		}).Create(&cert)
+		if result.Error != nil {
+			return result.Error
+		}
+		issued = result.RowsAffected == 1
+
+		if !issued {
+			return tx.Where("user_id = ? AND learning_path_id = ?", userID, pathID).First(&cert).Error
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, false, err
+	}
+	if cert.ID == 0 {
+		return nil, false, nil
+	}
+	if issued {
+		emitCertificateIssued(cert)
+	}
+	return &cert, issued, nil
+}
+
+// CertificateBackfillResult adalah ringkasan hasil backfill sertifikat
+type CertificateBackfillResult struct {
+	Checked int `json:"checked"`
+	Issued  int `json:"issued"`
+	Failed  int `json:"failed"`
+}
+
+// BackfillCertificates memeriksa ulang semua pasangan user-path yang punya progres tetapi
+// belum memiliki sertifikat, lalu menerbitkan sertifikat bagi yang sudah memenuhi syarat.
+func BackfillCertificates() CertificateBackfillResult {
+	type candidate struct {
+		UserID uint
+		PathID uint
+	}
+	var candidates []candidate
+	config.DB.Table("user_progresses").
+		Select("DISTINCT user_progresses.user_id, quizzes.path_id").
+		Joins("JOIN quizzes ON quizzes.id = user_progresses.quiz_id").
+		Where("NOT EXISTS (SELECT 1 FROM certificates WHERE certificates.user_id = user_progresses.user_id AND certificates.learning_path_id = quizzes.path_id)").
+		Scan(&candidates)
+
+	result := CertificateBackfillResult{Checked: len(candidates)}
+	for _, cand := range candidates {
+		_, issued, err := IssueCertificate(cand.UserID, cand.PathID)
+		switch {
+		case err != nil:
+			result.Failed++
+			log.Printf("[Certificate] backfill user #%d path #%d gagal: %v", cand.UserID, cand.PathID, err)
+		case issued:
+			result.Issued++
+		}
+	}
+	return result
+}
+
+// RevokeCertificate mencabut sertifikat tanpa menghapusnya; waktu, alasan dan admin pencabut dicatat
+func RevokeCertificate(id uint, reason string, adminID *uint) (*models.Certificate, error) {
+	var cert models.Certificate
+	if err := config.DB.First(&cert, id).Error; err != nil {
+		return nil, ErrCertificateNotFound
+	}
+	if cert.IsRevoked() {
+		return nil, ErrCertificateAlreadyRevoked
+	}
+
+	now := time.Now()
+	cert.RevokedAt = &now
+	cert.RevokedReason = strings.TrimSpace(reason)
+	cert.RevokedByID = adminID
+
+	// Kondisi revoked_at IS NULL mencegah dua admin mencabut bersamaan menimpa alasan satu sama lain
+	result := config.DB.Model(&cert).Where("revoked_at IS NULL").
+		Select("RevokedAt", "RevokedReason", "RevokedByID").Updates(&cert)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrCertificateAlreadyRevoked
+	}
+
+	log.Printf("[Certificate] revoked %s: %s", cert.CertificateID, cert.RevokedReason)
+	return &cert, nil
+}
+
+// ReinstateCertificate memulihkan sertifikat yang sudah dicabut
+func ReinstateCertificate(id uint) (*models.Certificate, error) {
+	var cert models.Certificate
+	if err := config.DB.First(&cert, id).Error; err != nil {
+		return nil, ErrCertificateNotFound
+	}
+	if !cert.IsRevoked() {
+		return nil, ErrCertificateNotRevoked
+	}
+
+	if err := config.DB.Model(&cert).Updates(map[string]interface{}{
+		"revoked_at":     nil,
+		"revoked_reason": "",
+		"revoked_by_id":  nil,
+	}).Error; err != nil {
+		return nil, err
+	}
+	cert.RevokedAt = nil
+	cert.RevokedReason = ""
+	cert.RevokedByID = nil
+
+	log.Printf("[Certificate] reinstated %s", cert.CertificateID)
+	return &cert, nil
+}
@@ -0,0 +1,235 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/imam/backend-blog-kuis/config"
+	"github.com/imam/backend-blog-kuis/models"
+	"github.com/imam/backend-blog-kuis/utils"
+)
+
+// CertificatePayload adalah data sertifikat yang ditandatangani. Urutan field tetap
+// sehingga hasil json.Marshal bisa diverifikasi ulang secara offline.
+type CertificatePayload struct {
+	Version       int    `json:"v"`
+	CertificateID string `json:"certId"`
+	Holder        string `json:"holder"`
+	PathID        uint   `json:"pathId"`
+	PathTitle     string `json:"path"`
+	IssuedAt      string `json:"issuedAt"` // RFC3339 UTC
+	KeyID         string `json:"kid"`
+}
+
+// Status hasil verifikasi sertifikat
+const (
+	CertificateValid   = "valid"
+	CertificateRevoked = "revoked"
+	CertificateUnknown = "unknown"
+)
+
+var (
+	signingKeyOnce sync.Once
+	signingKey     ed25519.PrivateKey
+	signingKeyID   string
+	signingKeyErr  error
+)
+
+// certificateSigningKey memuat kunci Ed25519 dari CERT_SIGNING_KEY (seed base64, 32 byte).
+// Jika tidak diatur, kunci dibuat sekali dan disimpan di CERT_SIGNING_KEY_FILE agar tetap sama setelah restart.
+func certificateSigningKey() (ed25519.PrivateKey, string, error) {
+	signingKeyOnce.Do(func() {
+		var seed []byte
+		if encoded := strings.TrimSpace(os.Getenv("CERT_SIGNING_KEY")); encoded != "" {
+			seed, signingKeyErr = base64.StdEncoding.DecodeString(encoded)
+		} else {
+			seed, signingKeyErr = loadOrCreateKeyFile()
+		}
+		if signingKeyErr != nil {
+			return
+		}
+		if len(seed) != ed25519.SeedSize {
+			signingKeyErr = fmt.Errorf("kunci penandatangan harus %d byte", ed25519.SeedSize)
+			return
+		}
+
+		signingKey = ed25519.NewKeyFromSeed(seed)
+		sum := sha256.Sum256(signingKey.Public().(ed25519.PublicKey))
+		signingKeyID = base64.RawURLEncoding.EncodeToString(sum[:8])
+	})
+	return signingKey, signingKeyID, signingKeyErr
+}
+
+// loadOrCreateKeyFile membaca seed dari file kunci, atau membuatnya jika belum ada
+func loadOrCreateKeyFile() ([]byte, error) {
+	keyFile := os.Getenv("CERT_SIGNING_KEY_FILE")
+	if keyFile == "" {
+		keyFile = filepath.Join("storage", "keys", "certificate_ed25519.key")
+	}
+
+	if data, err := os.ReadFile(keyFile); err == nil {
+		return base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	}
+
+	seed := make([]byte, ed25519.SeedSize)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(keyFile), 0o700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(keyFile, []byte(base64.StdEncoding.EncodeToString(seed)), 0o600); err != nil {
+		return nil, err
+	}
+	log.Printf("[Certificate] CERT_SIGNING_KEY tidak diatur, kunci baru dibuat di %s", keyFile)
+	return seed, nil
+}
+
+// CertificatePublicKeyJWK mengembalikan kunci publik dalam format JWK (OKP/Ed25519)
+func CertificatePublicKeyJWK() (map[string]string, error) {
+	key, kid, err := certificateSigningKey()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{
+		"kty": "OKP",
+		"crv": "Ed25519",
+		"alg": "EdDSA",
+		"use": "sig",
+		"kid": kid,
+		"x":   base64.RawURLEncoding.EncodeToString(key.Public().(ed25519.PublicKey)),
+	}, nil
+}
+
+// signCertificate mengisi Payload dan Signature sertifikat menggunakan nama pemegang dan judul path
+func signCertificate(cert *models.Certificate, holder, pathTitle string) error {
+	key, kid, err := certificateSigningKey()
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(CertificatePayload{
+		Version:       1,
+		CertificateID: cert.CertificateID,
+		Holder:        holder,
+		PathID:        cert.LearningPathID,
+		PathTitle:     pathTitle,
+		IssuedAt:      cert.IssuedAt.UTC().Format(time.RFC3339),
+		KeyID:         kid,
+	})
+	if err != nil {
+		return err
+	}
+
+	cert.Payload = string(payload)
+	cert.Signature = base64.RawURLEncoding.EncodeToString(ed25519.Sign(key, payload))
+	return nil
+}
+
+// CertificateToken menggabungkan payload dan signature menjadi token ringkas "<payload>.<signature>" (base64url)
+func CertificateToken(cert models.Certificate) string {
+	if cert.Payload == "" || cert.Signature == "" {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(cert.Payload)) + "." + cert.Signature
+}
+
+// CertificateVerifyURL adalah URL halaman verifikasi yang disematkan di QR code sertifikat
+func CertificateVerifyURL(cert models.Certificate) string {
+	verifyURL := utils.FrontendURL() + "/certificates/verify?id=" + url.QueryEscape(cert.CertificateID)
+	if token := CertificateToken(cert); token != "" {
+		verifyURL += "&token=" + token
+	}
+	return verifyURL
+}
+
+// CertificateVerification adalah hasil verifikasi payload bertanda tangan
+type CertificateVerification struct {
+	Status      string              `json:"status"` // valid, revoked, unknown
+	Reason      string              `json:"reason,omitempty"`
+	Payload     *CertificatePayload `json:"payload,omitempty"`
+	Certificate *models.Certificate `json:"certificate,omitempty"`
+}
+
+// VerifyCertificateToken memeriksa token "<payload>.<signature>" atau pasangan payload/signature.
+// Tanda tangan yang tidak valid atau sertifikat yang tidak terdaftar menghasilkan "unknown";
+// sertifikat yang dicabut atau datanya sudah berbeda menghasilkan "revoked".
+func VerifyCertificateToken(payload []byte, signature string) CertificateVerification {
+	key, _, err := certificateSigningKey()
+	if err != nil {
+		return CertificateVerification{Status: CertificateUnknown, Reason: "kunci verifikasi tidak tersedia"}
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(signature, "="))
+	if err != nil || !ed25519.Verify(key.Public().(ed25519.PublicKey), payload, sig) {
+		return CertificateVerification{Status: CertificateUnknown, Reason: "tanda tangan tidak valid"}
+	}
+
+	var data CertificatePayload
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return CertificateVerification{Status: CertificateUnknown, Reason: "payload tidak valid"}
+	}
+
+	var cert models.Certificate
+	if err := config.DB.Where("certificate_id = ?", data.CertificateID).First(&cert).Error; err != nil {
+		return CertificateVerification{Status: CertificateUnknown, Reason: "sertifikat tidak terdaftar", Payload: &data}
+	}
+	if cert.IsRevoked() {
+		reason := "sertifikat sudah dicabut"
+		if cert.RevokedReason != "" {
+			reason += ": " + cert.RevokedReason
+		}
+		return CertificateVerification{Status: CertificateRevoked, Reason: reason, Payload: &data, Certificate: &cert}
+	}
+	if cert.Payload != string(payload) {
+		return CertificateVerification{Status: CertificateRevoked, Reason: "data sertifikat sudah diperbarui", Payload: &data, Certificate: &cert}
+	}
+
+	return CertificateVerification{Status: CertificateValid, Payload: &data, Certificate: &cert}
+}
+
+// ParseCertificateToken memecah token "<payload>.<signature>" menjadi payload dan signature
+func ParseCertificateToken(token string) ([]byte, string, error) {
+	parts := strings.SplitN(strings.TrimSpace(token), ".", 2)
+	if len(parts) != 2 {
+		return nil, "", errors.New("format token tidak valid")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[0], "="))
+	if err != nil {
+		return nil, "", errors.New("format token tidak valid")
+	}
+	return payload, parts[1], nil
+}
+
+// SignUnsignedCertificates menandatangani sertifikat lama yang diterbitkan sebelum fitur tanda tangan ada
+func SignUnsignedCertificates() {
+	var certs []models.Certificate
+	config.DB.Preload("User").Preload("LearningPath").
+		Where("signature IS NULL OR signature = ''").
+		Find(&certs)
+
+	for i := range certs {
+		cert := &certs[i]
+		if err := signCertificate(cert, cert.User.Username, cert.LearningPath.Title); err != nil {
+			log.Printf("[Certificate] gagal menandatangani %s: %v", cert.CertificateID, err)
+			return
+		}
+		config.DB.Model(cert).Select("Payload", "Signature").Updates(cert)
+	}
+
+	if len(certs) > 0 {
+		log.Printf("[Certificate] %d sertifikat lama ditandatangani", len(certs))
+	}
+}
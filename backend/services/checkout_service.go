@@ -0,0 +1,237 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/imam/backend-blog-kuis/config"
+	"github.com/imam/backend-blog-kuis/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CheckoutOptions adalah pilihan tambahan user saat checkout
+type CheckoutOptions struct {
+	CouponCode   string
+	ReferralCode string
+	UseCredit    bool
+}
+
+// CheckoutQuote adalah rincian harga sebelum dikirim ke payment gateway
+type CheckoutQuote struct {
+	PlanID         uint   `json:"plan_id"`
+	OriginalAmount int64  `json:"original_amount"`
+	CouponCode     string `json:"coupon_code,omitempty"`
+	CouponDiscount int64  `json:"coupon_discount"`
+	CreditBalance  int64  `json:"credit_balance"`
+	CreditUsed     int64  `json:"credit_used"`
+	Amount         int64  `json:"amount"`
+
+	couponID uint
+}
+
+// quoteCheckout menghitung harga akhir: harga paket - potongan kupon - kredit yang dipakai
+func quoteCheckout(tx *gorm.DB, userID uint, plan models.Plan, opts CheckoutOptions, lock bool) (CheckoutQuote, error) {
+	quote := CheckoutQuote{PlanID: plan.ID, OriginalAmount: plan.Price, Amount: plan.Price}
+
+	if opts.CouponCode != "" {
+		coupon, err := findApplicableCoupon(tx, opts.CouponCode, userID, plan.ID, lock)
+		if err != nil {
+			return quote, err
+		}
+		quote.couponID = coupon.ID
+		quote.CouponCode = coupon.Code
+		quote.CouponDiscount = CouponDiscount(coupon, plan.Price)
+		quote.Amount -= quote.CouponDiscount
+	}
+
+	quote.CreditBalance = CreditBalance(tx, userID)
+	if opts.UseCredit && quote.CreditBalance > 0 {
+		quote.CreditUsed = quote.CreditBalance
+		if quote.CreditUsed > quote.Amount {
+			quote.CreditUsed = quote.Amount
+		}
+		quote.Amount -= quote.CreditUsed
+	}
+	return quote, nil
+}
+
+// QuoteCheckout menampilkan rincian harga tanpa membuat pembayaran
+func QuoteCheckout(userID uint, plan models.Plan, opts CheckoutOptions) (CheckoutQuote, error) {
+	return quoteCheckout(config.DB, userID, plan, opts, false)
+}
+
+// CheckoutResult adalah hasil checkout. Session kosong jika pembayaran lunas dengan kredit/kupon.
+type CheckoutResult struct {
+	Payment models.Payment
+	Quote   CheckoutQuote
+	Session *CheckoutSession
+}
+
+// Checkout membuat pembayaran untuk paket. Kupon dan kredit dicadangkan di transaksi yang sama
+// dengan pembuatan pembayaran, lalu dilepas lagi jika pembayaran gagal. Pembayaran dengan
+// nominal akhir 0 langsung dianggap sukses tanpa melalui payment gateway.
+func Checkout(user models.User, plan models.Plan, opts CheckoutOptions) (*CheckoutResult, error) {
+	gw := Gateway()
+	result := &CheckoutResult{}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// Kunci baris user agar saldo kredit tidak dipakai dua kali oleh checkout bersamaan
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.User{}, user.ID).Error; err != nil {
+			return err
+		}
+		if opts.ReferralCode != "" {
+			if err := applyReferralCode(tx, user.ID, opts.ReferralCode); err != nil {
+				return err
+			}
+		}
+
+		quote, err := quoteCheckout(tx, user.ID, plan, opts, true)
+		if err != nil {
+			return err
+		}
+
+		gatewayName := gw.Name()
+		if quote.Amount == 0 {
+			gatewayName = "credit"
+		}
+		payment := models.Payment{
+			UserID:         user.ID,
+			OrderID:        fmt.Sprintf("ORDER-%d-%d", user.ID, time.Now().UnixNano()),
+			Amount:         quote.Amount,
+			Status:         PaymentPending,
+			PaymentType:    plan.Code,
+			PlanID:         &plan.ID,
+			Gateway:        gatewayName,
+			OriginalAmount: quote.OriginalAmount,
+			DiscountAmount: quote.CouponDiscount,
+			CreditUsed:     quote.CreditUsed,
+			CouponCode:     quote.CouponCode,
+		}
+		if err := tx.Create(&payment).Error; err != nil {
+			return err
+		}
+
+		if quote.couponID != 0 {
+			if err := tx.Create(&models.CouponRedemption{
+				CouponID:  quote.couponID,
+				UserID:    user.ID,
+				PaymentID: payment.ID,
+				Code:      quote.CouponCode,
+				Discount:  quote.CouponDiscount,
+				Status:    RedemptionPending,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		if quote.CreditUsed > 0 {
+			if err := tx.Create(&models.CreditTransaction{
+				UserID:    user.ID,
+				Amount:    -quote.CreditUsed,
+				Reason:    CreditCheckout,
+				PaymentID: &payment.ID,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		result.Payment = payment
+		result.Quote = quote
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	payment := &result.Payment
+	if payment.Amount == 0 {
+		applied, _, err := ApplyPaymentStatus(payment.OrderID, PaymentSuccess, 0)
+		if err != nil {
+			return nil, err
+		}
+		result.Payment = *applied
+		return result, nil
+	}
+
+	session, err := gw.CreateCheckout(CheckoutRequest{
+		OrderID:       payment.OrderID,
+		Amount:        payment.Amount,
+		ItemID:        plan.Code,
+		ItemName:      plan.Name,
+		CustomerName:  user.Username,
+		CustomerEmail: user.Email,
+		CustomerPhone: user.Phone,
+	})
+	if err != nil {
+		// Lepaskan kupon dan kredit yang sudah dicadangkan
+		if _, _, releaseErr := ApplyPaymentStatus(payment.OrderID, PaymentFailure, payment.Amount); releaseErr != nil {
+			log.Printf("[Checkout] gagal membatalkan %s: %v", payment.OrderID, releaseErr)
+		}
+		return nil, err
+	}
+
+	payment.SnapURL = session.RedirectURL
+	payment.TransactionID = session.Token
+	config.DB.Model(payment).Select("SnapURL", "TransactionID").Updates(payment)
+	result.Session = session
+	return result, nil
+}
+
+// settleCheckoutLedger menandai kupon terpakai dan memberi reward referral saat pembayaran sukses
+func settleCheckoutLedger(tx *gorm.DB, payment models.Payment) error {
+	if err := tx.Model(&models.CouponRedemption{}).
+		Where("payment_id = ? AND status = ?", payment.ID, RedemptionPending).
+		Update("status", RedemptionRedeemed).Error; err != nil {
+		return err
+	}
+	return rewardReferrer(tx, payment)
+}
+
+// releaseCheckoutLedger melepas kupon dan mengembalikan kredit dari pembayaran yang gagal
+func releaseCheckoutLedger(tx *gorm.DB, payment models.Payment) error {
+	if err := tx.Model(&models.CouponRedemption{}).
+		Where("payment_id = ? AND status = ?", payment.ID, RedemptionPending).
+		Update("status", RedemptionReleased).Error; err != nil {
+		return err
+	}
+	if payment.CreditUsed > 0 {
+		return tx.Create(&models.CreditTransaction{
+			UserID:    payment.UserID,
+			Amount:    payment.CreditUsed,
+			Reason:    CreditCheckoutRelease,
+			PaymentID: &payment.ID,
+		}).Error
+	}
+	return nil
+}
+
+// reverseCheckoutLedger membatalkan efek checkout dari pembayaran yang direfund penuh:
+// kupon dilepas, kredit yang dipakai dikembalikan, dan reward referral ditarik.
+// Hanya berjalan sekali per pembayaran karena dijaga oleh mutasi refund_release/referral_reversal.
+func reverseCheckoutLedger(tx *gorm.DB, payment models.Payment) error {
+	var reversed int64
+	tx.Model(&models.CreditTransaction{}).
+		Where("payment_id = ? AND reason IN ?", payment.ID, []string{CreditRefundRelease, CreditReferralReversal}).
+		Count(&reversed)
+	if reversed > 0 {
+		return nil
+	}
+
+	if err := tx.Model(&models.CouponRedemption{}).
+		Where("payment_id = ? AND status = ?", payment.ID, RedemptionRedeemed).
+		Update("status", RedemptionRefunded).Error; err != nil {
+		return err
+	}
+	if payment.CreditUsed > 0 {
+		if err := tx.Create(&models.CreditTransaction{
+			UserID:    payment.UserID,
+			Amount:    payment.CreditUsed,
+			Reason:    CreditRefundRelease,
+			PaymentID: &payment.ID,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return reverseReferralReward(tx, payment)
+}
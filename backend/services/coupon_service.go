@@ -0,0 +1,121 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/imam/backend-blog-kuis/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Status redemption kupon
+const (
+	RedemptionPending  = "pending"
+	RedemptionRedeemed = "redeemed"
+	RedemptionReleased = "released"
+	RedemptionRefunded = "refunded"
+)
+
+var (
+	ErrCouponNotFound       = errors.New("kode promo tidak ditemukan")
+	ErrCouponNotActive      = errors.New("kode promo belum berlaku atau sudah berakhir")
+	ErrCouponExhausted      = errors.New("kuota kode promo sudah habis")
+	ErrCouponUserLimit      = errors.New("anda sudah mencapai batas pemakaian kode promo ini")
+	ErrCouponPlanNotAllowed = errors.New("kode promo tidak berlaku untuk paket ini")
+)
+
+// NormalizeCouponCode menyamakan format kode promo (huruf besar, tanpa spasi)
+func NormalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// CouponDiscount menghitung potongan kupon untuk harga tertentu (tidak melebihi harga)
+func CouponDiscount(coupon models.Coupon, price int64) int64 {
+	var discount int64
+	switch coupon.DiscountType {
+	case "percent":
+		discount = price * coupon.DiscountValue / 100
+		if coupon.MaxDiscount > 0 && discount > coupon.MaxDiscount {
+			discount = coupon.MaxDiscount
+		}
+	case "fixed":
+		discount = coupon.DiscountValue
+	}
+	if discount > price {
+		discount = price
+	}
+	return discount
+}
+
+// usedRedemptionStatuses adalah redemption yang dihitung terhadap kuota (yang dilepas tidak dihitung)
+var usedRedemptionStatuses = []string{RedemptionPending, RedemptionRedeemed}
+
+// findApplicableCoupon mencari kupon dan memeriksa masa berlaku, kuota, batas per user, dan paket.
+// lock = true mengunci baris kupon agar kuota tidak terlampaui oleh checkout bersamaan.
+func findApplicableCoupon(tx *gorm.DB, code string, userID, planID uint, lock bool) (models.Coupon, error) {
+	var coupon models.Coupon
+	query := tx.Where("code = ? AND active = ?", NormalizeCouponCode(code), true)
+	if lock {
+		query = query.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	if err := query.First(&coupon).Error; err != nil {
+		return coupon, ErrCouponNotFound
+	}
+
+	now := time.Now()
+	if (coupon.ValidFrom != nil && now.Before(*coupon.ValidFrom)) || (coupon.ValidUntil != nil && !now.Before(*coupon.ValidUntil)) {
+		return coupon, ErrCouponNotActive
+	}
+
+	if len(coupon.PlanIDs) > 0 {
+		allowed := false
+		for _, id := range coupon.PlanIDs {
+			if id == planID {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return coupon, ErrCouponPlanNotAllowed
+		}
+	}
+
+	if coupon.MaxRedemptions > 0 {
+		var used int64
+		tx.Model(&models.CouponRedemption{}).Where("coupon_id = ? AND status IN ?", coupon.ID, usedRedemptionStatuses).Count(&used)
+		if int(used) >= coupon.MaxRedemptions {
+			return coupon, ErrCouponExhausted
+		}
+	}
+	if coupon.PerUserLimit > 0 {
+		var used int64
+		tx.Model(&models.CouponRedemption{}).Where("coupon_id = ? AND user_id = ? AND status IN ?", coupon.ID, userID, usedRedemptionStatuses).Count(&used)
+		if int(used) >= coupon.PerUserLimit {
+			return coupon, ErrCouponUserLimit
+		}
+	}
+
+	return coupon, nil
+}
+
+// CouponRedemptionCounts mengembalikan jumlah redemption terpakai per kupon
+func CouponRedemptionCounts(tx *gorm.DB, couponIDs []uint) map[uint]int {
+	counts := map[uint]int{}
+	if len(couponIDs) == 0 {
+		return counts
+	}
+	var rows []struct {
+		CouponID uint
+		Total    int
+	}
+	tx.Model(&models.CouponRedemption{}).
+		Select("coupon_id, COUNT(*) AS total").
+		Where("coupon_id IN ? AND status IN ?", couponIDs, usedRedemptionStatuses).
+		Group("coupon_id").Scan(&rows)
+	for _, r := range rows {
+		counts[r.CouponID] = r.Total
+	}
+	return counts
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/imam/backend-blog-kuis/models"
+)
+
+func TestCouponDiscount(t *testing.T) {
+	tests := []struct {
+		name   string
+		coupon models.Coupon
+		price  int64
+		want   int64
+	}{
+		{"persen", models.Coupon{DiscountType: "percent", DiscountValue: 20}, 100000, 20000},
+		{"persen dibulatkan ke bawah", models.Coupon{DiscountType: "percent", DiscountValue: 15}, 99999, 14999},
+		{"persen dibatasi max discount", models.Coupon{DiscountType: "percent", DiscountValue: 50, MaxDiscount: 25000}, 100000, 25000},
+		{"persen di bawah max discount", models.Coupon{DiscountType: "percent", DiscountValue: 10, MaxDiscount: 25000}, 100000, 10000},
+		{"persen 100 gratis", models.Coupon{DiscountType: "percent", DiscountValue: 100}, 100000, 100000},
+		{"persen lebih dari 100 tidak melebihi harga", models.Coupon{DiscountType: "percent", DiscountValue: 150}, 100000, 100000},
+		{"nominal tetap", models.Coupon{DiscountType: "fixed", DiscountValue: 30000}, 100000, 30000},
+		{"nominal tetap melebihi harga", models.Coupon{DiscountType: "fixed", DiscountValue: 150000}, 100000, 100000},
+		{"nominal tetap max discount diabaikan", models.Coupon{DiscountType: "fixed", DiscountValue: 30000, MaxDiscount: 1000}, 100000, 30000},
+		{"harga nol", models.Coupon{DiscountType: "fixed", DiscountValue: 30000}, 0, 0},
+		{"tipe tidak dikenal", models.Coupon{DiscountType: "bogus", DiscountValue: 30000}, 100000, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CouponDiscount(tt.coupon, tt.price); got != tt.want {
+				t.Errorf("CouponDiscount() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeCouponCode(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{" hemat50 ", "HEMAT50"},
+		{"Promo", "PROMO"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeCouponCode(tt.in); got != tt.want {
+			t.Errorf("NormalizeCouponCode(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
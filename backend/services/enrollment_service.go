@@ -0,0 +1,197 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/imam/backend-blog-kuis/config"
+	"github.com/imam/backend-blog-kuis/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrCohortNotFound   = errors.New("cohort tidak ditemukan")
+	ErrCohortMismatch   = errors.New("cohort tidak termasuk dalam learning path ini")
+	ErrEnrollmentClosed = errors.New("pendaftaran cohort sedang ditutup")
+	ErrCohortFull       = errors.New("kuota cohort sudah penuh")
+	ErrPathNotFound     = errors.New("learning path tidak ditemukan")
+	ErrPremiumRequired  = errors.New("learning path premium, silakan upgrade ke PRO")
+	ErrNotEnrolled      = errors.New("anda belum terdaftar di learning path ini")
+)
+
+// HasEntitlement memeriksa apakah user boleh mengakses learning path premium:
+// admin selalu boleh, user lain harus memiliki langganan yang masih aktif
+func HasEntitlement(userID uint, path models.LearningPath) bool {
+	if !path.IsPremium {
+		return true
+	}
+	if userID == 0 {
+		return false
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		return false
+	}
+	if user.Role == "admin" || user.Role == "super_admin" {
+		return true
+	}
+	return HasActiveSubscription(userID)
+}
+
+// EnrollUser mendaftarkan user ke learning path, opsional ke cohort tertentu.
+// Jika user sudah terdaftar, cohort pada enrollment yang ada akan diperbarui.
+// Enrollment yang pernah dibatalkan dipulihkan beserta cohort lamanya agar drip tidak terlewati;
+// cohort lama yang sudah penuh atau ditutup ditolak seperti pendaftaran baru.
+// LearningPath.UserCount ikut dinaikkan dalam transaksi yang sama.
+func EnrollUser(userID, pathID uint, cohortID *uint) (models.Enrollment, error) {
+	var enrollment models.Enrollment
+
+	var path models.LearningPath
+	if err := config.DB.First(&path, pathID).Error; err != nil {
+		return enrollment, ErrPathNotFound
+	}
+	if !HasEntitlement(userID, path) {
+		return enrollment, ErrPremiumRequired
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		existing := tx.Unscoped().Where("user_id = ? AND learning_path_id = ?", userID, pathID).First(&enrollment).Error
+		if existing != nil && !errors.Is(existing, gorm.ErrRecordNotFound) {
+			return existing
+		}
+
+		restoring := enrollment.ID != 0 && enrollment.DeletedAt.Valid
+		if restoring && cohortID == nil {
+			// Daftar ulang tanpa memilih cohort kembali ke cohort lama, dengan aturan kuota dan
+			// jadwal pendaftaran yang sama seperti pendaftar baru
+			cohortID = enrollment.CohortID
+		}
+		if cohortID != nil && (restoring || enrollment.CohortID == nil || *enrollment.CohortID != *cohortID) {
+			if err := checkCohortJoinable(tx, *cohortID, pathID); err != nil {
+				return err
+			}
+		}
+
+		if enrollment.ID != 0 && !enrollment.DeletedAt.Valid {
+			if cohortID == nil {
+				return nil
+			}
+			enrollment.CohortID = cohortID
+			return tx.Model(&enrollment).Update("cohort_id", cohortID).Error
+		}
+
+		if restoring {
+			enrollment.CohortID = cohortID
+			enrollment.EnrolledAt = time.Now()
+			enrollment.DeletedAt = gorm.DeletedAt{}
+			if err := tx.Unscoped().Model(&enrollment).Updates(map[string]interface{}{
+				"cohort_id":   enrollment.CohortID,
+				"enrolled_at": enrollment.EnrolledAt,
+				"deleted_at":  nil,
+			}).Error; err != nil {
+				return err
+			}
+			return tx.Model(&models.LearningPath{}).Where("id = ?", pathID).
+				UpdateColumn("user_count", gorm.Expr("user_count + 1")).Error
+		}
+
+		enrollment = models.Enrollment{
+			UserID:         userID,
+			LearningPathID: pathID,
+			CohortID:       cohortID,
+			EnrolledAt:     time.Now(),
+		}
+		if err := tx.Create(&enrollment).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.LearningPath{}).Where("id = ?", pathID).
+			UpdateColumn("user_count", gorm.Expr("user_count + 1")).Error
+	})
+
+	return enrollment, err
+}
+
+// checkCohortJoinable memastikan cohort milik path, pendaftarannya dibuka, dan kuotanya masih ada.
+// Baris cohort dikunci agar pengecekan kuota tidak balapan dengan pendaftar lain.
+func checkCohortJoinable(tx *gorm.DB, cohortID, pathID uint) error {
+	var cohort models.Cohort
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&cohort, cohortID).Error; err != nil {
+		return ErrCohortNotFound
+	}
+	if cohort.LearningPathID != pathID {
+		return ErrCohortMismatch
+	}
+	if !cohort.EnrollmentOpen(time.Now()) {
+		return ErrEnrollmentClosed
+	}
+	if cohort.Capacity > 0 {
+		var enrolled int64
+		tx.Model(&models.Enrollment{}).Where("cohort_id = ?", cohort.ID).Count(&enrolled)
+		if int(enrolled) >= cohort.Capacity {
+			return ErrCohortFull
+		}
+	}
+	return nil
+}
+
+// UnenrollUser membatalkan pendaftaran user dan menurunkan LearningPath.UserCount.
+// Progres belajar dan cohort user tidak dihapus sehingga bisa dilanjutkan jika mendaftar lagi.
+func UnenrollUser(userID, pathID uint) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("user_id = ? AND learning_path_id = ?", userID, pathID).Delete(&models.Enrollment{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotEnrolled
+		}
+		return tx.Model(&models.LearningPath{}).Where("id = ?", pathID).
+			UpdateColumn("user_count", gorm.Expr("GREATEST(user_count - 1, 0)")).Error
+	})
+}
+
+// EnrolledPaths mengembalikan enrollment user dalam bentuk map pathID -> Enrollment
+func EnrolledPaths(userID uint) map[uint]models.Enrollment {
+	result := map[uint]models.Enrollment{}
+	if userID == 0 {
+		return result
+	}
+	var enrollments []models.Enrollment
+	config.DB.Where("user_id = ?", userID).Find(&enrollments)
+	for _, e := range enrollments {
+		result[e.LearningPathID] = e
+	}
+	return result
+}
+
+// FindEnrollment mengambil enrollment user pada sebuah path beserta cohort-nya
+func FindEnrollment(userID, pathID uint) (models.Enrollment, bool) {
+	var enrollment models.Enrollment
+	if userID == 0 {
+		return enrollment, false
+	}
+	err := config.DB.Preload("Cohort").
+		Where("user_id = ? AND learning_path_id = ?", userID, pathID).
+		First(&enrollment).Error
+	return enrollment, err == nil
+}
+
+// chapterUnlockTimes menghitung kapan setiap bab terbuka untuk enrollment cohort.
+// Mengembalikan nil jika user tidak terdaftar di cohort (tanpa jadwal drip).
+func chapterUnlockTimes(userID, pathID uint) map[uint]time.Time {
+	enrollment, ok := FindEnrollment(userID, pathID)
+	if !ok || enrollment.Cohort == nil {
+		return nil
+	}
+
+	var chapters []models.Chapter
+	config.DB.Select("id, unlock_offset_days").Where("learning_path_id = ?", pathID).Find(&chapters)
+
+	unlocks := map[uint]time.Time{}
+	for _, ch := range chapters {
+		unlocks[ch.ID] = enrollment.Cohort.StartDate.AddDate(0, 0, ch.UnlockOffsetDays)
+	}
+	return unlocks
+}
@@ -0,0 +1,330 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/imam/backend-blog-kuis/config"
+	"github.com/imam/backend-blog-kuis/models"
+	"github.com/imam/backend-blog-kuis/utils"
+	"github.com/jung-kurt/gofpdf"
+	"gorm.io/gorm"
+)
+
+var ErrInvoiceNotFound = errors.New("invoice tidak ditemukan")
+
+// invoiceIssuer mengembalikan nama dan alamat penerbit invoice (INVOICE_ISSUER_NAME, INVOICE_ISSUER_ADDRESS)
+func invoiceIssuer() (string, string) {
+	name := os.Getenv("INVOICE_ISSUER_NAME")
+	if name == "" {
+		name = "AIoT Chain"
+	}
+	return name, os.Getenv("INVOICE_ISSUER_ADDRESS")
+}
+
+// FormatRupiah memformat nominal menjadi "Rp 99.000"
+func FormatRupiah(amount int64) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	digits := strconv.FormatInt(amount, 10)
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(d)
+	}
+	return sign + "Rp " + b.String()
+}
+
+// nextInvoiceNumber menaikkan penghitung tahun berjalan secara atomik. Karena dijalankan di
+// transaksi yang sama dengan penerbitan invoice, nomor yang gagal dipakai ikut di-rollback.
+func nextInvoiceNumber(tx *gorm.DB, issuedAt time.Time) (string, error) {
+	year := issuedAt.Year()
+	var next int
+	err := tx.Raw(`INSERT INTO invoice_counters (year, last_number) VALUES (?, 1)
+		ON CONFLICT (year) DO UPDATE SET last_number = invoice_counters.last_number + 1
+		RETURNING last_number`, year).Scan(&next).Error
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("INV-%d-%06d", year, next), nil
+}
+
+// issueInvoice menerbitkan invoice untuk pembayaran sukses; tidak melakukan apa pun jika sudah ada
+func issueInvoice(tx *gorm.DB, payment models.Payment) error {
+	var existing int64
+	tx.Model(&models.Invoice{}).Where("payment_id = ?", payment.ID).Count(&existing)
+	if existing > 0 {
+		return nil
+	}
+
+	var user models.User
+	if err := tx.First(&user, payment.UserID).Error; err != nil {
+		return err
+	}
+	description := payment.PaymentType
+	if payment.PlanID != nil {
+		var plan models.Plan
+		if tx.First(&plan, *payment.PlanID).Error == nil {
+			description = plan.Name
+		}
+	}
+
+	issuedAt := time.Now()
+	if payment.PaidAt != nil {
+		issuedAt = *payment.PaidAt
+	}
+	number, err := nextInvoiceNumber(tx, issuedAt)
+	if err != nil {
+		return err
+	}
+
+	// Pembayaran lama belum punya rincian harga
+	original := payment.OriginalAmount
+	if original == 0 {
+		original = payment.Amount + payment.DiscountAmount + payment.CreditUsed
+	}
+
+	return tx.Create(&models.Invoice{
+		Number:         number,
+		PaymentID:      payment.ID,
+		UserID:         user.ID,
+		BilledName:     user.Username,
+		BilledEmail:    user.Email,
+		Description:    description,
+		OriginalAmount: original,
+		DiscountAmount: payment.DiscountAmount,
+		CreditUsed:     payment.CreditUsed,
+		Amount:         payment.Amount,
+		CouponCode:     payment.CouponCode,
+		IssuedAt:       issuedAt,
+	}).Error
+}
+
+// FindInvoice memuat invoice beserta pembayarannya
+func FindInvoice(id uint) (*models.Invoice, error) {
+	var invoice models.Invoice
+	if err := config.DB.Preload("Payment").First(&invoice, id).Error; err != nil {
+		return nil, ErrInvoiceNotFound
+	}
+	return &invoice, nil
+}
+
+// InvoiceDocument adalah data yang dirender ke HTML/PDF invoice
+type InvoiceDocument struct {
+	Invoice       models.Invoice
+	IssuerName    string
+	IssuerAddress string
+	Status        string
+	Refunds       []models.PaymentRefund
+	Lines         [][2]string // Label dan nominal rincian harga
+	Total         string
+	Refunded      string
+	DownloadURL   string
+}
+
+// invoiceDocument menyiapkan rincian invoice yang sama untuk HTML dan PDF
+func invoiceDocument(invoice models.Invoice) InvoiceDocument {
+	name, address := invoiceIssuer()
+	doc := InvoiceDocument{
+		Invoice:       invoice,
+		IssuerName:    name,
+		IssuerAddress: address,
+		Status:        "LUNAS",
+		Total:         FormatRupiah(invoice.Amount),
+		DownloadURL:   fmt.Sprintf("%s/account/invoices/%d", utils.FrontendURL(), invoice.ID),
+	}
+
+	doc.Lines = append(doc.Lines, [2]string{invoice.Description, FormatRupiah(invoice.OriginalAmount)})
+	if invoice.DiscountAmount > 0 {
+		label := "Diskon"
+		if invoice.CouponCode != "" {
+			label += " (" + invoice.CouponCode + ")"
+		}
+		doc.Lines = append(doc.Lines, [2]string{label, FormatRupiah(-invoice.DiscountAmount)})
+	}
+	if invoice.CreditUsed > 0 {
+		doc.Lines = append(doc.Lines, [2]string{"Kredit referral", FormatRupiah(-invoice.CreditUsed)})
+	}
+
+	config.DB.Where("payment_id = ? AND status = ?", invoice.PaymentID, RefundCompleted).Order("created_at ASC").Find(&doc.Refunds)
+	if invoice.Payment.RefundedAmount > 0 {
+		doc.Refunded = FormatRupiah(invoice.Payment.RefundedAmount)
+		doc.Status = "DIKEMBALIKAN SEBAGIAN"
+		if invoice.Payment.RefundedAmount >= invoice.Amount {
+			doc.Status = "DIKEMBALIKAN"
+		}
+	}
+	return doc
+}
+
+var invoiceHTMLTemplate = template.Must(template.New("invoice").Funcs(template.FuncMap{
+	"rupiah": FormatRupiah,
+	"date":   func(t time.Time) string { return t.Format("02 Jan 2006") },
+}).Parse(`<!DOCTYPE html>
+<html lang="id">
+<head><meta charset="UTF-8"><title>Invoice {{.Invoice.Number}}</title></head>
+<body style="font-family: Arial, sans-serif; color: #1f2937; max-width: 640px; margin: 0 auto;">
+  <h2 style="margin-bottom: 0;">{{.IssuerName}}</h2>
+  {{if .IssuerAddress}}<p style="margin-top: 4px; color: #6b7280;">{{.IssuerAddress}}</p>{{end}}
+  <h3>INVOICE {{.Invoice.Number}}</h3>
+  <table style="width: 100%; margin-bottom: 16px;">
+    <tr><td>Tanggal</td><td>{{date .Invoice.IssuedAt}}</td></tr>
+    <tr><td>Order ID</td><td>{{.Invoice.Payment.OrderID}}</td></tr>
+    <tr><td>Ditagihkan kepada</td><td>{{.Invoice.BilledName}} &lt;{{.Invoice.BilledEmail}}&gt;</td></tr>
+    <tr><td>Status</td><td><b>{{.Status}}</b></td></tr>
+  </table>
+  <table style="width: 100%; border-collapse: collapse;">
+    {{range .Lines}}<tr><td style="padding: 6px 0; border-bottom: 1px solid #e5e7eb;">{{index . 0}}</td><td style="text-align: right; border-bottom: 1px solid #e5e7eb;">{{index . 1}}</td></tr>
+    {{end}}<tr><td style="padding: 8px 0;"><b>Total dibayar</b></td><td style="text-align: right;"><b>{{.Total}}</b></td></tr>
+    {{range .Refunds}}<tr><td style="padding: 4px 0; color: #b91c1c;">Pengembalian dana {{date .CreatedAt}}</td><td style="text-align: right; color: #b91c1c;">-{{rupiah .Amount}}</td></tr>
+    {{end}}
+  </table>
+  <p style="margin-top: 24px; color: #6b7280; font-size: 12px;">Unduh invoice ini kapan saja dari akun Anda: <a href="{{.DownloadURL}}">{{.DownloadURL}}</a></p>
+</body>
+</html>`))
+
+// RenderInvoiceHTML merender invoice sebagai halaman HTML (juga dipakai sebagai isi email)
+func RenderInvoiceHTML(invoice models.Invoice) (string, error) {
+	var buf bytes.Buffer
+	if err := invoiceHTMLTemplate.Execute(&buf, invoiceDocument(invoice)); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// RenderInvoicePDF merender invoice sebagai PDF A4 portrait
+func RenderInvoicePDF(invoice models.Invoice) ([]byte, error) {
+	doc := invoiceDocument(invoice)
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetMargins(20, 20, 20)
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.CellFormat(0, 9, tr(doc.IssuerName), "", 1, "L", false, 0, "")
+	if doc.IssuerAddress != "" {
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.SetTextColor(107, 114, 128)
+		pdf.MultiCell(0, 5, tr(doc.IssuerAddress), "", "L", false)
+		pdf.SetTextColor(0, 0, 0)
+	}
+	pdf.Ln(6)
+
+	pdf.SetFont("Helvetica", "B", 14)
+	pdf.CellFormat(0, 8, "INVOICE "+invoice.Number, "", 1, "L", false, 0, "")
+	pdf.Ln(2)
+
+	pdf.SetFont("Helvetica", "", 10)
+	for _, row := range [][2]string{
+		{"Tanggal", invoice.IssuedAt.Format("02 Jan 2006")},
+		{"Order ID", invoice.Payment.OrderID},
+		{"Ditagihkan kepada", invoice.BilledName + " <" + invoice.BilledEmail + ">"},
+		{"Status", doc.Status},
+	} {
+		pdf.CellFormat(45, 6, tr(row[0]), "", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 6, tr(row[1]), "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(6)
+
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.SetFillColor(243, 244, 246)
+	pdf.CellFormat(120, 8, "Deskripsi", "B", 0, "L", true, 0, "")
+	pdf.CellFormat(0, 8, "Jumlah", "B", 1, "R", true, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	for _, line := range doc.Lines {
+		pdf.CellFormat(120, 7, tr(line[0]), "B", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 7, line[1], "B", 1, "R", false, 0, "")
+	}
+	pdf.SetFont("Helvetica", "B", 11)
+	pdf.CellFormat(120, 9, "Total dibayar", "", 0, "L", false, 0, "")
+	pdf.CellFormat(0, 9, doc.Total, "", 1, "R", false, 0, "")
+
+	if len(doc.Refunds) > 0 {
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.SetTextColor(185, 28, 28)
+		for _, refund := range doc.Refunds {
+			pdf.CellFormat(120, 6, "Pengembalian dana "+refund.CreatedAt.Format("02 Jan 2006"), "", 0, "L", false, 0, "")
+			pdf.CellFormat(0, 6, "-"+FormatRupiah(refund.Amount), "", 1, "R", false, 0, "")
+		}
+		pdf.SetTextColor(0, 0, 0)
+	}
+
+	pdf.Ln(10)
+	pdf.SetFont("Helvetica", "I", 8)
+	pdf.SetTextColor(107, 114, 128)
+	pdf.MultiCell(0, 4, "Invoice ini diterbitkan secara elektronik dan sah tanpa tanda tangan.", "", "L", false)
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SendInvoiceEmail mengirim invoice ke email pembeli dan mencatat waktu pengirimannya
+func SendInvoiceEmail(invoiceID uint) error {
+	invoice, err := FindInvoice(invoiceID)
+	if err != nil {
+		return err
+	}
+	body, err := RenderInvoiceHTML(*invoice)
+	if err != nil {
+		return err
+	}
+	if err := utils.SendEmail(invoice.BilledEmail, "Invoice "+invoice.Number+" - AIOT", body); err != nil {
+		return err
+	}
+	return config.DB.Model(invoice).Update("emailed_at", time.Now()).Error
+}
+
+// sendPaymentInvoice mengirim invoice pembayaran di background setelah transaksi commit
+func sendPaymentInvoice(paymentID uint) {
+	var invoice models.Invoice
+	if err := config.DB.Where("payment_id = ?", paymentID).First(&invoice).Error; err != nil {
+		return
+	}
+	go func() {
+		if err := SendInvoiceEmail(invoice.ID); err != nil {
+			log.Printf("[Invoice] gagal mengirim %s: %v", invoice.Number, err)
+		}
+	}()
+}
+
+// IssueMissingInvoices menerbitkan invoice untuk pembayaran sukses yang dibuat sebelum fitur invoice ada.
+// Email tidak dikirim ulang untuk pembayaran lama.
+func IssueMissingInvoices() {
+	var payments []models.Payment
+	config.DB.Where("status IN ? AND NOT EXISTS (SELECT 1 FROM invoices WHERE invoices.payment_id = payments.id)",
+		[]string{PaymentSuccess, PaymentPartialRefund, PaymentRefund}).
+		Order("COALESCE(paid_at, updated_at) ASC").Find(&payments)
+
+	for _, payment := range payments {
+		if payment.PaidAt == nil {
+			paidAt := payment.UpdatedAt
+			payment.PaidAt = &paidAt
+		}
+		if err := config.DB.Transaction(func(tx *gorm.DB) error {
+			return issueInvoice(tx, payment)
+		}); err != nil {
+			log.Printf("[Invoice] gagal menerbitkan invoice %s: %v", payment.OrderID, err)
+			return
+		}
+	}
+
+	if len(payments) > 0 {
+		log.Printf("[Invoice] %d invoice untuk pembayaran lama diterbitkan", len(payments))
+	}
+}
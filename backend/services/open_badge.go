@@ -0,0 +1,337 @@
+package services
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"html"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg"
+	"image/png"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/imam/backend-blog-kuis/models"
+	"github.com/imam/backend-blog-kuis/utils"
+)
+
+// Context JSON-LD untuk Open Badges 3.0 (Verifiable Credentials Data Model 2.0)
+var openBadgeContext = []interface{}{
+	"https://www.w3.org/ns/credentials/v2",
+	"https://purl.imsglobal.org/spec/ob/v3p0/context-3.0.3.json",
+}
+
+// badgeIssuerName mengembalikan nama penerbit badge (BADGE_ISSUER_NAME)
+func badgeIssuerName() string {
+	if name := os.Getenv("BADGE_ISSUER_NAME"); name != "" {
+		return name
+	}
+	return "AIoT Chain"
+}
+
+// BadgeIssuerURL adalah ID profil penerbit Open Badges
+func BadgeIssuerURL(baseURL string) string {
+	return baseURL + "/api/badges/issuer"
+}
+
+// BadgeAchievementURL adalah ID badge class (Achievement) sebuah learning path
+func BadgeAchievementURL(baseURL string, pathID uint) string {
+	return fmt.Sprintf("%s/api/badges/achievements/%d", baseURL, pathID)
+}
+
+// BadgeCredentialURL adalah ID credential Open Badges sebuah sertifikat
+func BadgeCredentialURL(baseURL, certificateID string) string {
+	return baseURL + "/api/certificates/" + certificateID + "/badge"
+}
+
+// BadgeIssuerProfile membangun profil penerbit (Profile) beserta kunci verifikasinya (Multikey)
+func BadgeIssuerProfile(baseURL string) (map[string]interface{}, error) {
+	key, kid, err := certificateSigningKey()
+	if err != nil {
+		return nil, err
+	}
+
+	issuerURL := BadgeIssuerURL(baseURL)
+	profile := map[string]interface{}{
+		"@context": openBadgeContext,
+		"id":       issuerURL,
+		"type":     []string{"Profile"},
+		"name":     badgeIssuerName(),
+		"url":      utils.FrontendURL(),
+		"verificationMethod": []map[string]interface{}{{
+			"id":                 issuerURL + "#" + kid,
+			"type":               "Multikey",
+			"controller":         issuerURL,
+			"publicKeyMultibase": ed25519Multikey(key.Public().(ed25519.PublicKey)),
+		}},
+	}
+	if email := os.Getenv("BADGE_ISSUER_EMAIL"); email != "" {
+		profile["email"] = email
+	}
+	return profile, nil
+}
+
+// BadgeAchievement membangun metadata badge class dari learning path
+func BadgeAchievement(baseURL string, path models.LearningPath) map[string]interface{} {
+	tags := []string{}
+	if path.Category.Name != "" {
+		tags = append(tags, path.Category.Name)
+	}
+	if path.Difficulty != "" {
+		tags = append(tags, path.Difficulty)
+	}
+
+	achievementURL := BadgeAchievementURL(baseURL, path.ID)
+	achievement := map[string]interface{}{
+		"id":              achievementURL,
+		"type":            []string{"Achievement"},
+		"achievementType": "Certificate",
+		"name":            path.Title,
+		"description":     path.Description,
+		"criteria": map[string]interface{}{
+			"id":        fmt.Sprintf("%s/learning-paths/%d", utils.FrontendURL(), path.ID),
+			"narrative": fmt.Sprintf("Menyelesaikan seluruh materi learning path \"%s\" (tingkat %s).", path.Title, path.Difficulty),
+		},
+		"image": map[string]interface{}{
+			"id":   achievementURL + "/image",
+			"type": "Image",
+		},
+		"tag":     tags,
+		"creator": map[string]interface{}{"id": BadgeIssuerURL(baseURL), "type": []string{"Profile"}, "name": badgeIssuerName()},
+	}
+	if path.Category.Name != "" {
+		achievement["fieldOfStudy"] = path.Category.Name
+	}
+	return achievement
+}
+
+// OpenBadgeCredential membangun OpenBadgeCredential (JSON-LD) untuk sertifikat dan menandatanganinya
+// dengan DataIntegrityProof eddsa-jcs-2022 memakai kunci yang sama dengan tanda tangan sertifikat.
+// cert harus sudah memuat relasi User dan LearningPath (beserta Category).
+func OpenBadgeCredential(baseURL string, cert models.Certificate) (map[string]interface{}, error) {
+	key, kid, err := certificateSigningKey()
+	if err != nil {
+		return nil, err
+	}
+
+	// Email pemegang disamarkan (hashed + salt) sesuai IdentityObject Open Badges
+	saltSum := sha256.Sum256([]byte("badge-salt:" + cert.CertificateID))
+	salt := hex.EncodeToString(saltSum[:8])
+	identityHash := sha256.Sum256([]byte(strings.ToLower(cert.User.Email) + salt))
+
+	credential := map[string]interface{}{
+		"@context":  openBadgeContext,
+		"id":        BadgeCredentialURL(baseURL, cert.CertificateID),
+		"type":      []string{"VerifiableCredential", "OpenBadgeCredential"},
+		"name":      cert.LearningPath.Title,
+		"issuer":    map[string]interface{}{"id": BadgeIssuerURL(baseURL), "type": []string{"Profile"}, "name": badgeIssuerName(), "url": utils.FrontendURL()},
+		"validFrom": cert.IssuedAt.UTC().Format(time.RFC3339),
+		"credentialSubject": map[string]interface{}{
+			"type": []string{"AchievementSubject"},
+			"name": cert.User.Username,
+			"identifier": []map[string]interface{}{{
+				"type":         "IdentityObject",
+				"identityType": "emailAddress",
+				"hashed":       true,
+				"salt":         salt,
+				"identityHash": "sha256$" + hex.EncodeToString(identityHash[:]),
+			}},
+			"achievement": BadgeAchievement(baseURL, cert.LearningPath),
+		},
+		"evidence": []map[string]interface{}{{
+			"id":          CertificateVerifyURL(cert),
+			"type":        []string{"Evidence"},
+			"name":        "Sertifikat " + cert.CertificateID,
+			"description": "Halaman verifikasi sertifikat bertanda tangan",
+		}},
+	}
+
+	// Normalisasi lewat JSON agar proof dihitung dari dokumen yang sama persis dengan yang dikirim
+	document, err := canonicalJSON(credential)
+	if err != nil {
+		return nil, err
+	}
+	var unsecured map[string]interface{}
+	if err := json.Unmarshal(document, &unsecured); err != nil {
+		return nil, err
+	}
+
+	proof := map[string]interface{}{
+		"type":               "DataIntegrityProof",
+		"cryptosuite":        "eddsa-jcs-2022",
+		"created":            time.Now().UTC().Format(time.RFC3339),
+		"verificationMethod": BadgeIssuerURL(baseURL) + "#" + kid,
+		"proofPurpose":       "assertionMethod",
+	}
+	proofConfig := map[string]interface{}{"@context": openBadgeContext}
+	for k, v := range proof {
+		proofConfig[k] = v
+	}
+	configJSON, err := canonicalJSON(proofConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	configHash := sha256.Sum256(configJSON)
+	documentHash := sha256.Sum256(document)
+	signature := ed25519.Sign(key, append(configHash[:], documentHash[:]...))
+	proof["proofValue"] = "z" + base58Encode(signature)
+
+	unsecured["proof"] = proof
+	return unsecured, nil
+}
+
+// canonicalJSON menghasilkan JSON ringkas dengan key terurut (JCS) tanpa escape HTML
+func canonicalJSON(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// ed25519Multikey mengenkode kunci publik Ed25519 sebagai Multikey (multicodec 0xed01, base58btc)
+func ed25519Multikey(pub ed25519.PublicKey) string {
+	return "z" + base58Encode(append([]byte{0xed, 0x01}, pub...))
+}
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// base58Encode mengenkode byte dengan alfabet Bitcoin (base58btc)
+func base58Encode(data []byte) string {
+	num := new(big.Int).SetBytes(data)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+
+	var out []byte
+	for num.Sign() > 0 {
+		num.DivMod(num, radix, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+	for _, b := range data {
+		if b != 0 {
+			break
+		}
+		out = append(out, base58Alphabet[0])
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+// BadgeImagePNG menggambar medali badge berwarna sertifikat path, dengan thumbnail path di tengah jika ada
+func BadgeImagePNG(path models.LearningPath) ([]byte, error) {
+	const size = 400
+	r, g, b := parseHexColor(ResolveCertificateTemplate(path).PrimaryColor)
+	primary := color.RGBA{uint8(r), uint8(g), uint8(b), 255}
+
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	center := float64(size) / 2
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			dx, dy := float64(x)+0.5-center, float64(y)+0.5-center
+			dist := dx*dx + dy*dy
+			switch {
+			case dist <= 150*150:
+				img.Set(x, y, color.White)
+			case dist <= 170*170:
+				img.Set(x, y, primary)
+			case dist <= 180*180:
+				img.Set(x, y, color.White)
+			case dist <= 196*196:
+				img.Set(x, y, primary)
+			}
+		}
+	}
+
+	// Thumbnail diperkecil (nearest neighbour) ke kotak di dalam lingkaran
+	if path.Thumbnail != "" {
+		if data, _, err := loadCertificateImage(path.Thumbnail); err == nil {
+			if thumb, _, err := image.Decode(bytes.NewReader(data)); err == nil {
+				drawScaled(img, image.Rect(100, 100, 300, 300), thumb)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// drawScaled menggambar src ke dalam dst pada area rect dengan skala nearest neighbour
+func drawScaled(dst draw.Image, rect image.Rectangle, src image.Image) {
+	sb := src.Bounds()
+	if sb.Empty() {
+		return
+	}
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		sy := sb.Min.Y + (y-rect.Min.Y)*sb.Dy()/rect.Dy()
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			sx := sb.Min.X + (x-rect.Min.X)*sb.Dx()/rect.Dx()
+			dst.Set(x, y, src.At(sx, sy))
+		}
+	}
+}
+
+// BakeBadgePNG menyisipkan credential ke chunk iTXt "openbadgecredential" sebelum chunk IEND
+func BakeBadgePNG(pngData, credential []byte) ([]byte, error) {
+	if len(pngData) < 20 || string(pngData[len(pngData)-8:len(pngData)-4]) != "IEND" {
+		return nil, fmt.Errorf("format PNG tidak valid")
+	}
+	iend := pngData[len(pngData)-12:]
+
+	var chunkData bytes.Buffer
+	chunkData.WriteString("openbadgecredential")
+	chunkData.Write([]byte{0, 0, 0}) // pemisah keyword, tanpa kompresi, metode kompresi
+	chunkData.WriteByte(0)           // tag bahasa kosong
+	chunkData.WriteByte(0)           // translated keyword kosong
+	chunkData.Write(credential)
+
+	var chunk bytes.Buffer
+	binary.Write(&chunk, binary.BigEndian, uint32(chunkData.Len()))
+	chunk.WriteString("iTXt")
+	chunk.Write(chunkData.Bytes())
+	crc := crc32.NewIEEE()
+	crc.Write([]byte("iTXt"))
+	crc.Write(chunkData.Bytes())
+	binary.Write(&chunk, binary.BigEndian, crc.Sum32())
+
+	baked := append([]byte{}, pngData[:len(pngData)-12]...)
+	baked = append(baked, chunk.Bytes()...)
+	return append(baked, iend...), nil
+}
+
+// BakedBadgeSVG membuat badge SVG dengan credential tertanam di elemen openbadges:credential
+func BakedBadgeSVG(path models.LearningPath, holder string, credential []byte) []byte {
+	r, g, b := parseHexColor(ResolveCertificateTemplate(path).PrimaryColor)
+	primary := fmt.Sprintf("#%02x%02x%02x", r, g, b)
+	title := path.Title
+	if len([]rune(title)) > 28 {
+		title = string([]rune(title)[:27]) + "…"
+	}
+
+	var svg bytes.Buffer
+	fmt.Fprintf(&svg, `<svg xmlns="http://www.w3.org/2000/svg" xmlns:openbadges="https://purl.imsglobal.org/ob/v3p0" width="400" height="400" viewBox="0 0 400 400">`)
+	fmt.Fprintf(&svg, `<openbadges:credential><![CDATA[%s]]></openbadges:credential>`, strings.ReplaceAll(string(credential), "]]>", "]]]]><![CDATA[>"))
+	fmt.Fprintf(&svg, `<circle cx="200" cy="200" r="196" fill="%s"/><circle cx="200" cy="200" r="180" fill="#ffffff"/>`, primary)
+	fmt.Fprintf(&svg, `<circle cx="200" cy="200" r="170" fill="%s"/><circle cx="200" cy="200" r="150" fill="#ffffff"/>`, primary)
+	fmt.Fprintf(&svg, `<text x="200" y="150" text-anchor="middle" font-family="Helvetica, Arial, sans-serif" font-size="20" font-weight="bold" fill="%s">%s</text>`, primary, html.EscapeString(badgeIssuerName()))
+	fmt.Fprintf(&svg, `<text x="200" y="205" text-anchor="middle" font-family="Helvetica, Arial, sans-serif" font-size="18" fill="#111111">%s</text>`, html.EscapeString(title))
+	fmt.Fprintf(&svg, `<text x="200" y="250" text-anchor="middle" font-family="Helvetica, Arial, sans-serif" font-size="14" fill="#555555">%s · %s</text>`, html.EscapeString(holder), html.EscapeString(path.Difficulty))
+	svg.WriteString(`</svg>`)
+	return svg.Bytes()
+}
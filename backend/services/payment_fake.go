@@ -0,0 +1,132 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// fakeServerKey dipakai untuk menandatangani notifikasi fake dengan skema yang sama seperti Midtrans
+const fakeServerKey = "fake-server-key"
+
+var ErrFakeOrderNotFound = errors.New("order tidak ditemukan di fake gateway")
+
+// fakeOrder adalah transaksi yang disimpan di memori oleh fake gateway
+type fakeOrder struct {
+	amount            int64
+	transactionID     string
+	transactionStatus string
+}
+
+// FakeGateway adalah PaymentGateway di dalam proses untuk pengujian end-to-end tanpa jaringan.
+// Status transaksi disimulasikan lewat Simulate, yang menghasilkan notifikasi bertanda tangan.
+type FakeGateway struct {
+	mu     sync.Mutex
+	orders map[string]*fakeOrder
+}
+
+// NewFakeGateway membuat fake gateway kosong
+func NewFakeGateway() *FakeGateway {
+	return &FakeGateway{orders: map[string]*fakeOrder{}}
+}
+
+func (g *FakeGateway) Name() string { return "fake" }
+
+func (g *FakeGateway) CreateCheckout(req CheckoutRequest) (*CheckoutSession, error) {
+	b := make([]byte, 8)
+	rand.Read(b)
+
+	g.mu.Lock()
+	g.orders[req.OrderID] = &fakeOrder{amount: req.Amount, transactionID: "fake-" + hex.EncodeToString(b), transactionStatus: "pending"}
+	g.mu.Unlock()
+
+	return &CheckoutSession{
+		Token:       "fake-token-" + req.OrderID,
+		RedirectURL: "/api/payments/fake/" + req.OrderID,
+	}, nil
+}
+
+func (g *FakeGateway) ParseNotification(body []byte) (*GatewayNotification, error) {
+	return (&midtransGateway{serverKey: fakeServerKey}).ParseNotification(body)
+}
+
+func (g *FakeGateway) CheckStatus(orderID string) (*GatewayStatus, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	order, ok := g.orders[orderID]
+	if !ok {
+		return nil, ErrFakeOrderNotFound
+	}
+	return &GatewayStatus{
+		OrderID:       orderID,
+		TransactionID: order.transactionID,
+		Status:        MidtransPaymentStatus(order.transactionStatus, "accept"),
+		GrossAmount:   order.amount,
+		PaymentMethod: "fake",
+	}, nil
+}
+
+func (g *FakeGateway) Refund(orderID, refundKey string, amount int64, reason string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	order, ok := g.orders[orderID]
+	if !ok {
+		return ErrFakeOrderNotFound
+	}
+	if order.transactionStatus != "settlement" {
+		return fmt.Errorf("transaksi %s belum settlement", orderID)
+	}
+	if amount < order.amount {
+		order.transactionStatus = "partial_refund"
+	} else {
+		order.transactionStatus = "refund"
+	}
+	return nil
+}
+
+// fakeTransactionStatuses adalah event yang bisa disimulasikan beserta status_code Midtrans-nya
+var fakeTransactionStatuses = map[string]string{
+	"settlement": "200",
+	"pending":    "201",
+	"deny":       "202",
+	"cancel":     "202",
+	"expire":     "407",
+}
+
+// Simulate mengubah status transaksi fake (settlement, pending, deny, cancel, expire) dan
+// mengembalikan body notifikasi bertanda tangan yang bisa diproses seperti webhook asli
+func (g *FakeGateway) Simulate(orderID, transactionStatus string) ([]byte, error) {
+	statusCode, ok := fakeTransactionStatuses[transactionStatus]
+	if !ok {
+		return nil, fmt.Errorf("event tidak dikenal: %s", transactionStatus)
+	}
+
+	g.mu.Lock()
+	order, found := g.orders[orderID]
+	if found {
+		order.transactionStatus = transactionStatus
+	}
+	g.mu.Unlock()
+	if !found {
+		return nil, ErrFakeOrderNotFound
+	}
+
+	grossAmount := strconv.FormatInt(order.amount, 10) + ".00"
+	signature := midtransSignature(orderID, statusCode, grossAmount, fakeServerKey)
+	return json.Marshal(map[string]string{
+		"order_id":           orderID,
+		"status_code":        statusCode,
+		"gross_amount":       grossAmount,
+		"signature_key":      signature,
+		"transaction_id":     order.transactionID,
+		"transaction_status": transactionStatus,
+		"fraud_status":       "accept",
+		"payment_type":       "fake",
+	})
+}
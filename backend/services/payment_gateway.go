@@ -0,0 +1,92 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"os"
+	"sync"
+)
+
+var (
+	ErrInvalidNotification = errors.New("notifikasi tidak valid")
+	ErrInvalidSignature    = errors.New("signature notifikasi tidak valid")
+	ErrGatewayUnavailable  = errors.New("payment gateway tidak dapat dihubungi")
+)
+
+// CheckoutRequest adalah data transaksi yang dikirim ke payment gateway
+type CheckoutRequest struct {
+	OrderID       string
+	Amount        int64
+	ItemID        string
+	ItemName      string
+	CustomerName  string
+	CustomerEmail string
+	CustomerPhone string
+}
+
+// CheckoutSession adalah hasil pembuatan transaksi (token dan URL halaman pembayaran)
+type CheckoutSession struct {
+	Token       string
+	RedirectURL string
+}
+
+// GatewayNotification adalah notifikasi gateway yang sudah diverifikasi
+type GatewayNotification struct {
+	OrderID           string
+	TransactionID     string
+	TransactionStatus string // Status asli dari gateway
+	StatusCode        string
+	GrossAmount       string
+	Status            string // Status internal (lihat PaymentSuccess, dst.)
+	PaymentMethod     string // Metode pembayaran dari gateway (mis. bank_transfer, gopay, credit_card)
+}
+
+// GatewayStatus adalah status transaksi hasil pengecekan langsung ke gateway
+type GatewayStatus struct {
+	OrderID       string
+	TransactionID string
+	Status        string // Status internal
+	GrossAmount   int64
+	PaymentMethod string
+}
+
+// PaymentGateway membungkus penyedia pembayaran sehingga alur pembayaran bisa memakai
+// Midtrans di production atau gateway palsu untuk pengujian lokal
+type PaymentGateway interface {
+	// Name adalah identitas gateway yang disimpan di Payment.Gateway
+	Name() string
+	// CreateCheckout membuat transaksi dan halaman pembayaran
+	CreateCheckout(req CheckoutRequest) (*CheckoutSession, error)
+	// ParseNotification mem-parsing notifikasi mentah dan memverifikasi signature-nya
+	ParseNotification(body []byte) (*GatewayNotification, error)
+	// CheckStatus menanyakan status transaksi langsung ke gateway
+	CheckStatus(orderID string) (*GatewayStatus, error)
+	// Refund mengembalikan dana (sebagian atau seluruhnya) sebuah transaksi.
+	// refundKey harus sama untuk percobaan ulang agar gateway tidak mengembalikan dana dua kali.
+	Refund(orderID, refundKey string, amount int64, reason string) error
+}
+
+var (
+	gatewayOnce sync.Once
+	gateway     PaymentGateway
+)
+
+// Gateway mengembalikan payment gateway aktif sesuai PAYMENT_GATEWAY (midtrans atau fake)
+func Gateway() PaymentGateway {
+	gatewayOnce.Do(func() {
+		switch os.Getenv("PAYMENT_GATEWAY") {
+		case "fake":
+			log.Println("[Payment] memakai fake payment gateway, jangan dipakai di production")
+			gateway = NewFakeGateway()
+		default:
+			gateway = NewMidtransGateway(os.Getenv("MIDTRANS_SERVER_KEY"), os.Getenv("MIDTRANS_IS_PRODUCTION") == "true")
+		}
+	})
+	return gateway
+}
+
+// SetGateway mengganti payment gateway aktif (untuk pengujian)
+func SetGateway(g PaymentGateway) {
+	gatewayOnce.Do(func() {})
+	gateway = g
+}
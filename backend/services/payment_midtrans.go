@@ -0,0 +1,122 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/midtrans/midtrans-go"
+	"github.com/midtrans/midtrans-go/coreapi"
+	"github.com/midtrans/midtrans-go/snap"
+)
+
+// midtransGateway adalah implementasi PaymentGateway untuk Midtrans Snap dan Core API
+type midtransGateway struct {
+	serverKey string
+	snap      snap.Client
+	core      coreapi.Client
+}
+
+// NewMidtransGateway membuat gateway Midtrans untuk sandbox atau production
+func NewMidtransGateway(serverKey string, production bool) PaymentGateway {
+	env := midtrans.Sandbox
+	if production {
+		env = midtrans.Production
+	}
+
+	g := &midtransGateway{serverKey: serverKey}
+	g.snap.New(serverKey, env)
+	g.core.New(serverKey, env)
+	return g
+}
+
+func (g *midtransGateway) Name() string { return "midtrans" }
+
+func (g *midtransGateway) CreateCheckout(req CheckoutRequest) (*CheckoutSession, error) {
+	snapReq := &snap.Request{
+		TransactionDetails: midtrans.TransactionDetails{
+			OrderID:  req.OrderID,
+			GrossAmt: req.Amount,
+		},
+		Items: &[]midtrans.ItemDetails{{
+			ID:    req.ItemID,
+			Name:  req.ItemName,
+			Price: req.Amount,
+			Qty:   1,
+		}},
+		CreditCard: &snap.CreditCardDetails{
+			Secure: true,
+		},
+		CustomerDetail: &midtrans.CustomerDetails{
+			FName: req.CustomerName,
+			Email: req.CustomerEmail,
+			Phone: req.CustomerPhone,
+		},
+		EnabledPayments: snap.AllSnapPaymentType,
+	}
+
+	resp, err := g.snap.CreateTransaction(snapReq)
+	if err != nil {
+		return nil, fmt.Errorf("Midtrans Error: %s", err.Message)
+	}
+	return &CheckoutSession{Token: resp.Token, RedirectURL: resp.RedirectURL}, nil
+}
+
+func (g *midtransGateway) ParseNotification(body []byte) (*GatewayNotification, error) {
+	var payload struct {
+		OrderID           string `json:"order_id"`
+		StatusCode        string `json:"status_code"`
+		GrossAmount       string `json:"gross_amount"`
+		SignatureKey      string `json:"signature_key"`
+		TransactionID     string `json:"transaction_id"`
+		TransactionStatus string `json:"transaction_status"`
+		FraudStatus       string `json:"fraud_status"`
+		PaymentType       string `json:"payment_type"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil || payload.OrderID == "" {
+		return nil, ErrInvalidNotification
+	}
+
+	notification := &GatewayNotification{
+		OrderID:           payload.OrderID,
+		TransactionID:     payload.TransactionID,
+		TransactionStatus: payload.TransactionStatus,
+		StatusCode:        payload.StatusCode,
+		GrossAmount:       payload.GrossAmount,
+		Status:            MidtransPaymentStatus(payload.TransactionStatus, payload.FraudStatus),
+		PaymentMethod:     payload.PaymentType,
+	}
+	if !VerifyMidtransSignature(payload.OrderID, payload.StatusCode, payload.GrossAmount, g.serverKey, payload.SignatureKey) {
+		return notification, ErrInvalidSignature
+	}
+	return notification, nil
+}
+
+func (g *midtransGateway) CheckStatus(orderID string) (*GatewayStatus, error) {
+	resp, err := g.core.CheckTransaction(orderID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrGatewayUnavailable, err.Message)
+	}
+	amount, parseErr := ParseGrossAmount(resp.GrossAmount)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	return &GatewayStatus{
+		OrderID:       resp.OrderID,
+		TransactionID: resp.TransactionID,
+		Status:        MidtransPaymentStatus(resp.TransactionStatus, resp.FraudStatus),
+		GrossAmount:   amount,
+		PaymentMethod: resp.PaymentType,
+	}, nil
+}
+
+func (g *midtransGateway) Refund(orderID, refundKey string, amount int64, reason string) error {
+	_, err := g.core.RefundTransaction(orderID, &coreapi.RefundReq{
+		RefundKey: refundKey,
+		Amount:    amount,
+		Reason:    reason,
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrGatewayUnavailable, err.Message)
+	}
+	return nil
+}
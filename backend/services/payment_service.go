@@ -0,0 +1,251 @@
+package services
+
+import (
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/imam/backend-blog-kuis/config"
+	"github.com/imam/backend-blog-kuis/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Status pembayaran internal
+const (
+	PaymentPending   = "pending"
+	PaymentSuccess   = "success"
+	PaymentChallenge = "challenge"
+	PaymentDeny      = "deny"
+	PaymentFailure   = "failure"
+	PaymentRefund    = "refund"
+
+	PaymentPartialRefund = "partial_refund"
+)
+
+var (
+	ErrPaymentNotFound       = errors.New("order tidak ditemukan")
+	ErrPaymentAmountMismatch = errors.New("nominal pembayaran tidak sesuai")
+)
+
+// MidtransPaymentStatus memetakan transaction_status dan fraud_status Midtrans ke status internal.
+// String kosong berarti status tidak dikenal dan tidak mengubah pembayaran.
+func MidtransPaymentStatus(transactionStatus, fraudStatus string) string {
+	switch transactionStatus {
+	case "capture":
+		switch fraudStatus {
+		case "challenge":
+			return PaymentChallenge
+		case "accept", "":
+			return PaymentSuccess
+		}
+		return PaymentDeny
+	case "settlement":
+		return PaymentSuccess
+	case "deny":
+		return PaymentDeny
+	case "cancel", "expire", "failure":
+		return PaymentFailure
+	case "pending":
+		return PaymentPending
+	case "refund", "chargeback":
+		return PaymentRefund
+	case "partial_refund", "partial_chargeback":
+		return PaymentPartialRefund
+	}
+	return ""
+}
+
+// VerifyMidtransSignature memeriksa signature_key = SHA512(order_id + status_code + gross_amount + server key)
+func VerifyMidtransSignature(orderID, statusCode, grossAmount, serverKey, signature string) bool {
+	if serverKey == "" || signature == "" {
+		return false
+	}
+	expected := midtransSignature(orderID, statusCode, grossAmount, serverKey)
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(strings.ToLower(signature))) == 1
+}
+
+// midtransSignature menghitung signature_key notifikasi dalam bentuk hex
+func midtransSignature(orderID, statusCode, grossAmount, serverKey string) string {
+	sum := sha512.Sum512([]byte(orderID + statusCode + grossAmount + serverKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// ParseGrossAmount mengubah gross_amount Midtrans ("150000.00") menjadi rupiah bulat
+func ParseGrossAmount(grossAmount string) (int64, error) {
+	value, err := strconv.ParseFloat(strings.TrimSpace(grossAmount), 64)
+	if err != nil {
+		return 0, fmt.Errorf("gross_amount tidak valid: %q", grossAmount)
+	}
+	return int64(math.Round(value)), nil
+}
+
+// paymentTransitionAllowed mencegah notifikasi yang terlambat/diulang menurunkan status final.
+// Pembayaran sukses hanya bisa berubah menjadi (partial) refund; failure, deny, dan refund bersifat final.
+func paymentTransitionAllowed(from, to string) bool {
+	switch from {
+	case PaymentSuccess:
+		return to == PaymentRefund || to == PaymentPartialRefund
+	case PaymentPartialRefund:
+		return to == PaymentRefund
+	case PaymentFailure, PaymentDeny, PaymentRefund:
+		return false
+	}
+	return true
+}
+
+// ApplyPaymentStatus menerapkan status dari payment gateway ke pembayaran secara idempoten.
+// Baris pembayaran dikunci selama transaksi sehingga notifikasi ganda yang datang bersamaan
+// hanya diproses sekali. changed = false berarti notifikasi duplikat atau tidak mengubah apa pun.
+func ApplyPaymentStatus(orderID, status string, grossAmount int64) (*models.Payment, bool, error) {
+	var payment models.Payment
+	changed := false
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("order_id = ?", orderID).First(&payment).Error; err != nil {
+			return ErrPaymentNotFound
+		}
+		if grossAmount != payment.Amount {
+			return ErrPaymentAmountMismatch
+		}
+		if status == "" || status == payment.Status || !paymentTransitionAllowed(payment.Status, status) {
+			return nil
+		}
+
+		updates := map[string]interface{}{"status": status}
+		if status == PaymentSuccess && payment.PaidAt == nil {
+			now := time.Now()
+			updates["paid_at"] = now
+			payment.PaidAt = &now
+		}
+		// Refund penuh yang dilakukan langsung di dashboard gateway
+		if status == PaymentRefund && payment.RefundedAmount < payment.Amount {
+			updates["refunded_amount"] = payment.Amount
+			payment.RefundedAmount = payment.Amount
+		}
+		if err := tx.Model(&payment).Updates(updates).Error; err != nil {
+			return err
+		}
+		payment.Status = status
+		changed = true
+
+		switch status {
+		case PaymentSuccess:
+			if err := settleCheckoutLedger(tx, payment); err != nil {
+				return err
+			}
+			if err := issueInvoice(tx, payment); err != nil {
+				return err
+			}
+			return activateSubscription(tx, payment)
+		case PaymentFailure, PaymentDeny:
+			return releaseCheckoutLedger(tx, payment)
+		case PaymentRefund:
+			if err := reverseCheckoutLedger(tx, payment); err != nil {
+				return err
+			}
+			return cancelPaymentSubscription(tx, payment)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	if changed {
+		log.Printf("[Payment] %s -> %s", payment.OrderID, payment.Status)
+		if payment.Status == PaymentSuccess {
+			sendPaymentInvoice(payment.ID)
+		}
+	}
+	return &payment, changed, nil
+}
+
+// paymentStatusRecheck menentukan apakah status dicek ulang ke gateway (MIDTRANS_STATUS_RECHECK, bawaan aktif)
+func paymentStatusRecheck() bool {
+	return os.Getenv("MIDTRANS_STATUS_RECHECK") != "false"
+}
+
+// ProcessNotification memproses webhook payment gateway. Setiap notifikasi mentah disimpan;
+// status hanya diterapkan jika signature valid, status terkonfirmasi lewat gateway,
+// dan nominal sesuai dengan pembayaran. changed = false berarti notifikasi duplikat.
+func ProcessNotification(body []byte, remoteIP string) (bool, error) {
+	entry := models.PaymentNotification{RawBody: string(body), RemoteIP: remoteIP}
+	changed, err := processNotification(body, &entry)
+
+	switch {
+	case err == nil && changed:
+		entry.Result = "processed"
+	case err == nil:
+		entry.Result = "duplicate"
+	case errors.Is(err, ErrGatewayUnavailable):
+		entry.Result = "error"
+	default:
+		entry.Result = "rejected"
+	}
+	if err != nil {
+		entry.Message = err.Error()
+	}
+	if logErr := config.DB.Create(&entry).Error; logErr != nil {
+		log.Printf("[Payment] gagal menyimpan log notifikasi: %v", logErr)
+	}
+	return changed, err
+}
+
+func processNotification(body []byte, entry *models.PaymentNotification) (bool, error) {
+	gw := Gateway()
+	notification, err := gw.ParseNotification(body)
+	if notification != nil {
+		entry.OrderID = notification.OrderID
+		entry.TransactionID = notification.TransactionID
+		entry.TransactionStatus = notification.TransactionStatus
+		entry.StatusCode = notification.StatusCode
+		entry.GrossAmount = notification.GrossAmount
+	}
+	if err != nil {
+		return false, err
+	}
+	entry.SignatureValid = true
+
+	status, method := notification.Status, notification.PaymentMethod
+	amount, err := ParseGrossAmount(notification.GrossAmount)
+	if err != nil {
+		return false, err
+	}
+
+	// Status dari gateway lebih dipercaya daripada isi notifikasi
+	if paymentStatusRecheck() {
+		current, err := gw.CheckStatus(notification.OrderID)
+		if err != nil {
+			return false, err
+		}
+		status, amount = current.Status, current.GrossAmount
+		if current.PaymentMethod != "" {
+			method = current.PaymentMethod
+		}
+	}
+
+	_, changed, err := ApplyPaymentStatus(notification.OrderID, status, amount)
+	if err == nil && method != "" {
+		recordPaymentMethod(notification.OrderID, method)
+	}
+	return changed, err
+}
+
+// recordPaymentMethod menyimpan metode pembayaran dari gateway untuk analitik pendapatan
+func recordPaymentMethod(orderID, method string) {
+	if err := config.DB.Model(&models.Payment{}).
+		Where("order_id = ? AND COALESCE(payment_method, '') <> ?", orderID, method).
+		Update("payment_method", method).Error; err != nil {
+		log.Printf("[Payment] gagal menyimpan metode pembayaran %s: %v", orderID, err)
+	}
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVerifyMidtransSignature(t *testing.T) {
+	// SHA512("ORDER-101" + "200" + "1000.00" + "SB-Mid-server-key")
+	const valid = "200a9229045e4275ab17de9e4e9445e5dc8b12f6366bf8bc3658fccf38adea26cf35ae5c2b220c8b22657fa0221a499dfd3f891f7670ce20bc84bb5715f8359b"
+
+	tests := []struct {
+		name        string
+		orderID     string
+		statusCode  string
+		grossAmount string
+		serverKey   string
+		signature   string
+		want        bool
+	}{
+		{"signature valid", "ORDER-101", "200", "1000.00", "SB-Mid-server-key", valid, true},
+		{"signature huruf besar", "ORDER-101", "200", "1000.00", "SB-Mid-server-key", strings.ToUpper(valid), true},
+		{"nominal diubah", "ORDER-101", "200", "10.00", "SB-Mid-server-key", valid, false},
+		{"status code diubah", "ORDER-101", "201", "1000.00", "SB-Mid-server-key", valid, false},
+		{"order lain", "ORDER-102", "200", "1000.00", "SB-Mid-server-key", valid, false},
+		{"server key lain", "ORDER-101", "200", "1000.00", "other-key", valid, false},
+		{"signature kosong", "ORDER-101", "200", "1000.00", "SB-Mid-server-key", "", false},
+		{"server key kosong", "ORDER-101", "200", "1000.00", "", midtransSignature("ORDER-101", "200", "1000.00", ""), false},
+		{"signature terpotong", "ORDER-101", "200", "1000.00", "SB-Mid-server-key", valid[:64], false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyMidtransSignature(tt.orderID, tt.statusCode, tt.grossAmount, tt.serverKey, tt.signature); got != tt.want {
+				t.Errorf("VerifyMidtransSignature() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMidtransPaymentStatus(t *testing.T) {
+	tests := []struct {
+		transactionStatus string
+		fraudStatus       string
+		want              string
+	}{
+		{"capture", "accept", PaymentSuccess},
+		{"capture", "", PaymentSuccess},
+		{"capture", "challenge", PaymentChallenge},
+		{"capture", "deny", PaymentDeny},
+		{"settlement", "", PaymentSuccess},
+		{"pending", "", PaymentPending},
+		{"deny", "", PaymentDeny},
+		{"cancel", "", PaymentFailure},
+		{"expire", "", PaymentFailure},
+		{"failure", "", PaymentFailure},
+		{"refund", "", PaymentRefund},
+		{"chargeback", "", PaymentRefund},
+		{"partial_refund", "", PaymentPartialRefund},
+		{"partial_chargeback", "", PaymentPartialRefund},
+		{"authorize", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.transactionStatus+"/"+tt.fraudStatus, func(t *testing.T) {
+			if got := MidtransPaymentStatus(tt.transactionStatus, tt.fraudStatus); got != tt.want {
+				t.Errorf("MidtransPaymentStatus() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseGrossAmount(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"150000.00", 150000, false},
+		{" 99000 ", 99000, false},
+		{"1000.50", 1001, false},
+		{"", 0, true},
+		{"abc", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseGrossAmount(tt.in)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("ParseGrossAmount(%q) = %d, %v; want %d, err %v", tt.in, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPaymentTransitionAllowed(t *testing.T) {
+	tests := []struct {
+		from string
+		to   string
+		want bool
+	}{
+		{PaymentPending, PaymentSuccess, true},
+		{PaymentPending, PaymentChallenge, true},
+		{PaymentPending, PaymentFailure, true},
+		{PaymentPending, PaymentDeny, true},
+		{PaymentChallenge, PaymentSuccess, true},
+		{PaymentChallenge, PaymentDeny, true},
+		{PaymentSuccess, PaymentRefund, true},
+		{PaymentSuccess, PaymentPartialRefund, true},
+		{PaymentSuccess, PaymentPending, false},
+		{PaymentSuccess, PaymentFailure, false},
+		{PaymentSuccess, PaymentDeny, false},
+		{PaymentPartialRefund, PaymentRefund, true},
+		{PaymentPartialRefund, PaymentSuccess, false},
+		{PaymentPartialRefund, PaymentPending, false},
+		{PaymentFailure, PaymentSuccess, false},
+		{PaymentFailure, PaymentPending, false},
+		{PaymentDeny, PaymentSuccess, false},
+		{PaymentRefund, PaymentSuccess, false},
+		{PaymentRefund, PaymentPartialRefund, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from+"->"+tt.to, func(t *testing.T) {
+			if got := paymentTransitionAllowed(tt.from, tt.to); got != tt.want {
+				t.Errorf("paymentTransitionAllowed(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
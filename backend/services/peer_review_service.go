@@ -0,0 +1,138 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/imam/backend-blog-kuis/config"
+	"github.com/imam/backend-blog-kuis/models"
+)
+
+// ValidatePeerReviewConfig memvalidasi konfigurasi peer review dan memberi ID pada kriteria rubrik
+func ValidatePeerReviewConfig(cfg *models.PeerReviewConfig) error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Reviewers < 1 {
+		return errors.New("jumlah reviewer minimal 1")
+	}
+	if cfg.PassingScore < 0 || cfg.PassingScore > 100 {
+		return errors.New("passingScore harus antara 0 dan 100")
+	}
+	if len(cfg.Rubric) == 0 {
+		return errors.New("rubrik minimal memiliki 1 kriteria")
+	}
+
+	ids := map[string]bool{}
+	for i := range cfg.Rubric {
+		criterion := &cfg.Rubric[i]
+		if strings.TrimSpace(criterion.Title) == "" {
+			return fmt.Errorf("kriteria #%d: judul wajib diisi", i+1)
+		}
+		if criterion.MaxScore <= 0 {
+			return fmt.Errorf("kriteria #%d: skor maksimum harus lebih dari 0", i+1)
+		}
+		if criterion.ID == "" {
+			criterion.ID = fmt.Sprintf("c%d", i+1)
+		}
+		if ids[criterion.ID] {
+			return fmt.Errorf("ID kriteria %q duplikat", criterion.ID)
+		}
+		ids[criterion.ID] = true
+	}
+	return nil
+}
+
+// AssignPeerReviews menugaskan submission peserta lain pada kuis yang sama kepada reviewer
+// sampai kuota Reviewers terpenuhi. Submission dengan review paling sedikit didahulukan.
+func AssignPeerReviews(reviewerID uint, quiz models.Quiz) {
+	cfg := quiz.PeerReview
+	if cfg == nil {
+		return
+	}
+
+	var assigned int64
+	config.DB.Model(&models.PeerReview{}).Where("reviewer_id = ? AND quiz_id = ?", reviewerID, quiz.ID).Count(&assigned)
+	need := cfg.Reviewers - int(assigned)
+	if need <= 0 {
+		return
+	}
+
+	var candidates []models.UserProgress
+	config.DB.Where("quiz_id = ? AND user_id != ?", quiz.ID, reviewerID).
+		Where("(submission_file_url != '' OR submission_drive_link != '')").
+		Where("approval_overridden = ? AND approval_status = ?", false, "pending").
+		Where("id NOT IN (?)", config.DB.Model(&models.PeerReview{}).Select("progress_id").Where("reviewer_id = ?", reviewerID)).
+		Where("(SELECT COUNT(*) FROM peer_reviews WHERE peer_reviews.progress_id = user_progresses.id) < ?", cfg.Reviewers).
+		Order("(SELECT COUNT(*) FROM peer_reviews WHERE peer_reviews.progress_id = user_progresses.id) ASC, created_at ASC").
+		Limit(need).
+		Find(&candidates)
+
+	for _, candidate := range candidates {
+		config.DB.Create(&models.PeerReview{
+			ProgressID: candidate.ID,
+			ReviewerID: reviewerID,
+			QuizID:     quiz.ID,
+			Status:     "assigned",
+		})
+	}
+}
+
+// ResetPeerReviews menghapus review untuk versi submission sebelumnya saat peserta mengirim ulang
+func ResetPeerReviews(progressID uint) {
+	config.DB.Where("progress_id = ?", progressID).Delete(&models.PeerReview{})
+	config.DB.Model(&models.UserProgress{}).Where("id = ?", progressID).Updates(map[string]interface{}{
+		"peer_review_score": 0,
+		"peer_review_count": 0,
+	})
+}
+
+// AggregatePeerReviews menghitung rata-rata rubrik sebuah submission. Setelah jumlah review
+// yang selesai mencapai kuota, status approval ditentukan dari PassingScore kecuali admin sudah memutuskan.
+func AggregatePeerReviews(progressID uint) {
+	var progress models.UserProgress
+	if err := config.DB.Preload("Quiz").First(&progress, progressID).Error; err != nil {
+		return
+	}
+
+	var reviews []models.PeerReview
+	config.DB.Where("progress_id = ? AND status = ?", progressID, "completed").Find(&reviews)
+
+	totalPercent := 0
+	for _, r := range reviews {
+		if r.MaxScore > 0 {
+			totalPercent += r.TotalScore * 100 / r.MaxScore
+		}
+	}
+	average := 0
+	if len(reviews) > 0 {
+		average = totalPercent / len(reviews)
+	}
+
+	updates := map[string]interface{}{
+		"peer_review_score": average,
+		"peer_review_count": len(reviews),
+	}
+
+	cfg := progress.Quiz.PeerReview
+	if cfg != nil && len(reviews) >= cfg.Reviewers && !progress.ApprovalOverridden && progress.ApprovalStatus != "approved" {
+		if average >= cfg.PassingScore {
+			updates["approval_status"] = "approved"
+			updates["admin_note"] = fmt.Sprintf("Disetujui melalui peer review (rata-rata %d%%)", average)
+		} else {
+			updates["approval_status"] = "rejected"
+			updates["admin_note"] = fmt.Sprintf("Ditolak melalui peer review (rata-rata %d%%, minimal %d%%)", average, cfg.PassingScore)
+		}
+	}
+
+	config.DB.Model(&progress).Updates(updates)
+
+	if status, decided := updates["approval_status"].(string); decided {
+		ReviewLatestSubmission(progress.ID, status, nil, updates["admin_note"].(string))
+	}
+
+	if updates["approval_status"] == "approved" {
+		CheckPathCompletion(progress.UserID, progress.Quiz.PathID)
+	}
+}
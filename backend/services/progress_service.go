@@ -0,0 +1,82 @@
+package services
+
+import (
+	"log"
+
+	"github.com/imam/backend-blog-kuis/config"
+	"gorm.io/gorm"
+)
+
+// PathProgress adalah ringkasan progres user pada satu learning path
+type PathProgress struct {
+	PathID          uint `json:"pathId"`
+	Total           int  `json:"total"`
+	Completed       int  `json:"completed"`
+	PendingProjects int  `json:"pendingProjects"` // Project yang belum disetujui
+}
+
+// Percentage mengembalikan persentase materi yang sudah selesai (0-100)
+func (p PathProgress) Percentage() int {
+	if p.Total == 0 {
+		return 0
+	}
+	return p.Completed * 100 / p.Total
+}
+
+// IsComplete bernilai true jika semua materi selesai dan semua project sudah disetujui
+func (p PathProgress) IsComplete() bool {
+	return p.Total > 0 && p.Completed >= p.Total && p.PendingProjects == 0
+}
+
+// PathProgresses menghitung total dan jumlah materi selesai untuk banyak path sekaligus
+// dalam satu query agregat. userID 0 menghasilkan Completed = 0 untuk setiap path.
+func PathProgresses(userID uint, pathIDs []uint) map[uint]PathProgress {
+	return pathProgresses(config.DB, userID, pathIDs)
+}
+
+// pathProgresses menjalankan query PathProgresses pada koneksi/transaksi db
+func pathProgresses(db *gorm.DB, userID uint, pathIDs []uint) map[uint]PathProgress {
+	result := map[uint]PathProgress{}
+	if len(pathIDs) == 0 {
+		return result
+	}
+
+	var rows []PathProgress
+	db.Table("quizzes").
+		Select(`quizzes.path_id,
+			COUNT(quizzes.id) AS total,
+			COUNT(user_progresses.id) FILTER (WHERE user_progresses.completed) AS completed,
+			COUNT(quizzes.id) FILTER (WHERE quizzes.type = 'project' AND COALESCE(user_progresses.approval_status, '') != 'approved') AS pending_projects`).
+		Joins("LEFT JOIN user_progresses ON user_progresses.quiz_id = quizzes.id AND user_progresses.user_id = ?", userID).
+		Where("quizzes.path_id IN ?", pathIDs).
+		Group("quizzes.path_id").
+		Scan(&rows)
+
+	for _, id := range pathIDs {
+		result[id] = PathProgress{PathID: id}
+	}
+	for _, row := range rows {
+		result[row.PathID] = row
+	}
+	return result
+}
+
+// GetPathProgress menghitung progres user pada satu learning path
+func GetPathProgress(userID, pathID uint) PathProgress {
+	return PathProgresses(userID, []uint{pathID})[pathID]
+}
+
+// CheckPathCompletion memeriksa apakah user sudah menyelesaikan seluruh materi di path
+// dan menerbitkan sertifikat jika semua proyek sudah disetujui
+func CheckPathCompletion(userID, pathID uint) bool {
+	progress := GetPathProgress(userID, pathID)
+	isComplete := progress.Total > 0 && progress.Completed >= progress.Total
+
+	if progress.IsComplete() {
+		if _, _, err := IssueCertificate(userID, pathID); err != nil {
+			log.Printf("[Certificate] gagal menerbitkan sertifikat user #%d path #%d: %v", userID, pathID, err)
+		}
+	}
+
+	return isComplete
+}
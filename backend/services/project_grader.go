@@ -0,0 +1,455 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/imam/backend-blog-kuis/config"
+	"github.com/imam/backend-blog-kuis/models"
+)
+
+const (
+	defaultGradingTimeout = 60 * time.Second
+	maxGradingTimeout     = 10 * time.Minute
+	maxExtractBytes       = 200 << 20 // Batas total ukuran isi ZIP yang diekstrak
+	maxExtractFiles       = 5000
+	maxCommandOutput      = 64 << 10
+	maxManifestBytes      = 1 << 20
+)
+
+// gradingQueue berisi ID UserProgress yang menunggu dinilai oleh worker
+var gradingQueue = make(chan uint, 100)
+
+// gradingCheck adalah hasil satu pemeriksaan dalam laporan penilaian otomatis
+type gradingCheck struct {
+	Name   string
+	Passed bool
+	Detail string
+	// Skipped berarti pemeriksaan tidak bisa dijalankan (mis. sandbox belum dikonfigurasi)
+	// sehingga tidak dihitung dalam skor dan submission perlu dinilai manual
+	Skipped bool
+}
+
+// ValidateGradingSpec memeriksa aturan penilaian otomatis sebelum disimpan ke modul project
+func ValidateGradingSpec(spec *models.ProjectGradingSpec) error {
+	if spec == nil {
+		return nil
+	}
+	for _, p := range spec.FilePatterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("pola file %q tidak valid", p)
+		}
+	}
+	if spec.MaxSizeMB < 0 {
+		return errors.New("ukuran maksimum tidak boleh negatif")
+	}
+	if len(spec.ManifestEntries) > 0 && spec.ManifestFile == "" {
+		return errors.New("manifestFile wajib diisi jika manifestEntries digunakan")
+	}
+	if spec.TimeoutSeconds < 0 || time.Duration(spec.TimeoutSeconds)*time.Second > maxGradingTimeout {
+		return fmt.Errorf("timeout harus antara 0 dan %d detik", int(maxGradingTimeout.Seconds()))
+	}
+	return nil
+}
+
+// StartGradingWorker menjalankan worker penilaian ZIP di background dan mengantrekan ulang
+// submission yang belum selesai dinilai saat server terakhir berhenti
+func StartGradingWorker() {
+	go func() {
+		for id := range gradingQueue {
+			gradeSubmission(id)
+		}
+	}()
+
+	var pending []uint
+	config.DB.Model(&models.UserProgress{}).
+		Where("auto_grade_status IN ?", []string{"queued", "running"}).
+		Pluck("id", &pending)
+	for _, id := range pending {
+		go func(id uint) { gradingQueue <- id }(id)
+	}
+}
+
+// EnqueueGrading mereset laporan sebelumnya dan memasukkan submission ke antrean penilaian otomatis
+func EnqueueGrading(progressID uint) {
+	config.DB.Model(&models.UserProgress{}).Where("id = ?", progressID).Updates(map[string]interface{}{
+		"auto_grade_status": "queued",
+		"auto_grade_score":  0,
+		"auto_grade_log":    "",
+		"auto_graded_at":    nil,
+	})
+
+	// Kirim ke antrean tanpa menahan request HTTP jika antrean sedang penuh
+	go func() { gradingQueue <- progressID }()
+}
+
+// gradeSubmission menilai satu submission ZIP dan menyimpan skor serta log-nya di UserProgress
+func gradeSubmission(progressID uint) {
+	var progress models.UserProgress
+	if err := config.DB.Preload("Quiz").First(&progress, progressID).Error; err != nil {
+		return
+	}
+
+	spec := progress.Quiz.GradingSpec
+	if spec == nil || progress.SubmissionFileURL == "" {
+		config.DB.Model(&progress).Update("auto_grade_status", "")
+		return
+	}
+
+	config.DB.Model(&progress).Update("auto_grade_status", "running")
+
+	checks, err := runGradingChecks(*spec, progress.UserID, progress.SubmissionFileURL)
+
+	var logLines []string
+	passedCount, skippedCount := 0, 0
+	for _, check := range checks {
+		mark := "FAIL"
+		if check.Skipped {
+			mark = "SKIP"
+			skippedCount++
+		} else if check.Passed {
+			mark = "PASS"
+			passedCount++
+		}
+		line := fmt.Sprintf("[%s] %s", mark, check.Name)
+		if check.Detail != "" {
+			line += "\n" + check.Detail
+		}
+		logLines = append(logLines, line)
+	}
+
+	status := "failed"
+	score := 0
+	if counted := len(checks) - skippedCount; counted > 0 {
+		score = passedCount * 100 / counted
+	}
+	if err != nil {
+		status = "error"
+		logLines = append(logLines, "[ERROR] "+err.Error())
+	} else if passedCount+skippedCount == len(checks) {
+		// Pemeriksaan yang dilewati tidak boleh meluluskan otomatis; admin menilai manual
+		status = "passed"
+		score = 100
+		if skippedCount > 0 {
+			status = "manual_review"
+		}
+	}
+
+	now := time.Now()
+	config.DB.Model(&progress).Updates(map[string]interface{}{
+		"auto_grade_status": status,
+		"auto_grade_score":  score,
+		"auto_grade_log":    strings.Join(logLines, "\n"),
+		"auto_graded_at":    now,
+	})
+
+	if status == "passed" && spec.AutoApprove && progress.ApprovalStatus != "approved" {
+		note := "Disetujui otomatis: semua pemeriksaan lulus"
+		config.DB.Model(&progress).Updates(map[string]interface{}{
+			"approval_status": "approved",
+			"admin_note":      note,
+		})
+		ReviewLatestSubmission(progress.ID, "approved", nil, note)
+		CheckPathCompletion(progress.UserID, progress.Quiz.PathID)
+	}
+
+	log.Printf("[Grader] Submission #%d dinilai: %s (%d%%)", progress.ID, status, score)
+}
+
+// runGradingChecks menjalankan seluruh pemeriksaan dari spec terhadap file ZIP.
+// File dicari dari catatan upload milik peserta, bukan langsung dari URL yang dikirim.
+func runGradingChecks(spec models.ProjectGradingSpec, userID uint, fileURL string) ([]gradingCheck, error) {
+	var checks []gradingCheck
+
+	zipPath, err := ownedUploadPath(userID, fileURL)
+	if err != nil {
+		return checks, err
+	}
+
+	info, err := os.Stat(zipPath)
+	if err != nil {
+		return checks, errors.New("file submission tidak ditemukan")
+	}
+
+	if spec.MaxSizeMB > 0 {
+		limit := int64(spec.MaxSizeMB) << 20
+		checks = append(checks, gradingCheck{
+			Name:   fmt.Sprintf("Ukuran file maksimal %d MB", spec.MaxSizeMB),
+			Passed: info.Size() <= limit,
+			Detail: fmt.Sprintf("Ukuran: %.2f MB", float64(info.Size())/(1<<20)),
+		})
+	}
+
+	reader, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return checks, errors.New("file bukan arsip ZIP yang valid")
+	}
+	defer reader.Close()
+
+	root := commonRoot(reader.File)
+	files := map[string]*zip.File{}
+	for _, f := range reader.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		files[strings.TrimPrefix(f.Name, root)] = f
+	}
+
+	for _, required := range spec.RequiredFiles {
+		_, ok := files[strings.TrimPrefix(required, "/")]
+		checks = append(checks, gradingCheck{Name: "File wajib: " + required, Passed: ok})
+	}
+
+	for _, pattern := range spec.FilePatterns {
+		matched := ""
+		for name := range files {
+			if ok, _ := path.Match(pattern, name); ok {
+				matched = name
+				break
+			}
+			if ok, _ := path.Match(pattern, path.Base(name)); ok {
+				matched = name
+				break
+			}
+		}
+		check := gradingCheck{Name: "Pola file: " + pattern, Passed: matched != ""}
+		if matched != "" {
+			check.Detail = "Cocok: " + matched
+		}
+		checks = append(checks, check)
+	}
+
+	if spec.ManifestFile != "" {
+		checks = append(checks, checkManifest(files[strings.TrimPrefix(spec.ManifestFile, "/")], spec)...)
+	}
+
+	if spec.Command != "" {
+		checks = append(checks, runSandboxedCommand(reader.File, root, spec))
+	}
+
+	return checks, nil
+}
+
+// commonRoot mengembalikan folder teratas jika seluruh isi ZIP berada di dalam satu folder
+func commonRoot(files []*zip.File) string {
+	root := ""
+	for _, f := range files {
+		idx := strings.Index(f.Name, "/")
+		if idx < 0 {
+			return ""
+		}
+		if root == "" {
+			root = f.Name[:idx+1]
+		} else if f.Name[:idx+1] != root {
+			return ""
+		}
+	}
+	return root
+}
+
+// checkManifest memastikan file manifest ada dan memuat setiap entri yang diwajibkan
+func checkManifest(file *zip.File, spec models.ProjectGradingSpec) []gradingCheck {
+	if file == nil {
+		return []gradingCheck{{Name: "Manifest: " + spec.ManifestFile, Passed: false, Detail: "File manifest tidak ditemukan"}}
+	}
+
+	rc, err := file.Open()
+	if err != nil {
+		return []gradingCheck{{Name: "Manifest: " + spec.ManifestFile, Passed: false, Detail: err.Error()}}
+	}
+	content, _ := io.ReadAll(io.LimitReader(rc, maxManifestBytes))
+	rc.Close()
+
+	checks := []gradingCheck{{Name: "Manifest: " + spec.ManifestFile, Passed: true}}
+
+	var manifest map[string]interface{}
+	isJSON := json.Unmarshal(content, &manifest) == nil
+
+	for _, entry := range spec.ManifestEntries {
+		found := false
+		if isJSON {
+			found = hasJSONKey(manifest, entry)
+		} else {
+			found = bytes.Contains(content, []byte(entry))
+		}
+		checks = append(checks, gradingCheck{Name: "Entri manifest: " + entry, Passed: found})
+	}
+	return checks
+}
+
+// hasJSONKey memeriksa key bertitik (mis. "device.board") di dalam objek JSON
+func hasJSONKey(data map[string]interface{}, key string) bool {
+	current := interface{}(data)
+	for _, part := range strings.Split(key, ".") {
+		obj, ok := current.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		if current, ok = obj[part]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// runSandboxedCommand mengekstrak ZIP ke folder sementara lalu menjalankan perintah di dalam sandbox.
+// Sandbox diatur lewat env GRADER_SANDBOX (mis. "docker run --rm --network none -v {dir}:/work -w /work alpine:3.20");
+// tanpa sandbox perintah tidak dijalankan sama sekali. Untuk "docker run", container diberi nama
+// dan dihapus paksa saat timeout.
+func runSandboxedCommand(files []*zip.File, root string, spec models.ProjectGradingSpec) gradingCheck {
+	check := gradingCheck{Name: "Perintah: " + spec.Command}
+
+	sandbox := os.Getenv("GRADER_SANDBOX")
+	if sandbox == "" {
+		check.Skipped = true
+		check.Detail = "Sandbox belum dikonfigurasi (GRADER_SANDBOX), perintah tidak dijalankan; perlu penilaian manual"
+		return check
+	}
+
+	dir, err := os.MkdirTemp("", "grader-*")
+	if err != nil {
+		check.Detail = "Gagal membuat folder sementara: " + err.Error()
+		return check
+	}
+	defer os.RemoveAll(dir)
+
+	if err := extractZip(files, root, dir); err != nil {
+		check.Detail = "Gagal mengekstrak ZIP: " + err.Error()
+		return check
+	}
+
+	timeout := defaultGradingTimeout
+	if spec.TimeoutSeconds > 0 {
+		timeout = time.Duration(spec.TimeoutSeconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	args := strings.Fields(strings.ReplaceAll(sandbox, "{dir}", dir))
+	// Container docker diberi nama yang diketahui agar bisa dihentikan saat timeout;
+	// membunuh proses docker CLI saja tidak menghentikan container-nya
+	container := ""
+	if len(args) > 1 && filepath.Base(args[0]) == "docker" && args[1] == "run" {
+		container = filepath.Base(dir)
+		args = append([]string{args[0], "run", "--name", container}, args[2:]...)
+	}
+	args = append(args, "sh", "-c", spec.Command)
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = dir
+	cmd.Env = []string{"PATH=/usr/local/bin:/usr/bin:/bin", "HOME=" + dir}
+
+	var output bytes.Buffer
+	cmd.Stdout = &limitedWriter{buf: &output, limit: maxCommandOutput}
+	cmd.Stderr = cmd.Stdout
+
+	err = cmd.Run()
+	check.Detail = strings.TrimSpace(output.String())
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		check.Detail += fmt.Sprintf("\nDihentikan setelah %s (timeout)", timeout)
+		if container != "" {
+			removeSandboxContainer(args[0], container)
+		}
+	case err != nil:
+		check.Detail += "\n" + err.Error()
+	default:
+		check.Passed = true
+	}
+	return check
+}
+
+// removeSandboxContainer menghentikan dan menghapus container sandbox yang melewati batas waktu
+func removeSandboxContainer(docker, name string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if out, err := exec.CommandContext(ctx, docker, "rm", "-f", name).CombinedOutput(); err != nil {
+		log.Printf("[Grader] Gagal menghapus container %s: %v %s", name, err, strings.TrimSpace(string(out)))
+	}
+}
+
+// extractZip mengekstrak isi ZIP ke dir dengan perlindungan zip-slip dan batas ukuran
+func extractZip(files []*zip.File, root, dir string) error {
+	if len(files) > maxExtractFiles {
+		return fmt.Errorf("jumlah file melebihi batas %d", maxExtractFiles)
+	}
+
+	var total int64
+	for _, f := range files {
+		name := strings.TrimPrefix(f.Name, root)
+		if name == "" {
+			continue
+		}
+
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if !strings.HasPrefix(target, filepath.Clean(dir)+string(os.PathSeparator)) {
+			return fmt.Errorf("path tidak valid: %s", f.Name)
+		}
+
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+			continue
+		}
+		// Symlink dan file khusus lainnya tidak diekstrak
+		if !f.Mode().IsRegular() {
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+		if err != nil {
+			rc.Close()
+			return err
+		}
+		n, err := io.Copy(out, io.LimitReader(rc, maxExtractBytes-total+1))
+		rc.Close()
+		out.Close()
+		if err != nil {
+			return err
+		}
+
+		total += n
+		if total > maxExtractBytes {
+			return errors.New("isi ZIP melebihi batas ukuran ekstraksi")
+		}
+	}
+	return nil
+}
+
+// limitedWriter menulis ke buffer sampai batas tertentu lalu membuang sisanya
+type limitedWriter struct {
+	buf   *bytes.Buffer
+	limit int
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if remaining := w.limit - w.buf.Len(); remaining > 0 {
+		if len(p) > remaining {
+			w.buf.Write(p[:remaining])
+		} else {
+			w.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
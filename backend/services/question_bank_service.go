@@ -0,0 +1,108 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+
+	"github.com/imam/backend-blog-kuis/config"
+	"github.com/imam/backend-blog-kuis/models"
+	"gorm.io/gorm"
+)
+
+// ValidateDrawRules memastikan setiap aturan undian merujuk ke bank yang ada dan soalnya mencukupi
+func ValidateDrawRules(rules []models.QuizDrawRule) error {
+	for i, rule := range rules {
+		if rule.Count <= 0 {
+			return fmt.Errorf("aturan #%d: jumlah soal harus lebih dari 0", i+1)
+		}
+		if rule.Difficulty != "" && !isValidDifficulty(rule.Difficulty) {
+			return fmt.Errorf("aturan #%d: tingkat kesulitan %q tidak dikenal", i+1, rule.Difficulty)
+		}
+
+		var bankCount int64
+		config.DB.Model(&models.QuestionBank{}).Where("id = ?", rule.BankID).Count(&bankCount)
+		if bankCount == 0 {
+			return fmt.Errorf("aturan #%d: bank soal #%d tidak ditemukan", i+1, rule.BankID)
+		}
+
+		var count int64
+		drawRuleQuery(rule).Count(&count)
+		if count < int64(rule.Count) {
+			return fmt.Errorf("aturan #%d: bank soal hanya memiliki %d soal yang cocok", i+1, count)
+		}
+	}
+	return nil
+}
+
+// DrawQuestions menyusun soal untuk satu attempt: soal tetap milik kuis ditambah soal acak dari bank,
+// lalu urutan soal dan urutan opsi diacak
+func DrawQuestions(quiz models.Quiz) ([]models.AttemptQuestion, error) {
+	questions := append([]models.Question{}, quiz.Questions...)
+	picked := map[uint]bool{}
+	for _, q := range questions {
+		picked[q.ID] = true
+	}
+
+	for i, rule := range quiz.DrawRules {
+		var candidates []models.Question
+		if err := drawRuleQuery(rule).Find(&candidates).Error; err != nil {
+			return nil, err
+		}
+
+		drawn := 0
+		for _, idx := range rand.Perm(len(candidates)) {
+			if drawn == rule.Count {
+				break
+			}
+			if picked[candidates[idx].ID] {
+				continue
+			}
+			picked[candidates[idx].ID] = true
+			questions = append(questions, candidates[idx])
+			drawn++
+		}
+		if drawn < rule.Count {
+			return nil, fmt.Errorf("aturan #%d: soal di bank tidak mencukupi", i+1)
+		}
+	}
+
+	if len(questions) == 0 {
+		return nil, errors.New("kuis belum memiliki soal")
+	}
+
+	if len(quiz.DrawRules) > 0 {
+		rand.Shuffle(len(questions), func(i, j int) { questions[i], questions[j] = questions[j], questions[i] })
+	}
+
+	results := []models.AttemptQuestion{}
+	for _, q := range questions {
+		aq := models.AttemptQuestion{Question: q}
+		for _, idx := range rand.Perm(len(q.Options)) {
+			aq.OptionOrder = append(aq.OptionOrder, q.Options[idx].ID)
+		}
+		for _, idx := range rand.Perm(len(q.Matches)) {
+			aq.MatchOrder = append(aq.MatchOrder, q.Matches[idx].ID)
+		}
+		results = append(results, aq)
+	}
+	return results, nil
+}
+
+// drawRuleQuery membangun query soal yang memenuhi satu aturan undian
+func drawRuleQuery(rule models.QuizDrawRule) *gorm.DB {
+	db := config.DB.Model(&models.Question{}).Where("bank_id = ?", rule.BankID)
+	if rule.Difficulty != "" {
+		db = db.Where("difficulty = ?", rule.Difficulty)
+	}
+	return db
+}
+
+func isValidDifficulty(difficulty string) bool {
+	for _, d := range models.QuestionDifficulties {
+		if d == difficulty {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,235 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/imam/backend-blog-kuis/models"
+)
+
+// ValidateQuestion menormalkan dan memvalidasi soal sebelum disimpan.
+// Option tanpa ID akan diberi ID baru ("opt1", "opt2", ...) yang tidak berubah setelah tersimpan.
+func ValidateQuestion(q *models.Question) error {
+	if q.Kind == "" {
+		q.Kind = models.QuestionKindSingleChoice
+	}
+	if strings.TrimSpace(q.QuestionText) == "" {
+		return errors.New("teks soal wajib diisi")
+	}
+	if q.Points <= 0 {
+		q.Points = 1
+	}
+	if q.Difficulty == "" {
+		q.Difficulty = "Sedang"
+	}
+	if !isValidDifficulty(q.Difficulty) {
+		return fmt.Errorf("tingkat kesulitan %q tidak dikenal", q.Difficulty)
+	}
+
+	if q.Kind == models.QuestionKindTrueFalse && len(q.Options) == 0 {
+		q.Options = []models.QuestionOption{
+			{ID: "true", Text: "Benar"},
+			{ID: "false", Text: "Salah"},
+		}
+	}
+
+	optionIDs, err := assignOptionIDs(q.Options)
+	if err != nil {
+		return err
+	}
+	matchIDs, err := assignOptionIDs(q.Matches)
+	if err != nil {
+		return err
+	}
+
+	answer := q.Answer
+	switch q.Kind {
+	case models.QuestionKindSingleChoice, models.QuestionKindTrueFalse:
+		if len(q.Options) < 2 {
+			return errors.New("soal pilihan minimal memiliki 2 opsi")
+		}
+		if len(answer.OptionIDs) != 1 || !optionIDs[answer.OptionIDs[0]] {
+			return errors.New("soal pilihan tunggal harus memiliki tepat 1 jawaban benar yang valid")
+		}
+	case models.QuestionKindMultipleChoice:
+		if len(q.Options) < 2 {
+			return errors.New("soal pilihan minimal memiliki 2 opsi")
+		}
+		if len(answer.OptionIDs) == 0 {
+			return errors.New("soal pilihan ganda harus memiliki minimal 1 jawaban benar")
+		}
+		seen := map[string]bool{}
+		for _, id := range answer.OptionIDs {
+			if !optionIDs[id] || seen[id] {
+				return fmt.Errorf("jawaban %q tidak valid", id)
+			}
+			seen[id] = true
+		}
+	case models.QuestionKindShortText:
+		if len(answer.Patterns) == 0 {
+			return errors.New("soal isian harus memiliki minimal 1 pola jawaban")
+		}
+		for _, p := range answer.Patterns {
+			if _, err := compileAnswerPattern(p); err != nil {
+				return fmt.Errorf("pola jawaban %q tidak valid: %v", p, err)
+			}
+		}
+	case models.QuestionKindNumeric:
+		if answer.Number == nil {
+			return errors.New("soal numerik harus memiliki nilai jawaban")
+		}
+		if answer.Tolerance < 0 {
+			return errors.New("toleransi tidak boleh negatif")
+		}
+	case models.QuestionKindOrdering:
+		if len(q.Options) < 2 {
+			return errors.New("soal urutan minimal memiliki 2 item")
+		}
+		// Jika urutan benar tidak dikirim, gunakan urutan opsi saat dibuat
+		if len(answer.OptionIDs) == 0 {
+			for _, o := range q.Options {
+				q.Answer.OptionIDs = append(q.Answer.OptionIDs, o.ID)
+			}
+			break
+		}
+		if len(answer.OptionIDs) != len(q.Options) {
+			return errors.New("urutan jawaban harus memuat semua item")
+		}
+		seen := map[string]bool{}
+		for _, id := range answer.OptionIDs {
+			if !optionIDs[id] || seen[id] {
+				return fmt.Errorf("item urutan %q tidak valid", id)
+			}
+			seen[id] = true
+		}
+	case models.QuestionKindMatching:
+		if len(q.Options) < 2 || len(q.Matches) < 2 {
+			return errors.New("soal menjodohkan minimal memiliki 2 pasangan")
+		}
+		if len(answer.Pairs) != len(q.Options) {
+			return errors.New("setiap opsi harus memiliki pasangan jawaban")
+		}
+		for optionID, matchID := range answer.Pairs {
+			if !optionIDs[optionID] || !matchIDs[matchID] {
+				return fmt.Errorf("pasangan %q -> %q tidak valid", optionID, matchID)
+			}
+		}
+	default:
+		return fmt.Errorf("jenis soal %q tidak dikenal", q.Kind)
+	}
+
+	return nil
+}
+
+// assignOptionIDs memberi ID pada opsi yang belum punya dan memastikan ID unik
+func assignOptionIDs(options []models.QuestionOption) (map[string]bool, error) {
+	ids := map[string]bool{}
+	for _, o := range options {
+		if o.ID == "" {
+			continue
+		}
+		if ids[o.ID] {
+			return nil, fmt.Errorf("ID opsi %q duplikat", o.ID)
+		}
+		ids[o.ID] = true
+	}
+
+	next := 1
+	for i := range options {
+		if strings.TrimSpace(options[i].Text) == "" {
+			return nil, errors.New("teks opsi wajib diisi")
+		}
+		if options[i].ID != "" {
+			continue
+		}
+		for ids[fmt.Sprintf("opt%d", next)] {
+			next++
+		}
+		options[i].ID = fmt.Sprintf("opt%d", next)
+		ids[options[i].ID] = true
+	}
+
+	return ids, nil
+}
+
+// compileAnswerPattern mengompilasi pola jawaban isian menjadi regex yang mencocokkan seluruh teks
+func compileAnswerPattern(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile(`(?i)^(?:` + strings.TrimSpace(pattern) + `)$`)
+}
+
+// GradeQuestion menilai satu jawaban peserta dan mengembalikan hasilnya
+func GradeQuestion(q models.Question, submission models.QuestionSubmission) models.QuizQuestionResult {
+	submission.QuestionID = q.ID
+	submission.Answer = strings.TrimSpace(submission.Answer)
+
+	correct := false
+	switch q.Kind {
+	case models.QuestionKindSingleChoice, models.QuestionKindTrueFalse, "":
+		selected := submission.Answer
+		if selected == "" && len(submission.OptionIDs) == 1 {
+			selected = submission.OptionIDs[0]
+		}
+		correct = len(q.Answer.OptionIDs) == 1 && selected == q.Answer.OptionIDs[0]
+	case models.QuestionKindMultipleChoice:
+		correct = sameSet(submission.OptionIDs, q.Answer.OptionIDs)
+	case models.QuestionKindShortText:
+		for _, p := range q.Answer.Patterns {
+			re, err := compileAnswerPattern(p)
+			if err == nil && submission.Answer != "" && re.MatchString(submission.Answer) {
+				correct = true
+				break
+			}
+		}
+	case models.QuestionKindNumeric:
+		value, err := strconv.ParseFloat(strings.ReplaceAll(submission.Answer, ",", "."), 64)
+		correct = err == nil && q.Answer.Number != nil && math.Abs(value-*q.Answer.Number) <= q.Answer.Tolerance
+	case models.QuestionKindOrdering:
+		correct = len(submission.OptionIDs) == len(q.Answer.OptionIDs)
+		for i := 0; correct && i < len(q.Answer.OptionIDs); i++ {
+			correct = submission.OptionIDs[i] == q.Answer.OptionIDs[i]
+		}
+	case models.QuestionKindMatching:
+		correct = len(q.Answer.Pairs) > 0 && len(submission.Pairs) == len(q.Answer.Pairs)
+		for optionID, matchID := range q.Answer.Pairs {
+			if submission.Pairs[optionID] != matchID {
+				correct = false
+				break
+			}
+		}
+	}
+
+	result := models.QuizQuestionResult{
+		QuestionSubmission: submission,
+		Correct:            correct,
+		Points:             q.Points,
+		Explanation:        q.Explanation,
+	}
+	if correct {
+		result.EarnedPoints = q.Points
+	}
+	return result
+}
+
+// sameSet membandingkan dua daftar ID tanpa memperhatikan urutan
+func sameSet(a, b []string) bool {
+	if len(a) != len(b) || len(a) == 0 {
+		return false
+	}
+	seen := map[string]bool{}
+	for _, id := range a {
+		seen[id] = true
+	}
+	if len(seen) != len(a) {
+		return false
+	}
+	for _, id := range b {
+		if !seen[id] {
+			return false
+		}
+	}
+	return true
+}
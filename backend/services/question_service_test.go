@@ -0,0 +1,160 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/imam/backend-blog-kuis/models"
+)
+
+func TestGradeQuestion(t *testing.T) {
+	number := 9.81
+
+	tests := []struct {
+		name       string
+		question   models.Question
+		submission models.QuestionSubmission
+		want       bool
+	}{
+		{
+			name:       "single choice benar",
+			question:   models.Question{Kind: models.QuestionKindSingleChoice, Answer: models.QuestionAnswer{OptionIDs: []string{"opt2"}}},
+			submission: models.QuestionSubmission{Answer: "opt2"},
+			want:       true,
+		},
+		{
+			name:       "single choice lewat optionIds",
+			question:   models.Question{Kind: models.QuestionKindSingleChoice, Answer: models.QuestionAnswer{OptionIDs: []string{"opt2"}}},
+			submission: models.QuestionSubmission{OptionIDs: []string{"opt2"}},
+			want:       true,
+		},
+		{
+			name:       "single choice salah",
+			question:   models.Question{Kind: models.QuestionKindSingleChoice, Answer: models.QuestionAnswer{OptionIDs: []string{"opt2"}}},
+			submission: models.QuestionSubmission{Answer: "opt1"},
+			want:       false,
+		},
+		{
+			name:       "single choice tanpa kunci tidak pernah benar",
+			question:   models.Question{Kind: models.QuestionKindSingleChoice},
+			submission: models.QuestionSubmission{Answer: ""},
+			want:       false,
+		},
+		{
+			name:       "kind kosong dinilai seperti single choice",
+			question:   models.Question{Answer: models.QuestionAnswer{OptionIDs: []string{"opt1"}}},
+			submission: models.QuestionSubmission{Answer: " opt1 "},
+			want:       true,
+		},
+		{
+			name:       "true false benar",
+			question:   models.Question{Kind: models.QuestionKindTrueFalse, Answer: models.QuestionAnswer{OptionIDs: []string{"true"}}},
+			submission: models.QuestionSubmission{Answer: "true"},
+			want:       true,
+		},
+		{
+			name:       "multiple choice urutan bebas",
+			question:   models.Question{Kind: models.QuestionKindMultipleChoice, Answer: models.QuestionAnswer{OptionIDs: []string{"opt1", "opt3"}}},
+			submission: models.QuestionSubmission{OptionIDs: []string{"opt3", "opt1"}},
+			want:       true,
+		},
+		{
+			name:       "multiple choice kurang satu",
+			question:   models.Question{Kind: models.QuestionKindMultipleChoice, Answer: models.QuestionAnswer{OptionIDs: []string{"opt1", "opt3"}}},
+			submission: models.QuestionSubmission{OptionIDs: []string{"opt1"}},
+			want:       false,
+		},
+		{
+			name:       "multiple choice duplikat tidak dihitung dua kali",
+			question:   models.Question{Kind: models.QuestionKindMultipleChoice, Answer: models.QuestionAnswer{OptionIDs: []string{"opt1", "opt3"}}},
+			submission: models.QuestionSubmission{OptionIDs: []string{"opt1", "opt1"}},
+			want:       false,
+		},
+		{
+			name:       "short text cocok pola tanpa huruf besar kecil",
+			question:   models.Question{Kind: models.QuestionKindShortText, Answer: models.QuestionAnswer{Patterns: []string{"arduino( uno)?"}}},
+			submission: models.QuestionSubmission{Answer: "  Arduino UNO "},
+			want:       true,
+		},
+		{
+			name:       "short text harus cocok seluruh teks",
+			question:   models.Question{Kind: models.QuestionKindShortText, Answer: models.QuestionAnswer{Patterns: []string{"arduino"}}},
+			submission: models.QuestionSubmission{Answer: "bukan arduino"},
+			want:       false,
+		},
+		{
+			name:       "short text kosong ditolak",
+			question:   models.Question{Kind: models.QuestionKindShortText, Answer: models.QuestionAnswer{Patterns: []string{".*"}}},
+			submission: models.QuestionSubmission{Answer: ""},
+			want:       false,
+		},
+		{
+			name:       "numeric dalam toleransi dengan koma",
+			question:   models.Question{Kind: models.QuestionKindNumeric, Answer: models.QuestionAnswer{Number: &number, Tolerance: 0.05}},
+			submission: models.QuestionSubmission{Answer: "9,8"},
+			want:       true,
+		},
+		{
+			name:       "numeric di luar toleransi",
+			question:   models.Question{Kind: models.QuestionKindNumeric, Answer: models.QuestionAnswer{Number: &number, Tolerance: 0.001}},
+			submission: models.QuestionSubmission{Answer: "9.8"},
+			want:       false,
+		},
+		{
+			name:       "numeric bukan angka",
+			question:   models.Question{Kind: models.QuestionKindNumeric, Answer: models.QuestionAnswer{Number: &number, Tolerance: 1}},
+			submission: models.QuestionSubmission{Answer: "sepuluh"},
+			want:       false,
+		},
+		{
+			name:       "ordering benar",
+			question:   models.Question{Kind: models.QuestionKindOrdering, Answer: models.QuestionAnswer{OptionIDs: []string{"a", "b", "c"}}},
+			submission: models.QuestionSubmission{OptionIDs: []string{"a", "b", "c"}},
+			want:       true,
+		},
+		{
+			name:       "ordering tertukar",
+			question:   models.Question{Kind: models.QuestionKindOrdering, Answer: models.QuestionAnswer{OptionIDs: []string{"a", "b", "c"}}},
+			submission: models.QuestionSubmission{OptionIDs: []string{"a", "c", "b"}},
+			want:       false,
+		},
+		{
+			name:       "matching benar",
+			question:   models.Question{Kind: models.QuestionKindMatching, Answer: models.QuestionAnswer{Pairs: map[string]string{"opt1": "m2", "opt2": "m1"}}},
+			submission: models.QuestionSubmission{Pairs: map[string]string{"opt2": "m1", "opt1": "m2"}},
+			want:       true,
+		},
+		{
+			name:       "matching satu pasangan salah",
+			question:   models.Question{Kind: models.QuestionKindMatching, Answer: models.QuestionAnswer{Pairs: map[string]string{"opt1": "m2", "opt2": "m1"}}},
+			submission: models.QuestionSubmission{Pairs: map[string]string{"opt1": "m1", "opt2": "m2"}},
+			want:       false,
+		},
+		{
+			name:       "matching tanpa kunci tidak pernah benar",
+			question:   models.Question{Kind: models.QuestionKindMatching},
+			submission: models.QuestionSubmission{},
+			want:       false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.question.ID = 7
+			tt.question.Points = 3
+			result := GradeQuestion(tt.question, tt.submission)
+			if result.Correct != tt.want {
+				t.Fatalf("Correct = %v, want %v", result.Correct, tt.want)
+			}
+			if result.QuestionID != 7 {
+				t.Errorf("QuestionID = %d, want 7", result.QuestionID)
+			}
+			wantEarned := 0
+			if tt.want {
+				wantEarned = 3
+			}
+			if result.EarnedPoints != wantEarned || result.Points != 3 {
+				t.Errorf("points = %d/%d, want %d/3", result.EarnedPoints, result.Points, wantEarned)
+			}
+		})
+	}
+}
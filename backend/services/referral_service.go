@@ -0,0 +1,163 @@
+package services
+
+import (
+	"crypto/rand"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/imam/backend-blog-kuis/config"
+	"github.com/imam/backend-blog-kuis/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Alasan mutasi kredit
+const (
+	CreditReferralReward   = "referral_reward"
+	CreditReferralReversal = "referral_reversal"
+	CreditCheckout         = "checkout"
+	CreditCheckoutRelease  = "checkout_release"
+	CreditRefundRelease    = "refund_release"
+)
+
+var (
+	ErrReferralNotFound = errors.New("kode referral tidak ditemukan")
+	ErrReferralSelf     = errors.New("tidak bisa memakai kode referral sendiri")
+	ErrReferralUsed     = errors.New("kode referral hanya bisa dipakai sebelum pembayaran pertama")
+)
+
+// referralCodeAlphabet tanpa karakter yang mudah tertukar (0/O, 1/I)
+const referralCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// referralRewardAmount adalah kredit untuk pengundang per user yang membayar (REFERRAL_CREDIT_AMOUNT)
+func referralRewardAmount() int64 {
+	if amount, err := strconv.ParseInt(os.Getenv("REFERRAL_CREDIT_AMOUNT"), 10, 64); err == nil && amount >= 0 {
+		return amount
+	}
+	return 20000
+}
+
+// EnsureReferralCode mengembalikan kode referral user, membuatnya jika belum ada
+func EnsureReferralCode(userID uint) (string, error) {
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		return "", err
+	}
+	if user.ReferralCode != nil && *user.ReferralCode != "" {
+		return *user.ReferralCode, nil
+	}
+
+	for attempt := 0; attempt < 5; attempt++ {
+		b := make([]byte, 8)
+		rand.Read(b)
+		for i := range b {
+			b[i] = referralCodeAlphabet[int(b[i])%len(referralCodeAlphabet)]
+		}
+		code := string(b)
+
+		// Hanya isi jika masih kosong agar request bersamaan tidak saling menimpa
+		result := config.DB.Model(&models.User{}).Where("id = ? AND referral_code IS NULL", userID).Update("referral_code", code)
+		if result.Error == nil {
+			if result.RowsAffected == 0 {
+				config.DB.First(&user, userID)
+				return *user.ReferralCode, nil
+			}
+			return code, nil
+		}
+	}
+	return "", errors.New("gagal membuat kode referral")
+}
+
+// FindReferrer mencari pemilik kode referral
+func FindReferrer(code string) (models.User, error) {
+	var referrer models.User
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" || config.DB.Where("referral_code = ?", code).First(&referrer).Error != nil {
+		return referrer, ErrReferralNotFound
+	}
+	return referrer, nil
+}
+
+// applyReferralCode mencatat pengundang user; hanya bisa sekali dan sebelum pembayaran sukses pertama
+func applyReferralCode(tx *gorm.DB, userID uint, code string) error {
+	referrer, err := FindReferrer(code)
+	if err != nil {
+		return err
+	}
+	if referrer.ID == userID {
+		return ErrReferralSelf
+	}
+
+	var user models.User
+	if err := tx.First(&user, userID).Error; err != nil {
+		return err
+	}
+	if user.ReferredByID != nil {
+		if *user.ReferredByID == referrer.ID {
+			return nil
+		}
+		return ErrReferralUsed
+	}
+	var paid int64
+	tx.Model(&models.Payment{}).Where("user_id = ? AND status = ?", userID, PaymentSuccess).Count(&paid)
+	if paid > 0 {
+		return ErrReferralUsed
+	}
+
+	return tx.Model(&user).Update("referred_by_id", referrer.ID).Error
+}
+
+// rewardReferrer memberi kredit kepada pengundang saat pembayaran pertama user yang diundang sukses.
+// Unique index referred_user_id memastikan reward hanya diberikan sekali per user.
+func rewardReferrer(tx *gorm.DB, payment models.Payment) error {
+	var user models.User
+	if err := tx.First(&user, payment.UserID).Error; err != nil || user.ReferredByID == nil {
+		return nil
+	}
+	amount := referralRewardAmount()
+	if amount == 0 {
+		return nil
+	}
+
+	reward := models.CreditTransaction{
+		UserID:         *user.ReferredByID,
+		Amount:         amount,
+		Reason:         CreditReferralReward,
+		PaymentID:      &payment.ID,
+		ReferredUserID: &user.ID,
+	}
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&reward)
+	if result.Error == nil && result.RowsAffected == 1 {
+		log.Printf("[Referral] user #%d mendapat kredit %d dari user #%d", reward.UserID, amount, user.ID)
+	}
+	return result.Error
+}
+
+// reverseReferralReward menarik kembali reward referral yang diberikan dari pembayaran yang direfund penuh.
+// ReferredUserID reward asal tetap ada sehingga user yang sama tidak memberi reward kedua kalinya.
+func reverseReferralReward(tx *gorm.DB, payment models.Payment) error {
+	var reward models.CreditTransaction
+	if err := tx.Where("payment_id = ? AND reason = ?", payment.ID, CreditReferralReward).First(&reward).Error; err != nil {
+		return nil
+	}
+	if err := tx.Create(&models.CreditTransaction{
+		UserID:    reward.UserID,
+		Amount:    -reward.Amount,
+		Reason:    CreditReferralReversal,
+		PaymentID: &payment.ID,
+	}).Error; err != nil {
+		return err
+	}
+	log.Printf("[Referral] reward %d user #%d ditarik karena refund %s", reward.Amount, reward.UserID, payment.OrderID)
+	return nil
+}
+
+// CreditBalance menghitung saldo kredit user
+func CreditBalance(tx *gorm.DB, userID uint) int64 {
+	var balance int64
+	tx.Model(&models.CreditTransaction{}).Where("user_id = ?", userID).Select("COALESCE(SUM(amount), 0)").Scan(&balance)
+	return balance
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/imam/backend-blog-kuis/config"
+	"github.com/imam/backend-blog-kuis/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrRefundNotAllowed    = errors.New("hanya pembayaran sukses yang bisa dikembalikan")
+	ErrRefundAmountInvalid = errors.New("nominal refund melebihi sisa dana yang bisa dikembalikan")
+	ErrRefundGateway       = errors.New("pembayaran dibuat melalui payment gateway lain")
+)
+
+// RefundRequest adalah permintaan refund dari admin. Amount 0 berarti refund seluruh sisa dana.
+// Akses premium dari pembayaran dicabut, kecuali KeepAccess diisi untuk refund sebagian.
+type RefundRequest struct {
+	Amount     int64
+	Reason     string
+	AdminID    uint
+	KeepAccess bool
+}
+
+// Status baris PaymentRefund. Refund dicatat pending sebelum gateway dipanggil
+// sehingga nominalnya sudah dipesan walau pemanggilan gateway gagal di tengah jalan.
+const (
+	RefundPending   = "pending"
+	RefundCompleted = "completed"
+	RefundFailed    = "failed"
+)
+
+// RefundPayment mengembalikan dana melalui payment gateway lalu mencatatnya pada pembayaran.
+// Refund dicatat pending lebih dulu, gateway dipanggil di luar transaksi, lalu hasilnya difinalisasi.
+// Nominal refund yang masih pending ikut dihitung sehingga dua refund bersamaan tidak melebihi nominal.
+func RefundPayment(paymentID uint, req RefundRequest) (*models.Payment, *models.PaymentRefund, error) {
+	var payment models.Payment
+	var refund models.PaymentRefund
+	gw := Gateway()
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&payment, paymentID).Error; err != nil {
+			return ErrPaymentNotFound
+		}
+		if payment.Status != PaymentSuccess && payment.Status != PaymentPartialRefund {
+			return ErrRefundNotAllowed
+		}
+		if payment.Gateway != gw.Name() {
+			return ErrRefundGateway
+		}
+
+		var pending int64
+		if err := tx.Model(&models.PaymentRefund{}).
+			Where("payment_id = ? AND status = ?", payment.ID, RefundPending).
+			Select("COALESCE(SUM(amount), 0)").Scan(&pending).Error; err != nil {
+			return err
+		}
+
+		remaining := payment.Amount - payment.RefundedAmount - pending
+		amount := req.Amount
+		if amount == 0 {
+			amount = remaining
+		}
+		if amount <= 0 || amount > remaining {
+			return ErrRefundAmountInvalid
+		}
+
+		refund = models.PaymentRefund{PaymentID: payment.ID, Amount: amount, Reason: req.Reason, Status: RefundPending}
+		if req.AdminID != 0 {
+			refund.AdminID = &req.AdminID
+		}
+		return tx.Create(&refund).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	refundKey := fmt.Sprintf("%s-refund-%d", payment.OrderID, refund.ID)
+	if err := gw.Refund(payment.OrderID, refundKey, refund.Amount, req.Reason); err != nil {
+		if dbErr := config.DB.Model(&refund).Update("status", RefundFailed).Error; dbErr != nil {
+			log.Printf("[Payment] gagal menandai refund %d gagal: %v", refund.ID, dbErr)
+		}
+		return nil, nil, err
+	}
+
+	if err := finalizeRefund(&payment, &refund, req.KeepAccess); err != nil {
+		// Dana sudah dikembalikan gateway; baris tetap pending agar nominalnya tidak dipakai refund lain
+		log.Printf("[Payment] refund %d untuk %s berhasil di gateway tapi gagal dicatat: %v", refund.ID, payment.OrderID, err)
+		return nil, nil, err
+	}
+
+	log.Printf("[Payment] refund %d untuk %s (%s)", refund.Amount, payment.OrderID, payment.Status)
+	return &payment, &refund, nil
+}
+
+// finalizeRefund menandai refund selesai dan menerapkannya pada pembayaran setelah gateway berhasil
+func finalizeRefund(payment *models.Payment, refund *models.PaymentRefund, keepAccess bool) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(payment, refund.PaymentID).Error; err != nil {
+			return err
+		}
+		refund.Status = RefundCompleted
+		if err := tx.Model(refund).Update("status", RefundCompleted).Error; err != nil {
+			return err
+		}
+
+		payment.RefundedAmount += refund.Amount
+		payment.Status = PaymentPartialRefund
+		if payment.RefundedAmount >= payment.Amount {
+			payment.Status = PaymentRefund
+		}
+		if err := tx.Model(payment).Select("RefundedAmount", "Status").Updates(payment).Error; err != nil {
+			return err
+		}
+
+		if payment.Status == PaymentRefund {
+			if err := reverseCheckoutLedger(tx, *payment); err != nil {
+				return err
+			}
+		}
+		if payment.Status == PaymentRefund || !keepAccess {
+			return cancelPaymentSubscription(tx, *payment)
+		}
+		return nil
+	})
+}
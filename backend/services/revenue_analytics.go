@@ -0,0 +1,265 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/imam/backend-blog-kuis/config"
+	"github.com/imam/backend-blog-kuis/models"
+	"gorm.io/gorm"
+)
+
+var ErrInvalidInterval = errors.New("interval harus day, week, atau month")
+
+// settledPaymentStatuses adalah pembayaran yang pernah lunas (termasuk yang kemudian di-refund)
+var settledPaymentStatuses = []string{PaymentSuccess, PaymentPartialRefund, PaymentRefund}
+
+// RevenueRange adalah rentang waktu analitik [From, To) dalam UTC
+type RevenueRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// ParseRevenueRange membaca tanggal YYYY-MM-DD (inklusif); bawaan 30 hari terakhir
+func ParseRevenueRange(from, to string) (RevenueRange, error) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	r := RevenueRange{From: today.AddDate(0, 0, -29), To: today.AddDate(0, 0, 1)}
+
+	if from != "" {
+		t, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return r, errors.New("format tanggal from harus YYYY-MM-DD")
+		}
+		r.From = t
+	}
+	if to != "" {
+		t, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return r, errors.New("format tanggal to harus YYYY-MM-DD")
+		}
+		r.To = t.AddDate(0, 0, 1)
+	}
+	if !r.To.After(r.From) {
+		return r, errors.New("tanggal to harus setelah from")
+	}
+	return r, nil
+}
+
+// RevenuePoint adalah pendapatan pada satu bucket waktu
+type RevenuePoint struct {
+	Period       string `json:"period"` // Awal bucket, YYYY-MM-DD
+	Transactions int64  `json:"transactions"`
+	Gross        int64  `json:"gross"`
+	Refunded     int64  `json:"refunded"`
+	Net          int64  `json:"net"`
+}
+
+// truncatePeriod memotong waktu ke awal hari/minggu (Senin)/bulan, sama dengan date_trunc Postgres
+func truncatePeriod(t time.Time, interval string) time.Time {
+	t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch interval {
+	case "week":
+		return t.AddDate(0, 0, -((int(t.Weekday()) + 6) % 7))
+	case "month":
+		return t.AddDate(0, 0, 1-t.Day())
+	}
+	return t
+}
+
+func nextPeriod(t time.Time, interval string) time.Time {
+	switch interval {
+	case "week":
+		return t.AddDate(0, 0, 7)
+	case "month":
+		return t.AddDate(0, 1, 0)
+	}
+	return t.AddDate(0, 0, 1)
+}
+
+// settledPayments adalah query pembayaran yang lunas di dalam rentang (berdasarkan paid_at)
+func settledPayments(r RevenueRange) *gorm.DB {
+	return config.DB.Model(&models.Payment{}).
+		Where("payments.status IN ? AND payments.paid_at >= ? AND payments.paid_at < ?", settledPaymentStatuses, r.From, r.To)
+}
+
+// RevenueSeries mengembalikan pendapatan per hari/minggu/bulan; bucket tanpa transaksi tetap diisi 0
+func RevenueSeries(r RevenueRange, interval string) ([]RevenuePoint, error) {
+	if interval != "day" && interval != "week" && interval != "month" {
+		return nil, ErrInvalidInterval
+	}
+
+	var rows []struct {
+		Period       time.Time
+		Transactions int64
+		Gross        int64
+		Refunded     int64
+	}
+	err := settledPayments(r).
+		Select("date_trunc(?, payments.paid_at AT TIME ZONE 'UTC') AS period, COUNT(*) AS transactions, COALESCE(SUM(payments.amount), 0) AS gross, COALESCE(SUM(payments.refunded_amount), 0) AS refunded", interval).
+		Group("period").Order("period").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byPeriod := map[string]RevenuePoint{}
+	for _, row := range rows {
+		key := row.Period.Format("2006-01-02")
+		byPeriod[key] = RevenuePoint{Period: key, Transactions: row.Transactions, Gross: row.Gross, Refunded: row.Refunded, Net: row.Gross - row.Refunded}
+	}
+
+	series := []RevenuePoint{}
+	for t := truncatePeriod(r.From, interval); t.Before(r.To); t = nextPeriod(t, interval) {
+		key := t.Format("2006-01-02")
+		point, ok := byPeriod[key]
+		if !ok {
+			point = RevenuePoint{Period: key}
+		}
+		series = append(series, point)
+	}
+	return series, nil
+}
+
+// activeSubscriptionAt adalah kondisi langganan yang berlaku pada waktu tertentu.
+// Langganan yang dibatalkan dianggap berakhir pada cancelled_at.
+const activeSubscriptionAt = `subscriptions.starts_at <= @at AND @at < CASE
+	WHEN subscriptions.status = 'cancelled' THEN COALESCE(subscriptions.cancelled_at, subscriptions.starts_at)
+	ELSE subscriptions.ends_at END`
+
+// activeSubscribersAt menghitung user unik yang memiliki langganan berlaku pada waktu tertentu
+func activeSubscribersAt(at time.Time) int64 {
+	var count int64
+	config.DB.Model(&models.Subscription{}).
+		Where(activeSubscriptionAt, map[string]interface{}{"at": at}).
+		Distinct("user_id").Count(&count)
+	return count
+}
+
+// MonthlyRecurringRevenue menghitung MRR pada waktu tertentu: nilai bersih setiap langganan
+// yang berlaku dinormalisasi ke 30 hari. Langganan hasil migrasi tanpa pembayaran memakai harga paket.
+func MonthlyRecurringRevenue(at time.Time) int64 {
+	var mrr float64
+	config.DB.Model(&models.Subscription{}).
+		Joins("JOIN plans ON plans.id = subscriptions.plan_id").
+		Joins("LEFT JOIN payments ON payments.id = subscriptions.payment_id").
+		Where(activeSubscriptionAt, map[string]interface{}{"at": at}).
+		Select(`COALESCE(SUM(
+			COALESCE(payments.amount - payments.refunded_amount, plans.price) * 30.0 / GREATEST(plans.duration_days, 1)
+		), 0)`).
+		Scan(&mrr)
+	return int64(mrr + 0.5)
+}
+
+// RevenueSummary adalah metrik utama untuk rentang waktu
+type RevenueSummary struct {
+	Gross             int64   `json:"gross"`
+	Refunded          int64   `json:"refunded"`
+	Net               int64   `json:"net"`
+	Transactions      int64   `json:"transactions"`
+	PayingUsers       int64   `json:"paying_users"`
+	MRR               int64   `json:"mrr"`                // Pada akhir rentang
+	ActiveSubscribers int64   `json:"active_subscribers"` // Pada akhir rentang
+	ARPU              int64   `json:"arpu"`               // MRR / subscriber aktif
+	SubscribersStart  int64   `json:"subscribers_start"`  // Subscriber aktif di awal rentang
+	Churned           int64   `json:"churned"`            // Subscriber awal yang tidak lagi aktif di akhir rentang
+	ChurnRate         float64 `json:"churn_rate"`         // Churned / SubscribersStart (persen)
+	NewSubscribers    int64   `json:"new_subscribers"`    // Aktif di akhir tetapi tidak di awal rentang
+}
+
+// GetRevenueSummary menghitung pendapatan, MRR, ARPU, dan churn untuk rentang waktu
+func GetRevenueSummary(r RevenueRange) RevenueSummary {
+	var summary RevenueSummary
+	settledPayments(r).
+		Select("COUNT(*) AS transactions, COALESCE(SUM(payments.amount), 0) AS gross, COALESCE(SUM(payments.refunded_amount), 0) AS refunded, COUNT(DISTINCT payments.user_id) AS paying_users").
+		Scan(&summary)
+	summary.Net = summary.Gross - summary.Refunded
+
+	// Metrik langganan diukur pada detik terakhir rentang
+	end := r.To.Add(-time.Second)
+	if now := time.Now(); end.After(now) {
+		end = now
+	}
+	summary.MRR = MonthlyRecurringRevenue(end)
+	summary.ActiveSubscribers = activeSubscribersAt(end)
+	if summary.ActiveSubscribers > 0 {
+		summary.ARPU = summary.MRR / summary.ActiveSubscribers
+	}
+
+	start := map[string]interface{}{"at": r.From}
+	stillActive := map[string]interface{}{"at": end}
+	summary.SubscribersStart = activeSubscribersAt(r.From)
+	config.DB.Model(&models.Subscription{}).
+		Where(activeSubscriptionAt, start).
+		Where("NOT EXISTS (SELECT 1 FROM subscriptions AS later WHERE later.user_id = subscriptions.user_id AND "+
+			"later.starts_at <= @at AND @at < CASE WHEN later.status = 'cancelled' THEN COALESCE(later.cancelled_at, later.starts_at) ELSE later.ends_at END)", stillActive).
+		Distinct("user_id").Count(&summary.Churned)
+	if summary.SubscribersStart > 0 {
+		summary.ChurnRate = float64(summary.Churned) * 100 / float64(summary.SubscribersStart)
+	}
+	summary.NewSubscribers = summary.ActiveSubscribers - (summary.SubscribersStart - summary.Churned)
+	return summary
+}
+
+// CheckoutFunnel adalah konversi checkout yang dibuat di rentang waktu sampai lunas
+type CheckoutFunnel struct {
+	Created        int64   `json:"created"`
+	Pending        int64   `json:"pending"`
+	Settled        int64   `json:"settled"`
+	Failed         int64   `json:"failed"`
+	UsersCreated   int64   `json:"users_created"`
+	UsersSettled   int64   `json:"users_settled"`
+	ConversionRate float64 `json:"conversion_rate"` // Settled / Created (persen)
+}
+
+// GetCheckoutFunnel menghitung funnel dari checkout dibuat (created_at) sampai status akhirnya
+func GetCheckoutFunnel(r RevenueRange) CheckoutFunnel {
+	var funnel CheckoutFunnel
+	config.DB.Model(&models.Payment{}).
+		Where("created_at >= ? AND created_at < ?", r.From, r.To).
+		Select(`COUNT(*) AS created,
+			COUNT(*) FILTER (WHERE status IN ('pending', 'challenge')) AS pending,
+			COUNT(*) FILTER (WHERE status IN ?) AS settled,
+			COUNT(*) FILTER (WHERE status IN ('failure', 'deny')) AS failed,
+			COUNT(DISTINCT user_id) AS users_created,
+			COUNT(DISTINCT user_id) FILTER (WHERE status IN ?) AS users_settled`, settledPaymentStatuses, settledPaymentStatuses).
+		Scan(&funnel)
+	if funnel.Created > 0 {
+		funnel.ConversionRate = float64(funnel.Settled) * 100 / float64(funnel.Created)
+	}
+	return funnel
+}
+
+// RevenueBreakdown adalah pendapatan per kelompok (paket, metode pembayaran, atau gateway)
+type RevenueBreakdown struct {
+	Key          string `json:"key"`
+	Label        string `json:"label"`
+	Transactions int64  `json:"transactions"`
+	Gross        int64  `json:"gross"`
+	Refunded     int64  `json:"refunded"`
+	Net          int64  `json:"net"`
+}
+
+// GetRevenueBreakdown mengelompokkan pendapatan berdasarkan plan, payment_method, atau gateway.
+// Pembayaran yang metodenya belum diketahui (mis. sebelum kolom ada) dikelompokkan sebagai "unknown".
+func GetRevenueBreakdown(r RevenueRange, by string) []RevenueBreakdown {
+	query := settledPayments(r)
+	var key, label string
+	switch by {
+	case "plan":
+		query = query.Joins("LEFT JOIN plans ON plans.id = payments.plan_id")
+		key = "COALESCE(plans.code, payments.payment_type)"
+		label = "COALESCE(plans.name, payments.payment_type)"
+	case "gateway":
+		key, label = "payments.gateway", "payments.gateway"
+	default:
+		key = "COALESCE(NULLIF(payments.payment_method, ''), 'unknown')"
+		label = key
+	}
+
+	var rows []RevenueBreakdown
+	query.Select(key + " AS key, " + label + " AS label, COUNT(*) AS transactions, COALESCE(SUM(payments.amount), 0) AS gross, COALESCE(SUM(payments.refunded_amount), 0) AS refunded").
+		Group("key, label").Order("gross DESC").Scan(&rows)
+	for i := range rows {
+		rows[i].Net = rows[i].Gross - rows[i].Refunded
+	}
+	return rows
+}
@@ -0,0 +1,238 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/imam/backend-blog-kuis/config"
+	"github.com/imam/backend-blog-kuis/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Alasan pencabutan sesi
+const (
+	SessionLogout        = "logout"
+	SessionRevoked       = "revoked"
+	SessionReuseDetected = "reuse_detected"
+	SessionPasswordReset = "password_reset"
+	SessionUserDeleted   = "user_deleted"
+)
+
+var (
+	ErrSessionInvalid  = errors.New("sesi tidak valid atau sudah berakhir, silakan login ulang")
+	ErrSessionNotFound = errors.New("sesi tidak ditemukan")
+)
+
+// durationEnv membaca durasi dari env (format time.ParseDuration), atau nilai bawaan
+func durationEnv(key string, fallback time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(key)); err == nil && d > 0 {
+		return d
+	}
+	return fallback
+}
+
+// accessTokenTTL adalah masa berlaku access token (ACCESS_TOKEN_TTL, bawaan 15 menit)
+func accessTokenTTL() time.Duration {
+	return durationEnv("ACCESS_TOKEN_TTL", 15*time.Minute)
+}
+
+// refreshTokenTTL adalah masa berlaku refresh token sejak terakhir dipakai (REFRESH_TOKEN_TTL, bawaan 30 hari)
+func refreshTokenTTL() time.Duration {
+	return durationEnv("REFRESH_TOKEN_TTL", 30*24*time.Hour)
+}
+
+// SessionClient adalah informasi perangkat yang membuat atau memakai sesi
+type SessionClient struct {
+	IPAddress string
+	UserAgent string
+}
+
+// SessionTokens adalah pasangan token yang dikembalikan ke klien saat login/refresh
+type SessionTokens struct {
+	AccessToken      string    `json:"token"`
+	RefreshToken     string    `json:"refresh_token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+	SessionID        uint      `json:"session_id"`
+}
+
+// hashRefreshToken menyimpan refresh token sebagai SHA-256 agar kebocoran database tidak membocorkan token
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func newRefreshToken() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// deviceLabel membuat nama perangkat singkat dari User-Agent, misal "Chrome di Windows"
+func deviceLabel(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	browser := "Browser"
+	for _, b := range []struct{ key, name string }{
+		{"edg/", "Edge"}, {"opr/", "Opera"}, {"firefox/", "Firefox"}, {"chrome/", "Chrome"}, {"safari/", "Safari"},
+		{"okhttp", "Aplikasi Android"}, {"curl/", "curl"}, {"postman", "Postman"},
+	} {
+		if strings.Contains(ua, b.key) {
+			browser = b.name
+			break
+		}
+	}
+	for _, platform := range []struct{ key, name string }{
+		{"android", "Android"}, {"iphone", "iOS"}, {"ipad", "iPadOS"}, {"windows", "Windows"},
+		{"mac os", "macOS"}, {"linux", "Linux"},
+	} {
+		if strings.Contains(ua, platform.key) {
+			return browser + " di " + platform.name
+		}
+	}
+	return browser
+}
+
+// CreateSession membuat sesi baru setelah login berhasil dan mengembalikan access + refresh token
+func CreateSession(user models.User, client SessionClient) (*SessionTokens, error) {
+	now := time.Now()
+	refreshToken := newRefreshToken()
+	session := models.UserSession{
+		UserID:           user.ID,
+		RefreshTokenHash: hashRefreshToken(refreshToken),
+		Device:           deviceLabel(client.UserAgent),
+		UserAgent:        client.UserAgent,
+		IPAddress:        client.IPAddress,
+		LastUsedAt:       now,
+		ExpiresAt:        now.Add(refreshTokenTTL()),
+	}
+	if err := config.DB.Create(&session).Error; err != nil {
+		return nil, err
+	}
+
+	expiresAt := now.Add(accessTokenTTL())
+	accessToken, err := IssueAccessToken(user, session.ID, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &SessionTokens{
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		ExpiresAt:        expiresAt,
+		RefreshExpiresAt: session.ExpiresAt,
+		SessionID:        session.ID,
+	}, nil
+}
+
+// RefreshSession merotasi refresh token dan menerbitkan access token baru dengan role terbaru.
+// Refresh token lama yang dipakai ulang dianggap dicuri sehingga seluruh sesinya dicabut.
+func RefreshSession(refreshToken string, client SessionClient) (*SessionTokens, error) {
+	hash := hashRefreshToken(strings.TrimSpace(refreshToken))
+	var tokens *SessionTokens
+	reused := false
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var session models.UserSession
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("refresh_token_hash = ?", hash).First(&session).Error; err != nil {
+			// Token sebelumnya (sudah dirotasi) dipakai lagi
+			if tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("previous_token_hash = ? AND revoked_at IS NULL", hash).First(&session).Error == nil {
+				reused = true
+				return revokeSession(tx, &session, SessionReuseDetected)
+			}
+			return ErrSessionInvalid
+		}
+
+		now := time.Now()
+		if !session.IsActive(now) {
+			return ErrSessionInvalid
+		}
+
+		var user models.User
+		if err := tx.First(&user, session.UserID).Error; err != nil {
+			if err := revokeSession(tx, &session, SessionUserDeleted); err != nil {
+				return err
+			}
+			return ErrSessionInvalid
+		}
+
+		newToken := newRefreshToken()
+		session.PreviousTokenHash = session.RefreshTokenHash
+		session.RefreshTokenHash = hashRefreshToken(newToken)
+		session.LastUsedAt = now
+		session.ExpiresAt = now.Add(refreshTokenTTL())
+		if client.IPAddress != "" {
+			session.IPAddress = client.IPAddress
+		}
+		if client.UserAgent != "" {
+			session.UserAgent = client.UserAgent
+			session.Device = deviceLabel(client.UserAgent)
+		}
+		if err := tx.Save(&session).Error; err != nil {
+			return err
+		}
+
+		expiresAt := now.Add(accessTokenTTL())
+		accessToken, err := IssueAccessToken(user, session.ID, expiresAt)
+		if err != nil {
+			return err
+		}
+		tokens = &SessionTokens{
+			AccessToken:      accessToken,
+			RefreshToken:     newToken,
+			ExpiresAt:        expiresAt,
+			RefreshExpiresAt: session.ExpiresAt,
+			SessionID:        session.ID,
+		}
+		return nil
+	})
+	if reused {
+		log.Printf("[Session] refresh token lama dipakai ulang dari %s, sesi dicabut", client.IPAddress)
+		return nil, ErrSessionInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func revokeSession(tx *gorm.DB, session *models.UserSession, reason string) error {
+	now := time.Now()
+	session.RevokedAt = &now
+	session.RevokedReason = reason
+	return tx.Model(session).Select("RevokedAt", "RevokedReason").Updates(session).Error
+}
+
+// RevokeSessionByRefreshToken mencabut sesi milik refresh token (logout)
+func RevokeSessionByRefreshToken(refreshToken string) error {
+	var session models.UserSession
+	if err := config.DB.Where("refresh_token_hash = ? AND revoked_at IS NULL", hashRefreshToken(strings.TrimSpace(refreshToken))).
+		First(&session).Error; err != nil {
+		return ErrSessionNotFound
+	}
+	return revokeSession(config.DB, &session, SessionLogout)
+}
+
+// RevokeUserSession mencabut satu sesi milik user
+func RevokeUserSession(userID, sessionID uint, reason string) error {
+	var session models.UserSession
+	if err := config.DB.Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).First(&session).Error; err != nil {
+		return ErrSessionNotFound
+	}
+	return revokeSession(config.DB, &session, reason)
+}
+
+// RevokeUserSessions mencabut semua sesi aktif user, kecuali exceptID (0 = cabut semua)
+func RevokeUserSessions(userID, exceptID uint, reason string) (int64, error) {
+	result := config.DB.Model(&models.UserSession{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, exceptID).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoked_reason": reason})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"time"
+
+	"github.com/imam/backend-blog-kuis/config"
+	"github.com/imam/backend-blog-kuis/models"
+)
+
+// RecordSubmission menyimpan versi baru sebuah submission project. Versi sebelumnya yang
+// masih pending ditandai "superseded" agar tidak ikut direview.
+func RecordSubmission(progress models.UserProgress, fileURL, driveLink, status string) (models.Submission, error) {
+	config.DB.Model(&models.Submission{}).
+		Where("progress_id = ? AND status = ?", progress.ID, "pending").
+		Update("status", "superseded")
+
+	var lastAttempt int
+	config.DB.Model(&models.Submission{}).
+		Where("progress_id = ?", progress.ID).
+		Select("COALESCE(MAX(attempt), 0)").
+		Scan(&lastAttempt)
+
+	submission := models.Submission{
+		ProgressID: progress.ID,
+		UserID:     progress.UserID,
+		QuizID:     progress.QuizID,
+		Attempt:    lastAttempt + 1,
+		FileURL:    fileURL,
+		DriveLink:  driveLink,
+		Status:     status,
+	}
+	err := config.DB.Create(&submission).Error
+	return submission, err
+}
+
+// ReviewLatestSubmission mencatat keputusan review pada versi submission terbaru.
+// reviewerID bernilai nil jika keputusan dibuat oleh sistem (auto-grade atau peer review).
+func ReviewLatestSubmission(progressID uint, status string, reviewerID *uint, note string) {
+	var submission models.Submission
+	if err := config.DB.Where("progress_id = ?", progressID).Order("attempt DESC").First(&submission).Error; err != nil {
+		return
+	}
+
+	now := time.Now()
+	config.DB.Model(&submission).Updates(map[string]interface{}{
+		"status":      status,
+		"reviewer_id": reviewerID,
+		"review_note": note,
+		"reviewed_at": now,
+	})
+}
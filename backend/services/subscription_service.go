@@ -0,0 +1,215 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/imam/backend-blog-kuis/config"
+	"github.com/imam/backend-blog-kuis/models"
+	"github.com/imam/backend-blog-kuis/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Status langganan
+const (
+	SubscriptionActive    = "active"
+	SubscriptionExpired   = "expired"
+	SubscriptionCancelled = "cancelled"
+)
+
+var ErrPlanNotFound = errors.New("paket langganan tidak ditemukan")
+
+// FindPlan mencari paket aktif berdasarkan ID, atau berdasarkan kode untuk klien lama
+func FindPlan(id uint, code string) (models.Plan, error) {
+	var plan models.Plan
+	db := config.DB.Where("active = ?", true)
+	if id != 0 {
+		db = db.Where("id = ?", id)
+	} else if code != "" {
+		db = db.Where("code = ?", code)
+	} else {
+		return plan, ErrPlanNotFound
+	}
+	if err := db.First(&plan).Error; err != nil {
+		return plan, ErrPlanNotFound
+	}
+	return plan, nil
+}
+
+// ActiveSubscription mengembalikan langganan yang sedang berlaku untuk user (nil jika tidak ada)
+func ActiveSubscription(userID uint) *models.Subscription {
+	if userID == 0 {
+		return nil
+	}
+	now := time.Now()
+	var sub models.Subscription
+	if err := config.DB.Preload("Plan").
+		Where("user_id = ? AND status = ? AND starts_at <= ? AND ends_at > ?", userID, SubscriptionActive, now, now).
+		Order("ends_at DESC").First(&sub).Error; err != nil {
+		return nil
+	}
+	return &sub
+}
+
+// HasActiveSubscription memeriksa apakah user memiliki langganan yang sedang berlaku
+func HasActiveSubscription(userID uint) bool {
+	return ActiveSubscription(userID) != nil
+}
+
+// activateSubscription membuat langganan dari pembayaran sukses di dalam transaksi pembayaran.
+// Jika user masih punya langganan aktif, masa langganan baru dimulai setelah yang lama berakhir.
+// Unique index payment_id membuat notifikasi berulang tidak menambah masa langganan dua kali.
+func activateSubscription(tx *gorm.DB, payment models.Payment) error {
+	duration := 30
+	if payment.PlanID != nil {
+		var plan models.Plan
+		if err := tx.First(&plan, *payment.PlanID).Error; err == nil {
+			duration = plan.DurationDays
+		}
+	}
+
+	start := time.Now()
+	var latest models.Subscription
+	if err := tx.Where("user_id = ? AND status = ? AND ends_at > ?", payment.UserID, SubscriptionActive, start).
+		Order("ends_at DESC").First(&latest).Error; err == nil {
+		start = latest.EndsAt
+	}
+
+	sub := models.Subscription{
+		UserID:    payment.UserID,
+		PlanID:    payment.PlanID,
+		PaymentID: &payment.ID,
+		Status:    SubscriptionActive,
+		StartsAt:  start,
+		EndsAt:    start.AddDate(0, 0, duration),
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&sub).Error; err != nil {
+		return err
+	}
+	return syncProRole(tx, payment.UserID)
+}
+
+// cancelPaymentSubscription membatalkan langganan yang dibuat dari pembayaran yang di-refund
+func cancelPaymentSubscription(tx *gorm.DB, payment models.Payment) error {
+	now := time.Now()
+	if err := tx.Model(&models.Subscription{}).
+		Where("payment_id = ? AND status = ?", payment.ID, SubscriptionActive).
+		Updates(map[string]interface{}{"status": SubscriptionCancelled, "cancelled_at": now}).Error; err != nil {
+		return err
+	}
+	return syncProRole(tx, payment.UserID)
+}
+
+// syncProRole menyamakan role "pro" (dipakai untuk tampilan) dengan status langganan.
+// Hanya role user/pro yang diubah sehingga admin dan super_admin tidak tersentuh.
+func syncProRole(tx *gorm.DB, userID uint) error {
+	now := time.Now()
+	var active int64
+	tx.Model(&models.Subscription{}).
+		Where("user_id = ? AND status = ? AND starts_at <= ? AND ends_at > ?", userID, SubscriptionActive, now, now).
+		Count(&active)
+
+	if active > 0 {
+		return tx.Model(&models.User{}).Where("id = ? AND role = ?", userID, "user").Update("role", "pro").Error
+	}
+	return tx.Model(&models.User{}).Where("id = ? AND role = ?", userID, "pro").Update("role", "user").Error
+}
+
+// ExpireSubscriptions menandai langganan yang sudah lewat masa berlakunya sebagai expired
+// dan mengembalikan role user yang tidak lagi memiliki langganan aktif
+func ExpireSubscriptions() int {
+	var subs []models.Subscription
+	config.DB.Where("status = ? AND ends_at <= ?", SubscriptionActive, time.Now()).Find(&subs)
+
+	expired := 0
+	for _, sub := range subs {
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&sub).Update("status", SubscriptionExpired).Error; err != nil {
+				return err
+			}
+			return syncProRole(tx, sub.UserID)
+		})
+		if err != nil {
+			log.Printf("[Subscription] gagal mengakhiri langganan #%d: %v", sub.ID, err)
+			continue
+		}
+		expired++
+	}
+
+	// Langganan lanjutan yang baru mulai berlaku juga perlu menaikkan role
+	var starting []uint
+	now := time.Now()
+	config.DB.Model(&models.Subscription{}).
+		Joins("JOIN users ON users.id = subscriptions.user_id").
+		Where("subscriptions.status = ? AND subscriptions.starts_at <= ? AND subscriptions.ends_at > ? AND users.role = ?", SubscriptionActive, now, now, "user").
+		Distinct().Pluck("subscriptions.user_id", &starting)
+	for _, userID := range starting {
+		syncProRole(config.DB, userID)
+	}
+
+	return expired
+}
+
+// renewalReminderDays adalah jumlah hari sebelum berakhir untuk mengirim pengingat (SUBSCRIPTION_REMINDER_DAYS)
+func renewalReminderDays() int {
+	if days, err := strconv.Atoi(os.Getenv("SUBSCRIPTION_REMINDER_DAYS")); err == nil && days > 0 {
+		return days
+	}
+	return 3
+}
+
+// SendRenewalReminders mengirim email pengingat perpanjangan untuk langganan yang segera berakhir.
+// Langganan yang sudah diperpanjang (ada langganan lanjutan) tidak dikirimi pengingat.
+func SendRenewalReminders() int {
+	now := time.Now()
+	var subs []models.Subscription
+	config.DB.Preload("User").Preload("Plan").
+		Where("status = ? AND reminder_sent_at IS NULL AND ends_at > ? AND ends_at <= ?", SubscriptionActive, now, now.AddDate(0, 0, renewalReminderDays())).
+		Where("NOT EXISTS (SELECT 1 FROM subscriptions later WHERE later.user_id = subscriptions.user_id AND later.status = ? AND later.starts_at >= subscriptions.ends_at)", SubscriptionActive).
+		Find(&subs)
+
+	sent := 0
+	for _, sub := range subs {
+		planName := "Pro"
+		if sub.Plan != nil {
+			planName = sub.Plan.Name
+		}
+		body := fmt.Sprintf(`<p>Halo %s,</p>
+<p>Langganan <b>%s</b> Anda akan berakhir pada <b>%s</b>.</p>
+<p>Perpanjang sekarang agar akses ke materi premium tidak terputus: <a href="%s/pricing">%s/pricing</a></p>`,
+			sub.User.Username, planName, sub.EndsAt.Format("02 Jan 2006 15:04"), utils.FrontendURL(), utils.FrontendURL())
+
+		if err := utils.SendEmail(sub.User.Email, "Langganan Anda segera berakhir - AIOT", body); err != nil {
+			log.Printf("[Subscription] gagal mengirim pengingat langganan #%d: %v", sub.ID, err)
+			continue
+		}
+		config.DB.Model(&sub).Update("reminder_sent_at", now)
+		sent++
+	}
+	return sent
+}
+
+// StartSubscriptionScheduler menjalankan job expiry dan pengingat perpanjangan setiap jam
+func StartSubscriptionScheduler() {
+	run := func() {
+		expired := ExpireSubscriptions()
+		reminded := SendRenewalReminders()
+		if expired > 0 || reminded > 0 {
+			log.Printf("[Subscription] %d langganan berakhir, %d pengingat dikirim", expired, reminded)
+		}
+	}
+
+	go func() {
+		run()
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for range ticker.C {
+			run()
+		}
+	}()
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/imam/backend-blog-kuis/config"
+	"github.com/imam/backend-blog-kuis/models"
+	"github.com/imam/backend-blog-kuis/utils"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTokenInvalid = errors.New("token tidak valid atau kadaluwarsa")
+	ErrTokenStale   = errors.New("sesi sudah berakhir, silakan login ulang")
+)
+
+// TokenClaims adalah isi access token. TokenVersion harus sama dengan token_version user;
+// versi dinaikkan saat role diubah atau user dihapus sehingga token lama langsung tidak berlaku.
+type TokenClaims struct {
+	UserID       uint   `json:"user_id"`
+	Username     string `json:"username"`
+	Role         string `json:"role"`
+	SessionID    uint   `json:"sid"`
+	TokenVersion int    `json:"ver"`
+	jwt.RegisteredClaims
+}
+
+// IssueAccessToken menandatangani access token (HS256) untuk user pada sesi tertentu
+func IssueAccessToken(user models.User, sessionID uint, expiresAt time.Time) (string, error) {
+	now := time.Now()
+	claims := TokenClaims{
+		UserID:       user.ID,
+		Username:     user.Username,
+		Role:         user.Role,
+		SessionID:    sessionID,
+		TokenVersion: user.TokenVersion,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(utils.GetJWTSecret())
+}
+
+// ParseAccessToken memverifikasi tanda tangan dan masa berlaku access token
+func ParseAccessToken(tokenString string) (*TokenClaims, error) {
+	claims := &TokenClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return utils.GetJWTSecret(), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil || !token.Valid || claims.UserID == 0 {
+		return nil, ErrTokenInvalid
+	}
+	return claims, nil
+}
+
+// ValidateTokenClaims memastikan sesi token belum dicabut, user masih ada, dan versi token masih berlaku.
+// Token tanpa sesi (diterbitkan sebelum fitur sesi ada) tidak bisa dicabut sehingga ditolak.
+func ValidateTokenClaims(claims *TokenClaims) error {
+	if claims.SessionID == 0 {
+		return ErrTokenStale
+	}
+
+	var current struct {
+		TokenVersion int
+	}
+	err := config.DB.Model(&models.UserSession{}).
+		Select("users.token_version").
+		Joins("JOIN users ON users.id = user_sessions.user_id AND users.deleted_at IS NULL").
+		Where("user_sessions.id = ? AND user_sessions.user_id = ? AND user_sessions.revoked_at IS NULL", claims.SessionID, claims.UserID).
+		Take(&current).Error
+	if err != nil || current.TokenVersion != claims.TokenVersion {
+		return ErrTokenStale
+	}
+	return nil
+}
+
+// BumpTokenVersion membatalkan semua access token user yang sudah terbit.
+// Sesi tetap ada, sehingga klien mendapat token dengan role terbaru lewat refresh.
+func BumpTokenVersion(userID uint) error {
+	return config.DB.Model(&models.User{}).Unscoped().Where("id = ?", userID).
+		UpdateColumn("token_version", gorm.Expr("token_version + 1")).Error
+}
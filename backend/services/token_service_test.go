@@ -0,0 +1,152 @@
+package services
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/imam/backend-blog-kuis/config"
+	"github.com/imam/backend-blog-kuis/models"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const testJWTSecret = "test-secret"
+
+func signTestToken(t *testing.T, method jwt.SigningMethod, key interface{}, claims TokenClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestParseAccessToken(t *testing.T) {
+	t.Setenv("JWT_SECRET", testJWTSecret)
+
+	user := models.User{Username: "budi", Role: "admin", TokenVersion: 3}
+	user.ID = 42
+	valid, err := IssueAccessToken(user, 7, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	future := jwt.NewNumericDate(time.Now().Add(time.Hour))
+	base := TokenClaims{UserID: 42, Role: "admin", SessionID: 7, RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: future}}
+	noUser := base
+	noUser.UserID = 0
+	noExpiry := base
+	noExpiry.ExpiresAt = nil
+	expired := base
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"token valid", valid, false},
+		{"secret lain", signTestToken(t, jwt.SigningMethodHS256, []byte("other-secret"), base), true},
+		{"algoritma lain", signTestToken(t, jwt.SigningMethodHS512, []byte(testJWTSecret), base), true},
+		{"algoritma none", signTestToken(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, base), true},
+		{"kadaluwarsa", signTestToken(t, jwt.SigningMethodHS256, []byte(testJWTSecret), expired), true},
+		{"tanpa exp", signTestToken(t, jwt.SigningMethodHS256, []byte(testJWTSecret), noExpiry), true},
+		{"tanpa user_id", signTestToken(t, jwt.SigningMethodHS256, []byte(testJWTSecret), noUser), true},
+		{"bukan jwt", "bukan.token.jwt", true},
+		{"kosong", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := ParseAccessToken(tt.token)
+			if tt.wantErr {
+				if !errors.Is(err, ErrTokenInvalid) || claims != nil {
+					t.Fatalf("ParseAccessToken() = %+v, %v; want ErrTokenInvalid", claims, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseAccessToken() error = %v", err)
+			}
+			if claims.UserID != 42 || claims.Username != "budi" || claims.Role != "admin" || claims.SessionID != 7 || claims.TokenVersion != 3 {
+				t.Errorf("claims = %+v", claims)
+			}
+		})
+	}
+}
+
+// useMockDB mengganti config.DB dengan koneksi sqlmock selama test berjalan
+func useMockDB(t *testing.T) sqlmock.Sqlmock {
+	t.Helper()
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := config.DB
+	config.DB = db
+	t.Cleanup(func() {
+		config.DB = previous
+		sqlDB.Close()
+	})
+	return mock
+}
+
+func TestValidateTokenClaims(t *testing.T) {
+	query := regexp.QuoteMeta(`SELECT users.token_version FROM "user_sessions" JOIN users ON users.id = user_sessions.user_id AND users.deleted_at IS NULL`)
+
+	tests := []struct {
+		name    string
+		claims  TokenClaims
+		rows    *sqlmock.Rows // nil berarti query tidak boleh dijalankan
+		wantErr error
+	}{
+		{
+			name:    "versi sama",
+			claims:  TokenClaims{UserID: 42, SessionID: 7, TokenVersion: 3},
+			rows:    sqlmock.NewRows([]string{"token_version"}).AddRow(3),
+			wantErr: nil,
+		},
+		{
+			name:    "versi sudah dinaikkan",
+			claims:  TokenClaims{UserID: 42, SessionID: 7, TokenVersion: 2},
+			rows:    sqlmock.NewRows([]string{"token_version"}).AddRow(3),
+			wantErr: ErrTokenStale,
+		},
+		{
+			name:    "sesi dicabut atau user dihapus",
+			claims:  TokenClaims{UserID: 42, SessionID: 7, TokenVersion: 3},
+			rows:    sqlmock.NewRows([]string{"token_version"}),
+			wantErr: ErrTokenStale,
+		},
+		{
+			name:    "token tanpa sesi",
+			claims:  TokenClaims{UserID: 42, TokenVersion: 3},
+			wantErr: ErrTokenStale,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := useMockDB(t)
+			if tt.rows != nil {
+				mock.ExpectQuery(query).WithArgs(tt.claims.SessionID, tt.claims.UserID, 1).WillReturnRows(tt.rows)
+			}
+
+			if err := ValidateTokenClaims(&tt.claims); !errors.Is(err, tt.wantErr) {
+				t.Errorf("ValidateTokenClaims() = %v, want %v", err, tt.wantErr)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
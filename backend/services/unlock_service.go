@@ -0,0 +1,269 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/imam/backend-blog-kuis/config"
+	"github.com/imam/backend-blog-kuis/models"
+	"gorm.io/gorm"
+)
+
+// completedLessonIDs mengembalikan materi yang sudah selesai milik user.
+// Project baru dianggap selesai setelah submission-nya disetujui.
+func completedLessonIDs(userID uint) map[uint]bool {
+	var ids []uint
+	if userID != 0 {
+		config.DB.Model(&models.UserProgress{}).
+			Joins("JOIN quizzes ON quizzes.id = user_progresses.quiz_id").
+			Where("user_progresses.user_id = ? AND user_progresses.completed = true", userID).
+			Where("(quizzes.type != 'project' OR user_progresses.approval_status = 'approved')").
+			Pluck("user_progresses.quiz_id", &ids)
+	}
+
+	completed := map[uint]bool{}
+	for _, id := range ids {
+		completed[id] = true
+	}
+	return completed
+}
+
+// orderedLessons mengurutkan materi path berdasarkan Chapter.Order lalu Quiz.Order
+func orderedLessons(pathID uint) []models.Quiz {
+	var lessons []models.Quiz
+	config.DB.Select("quizzes.id, quizzes.title, quizzes.path_id, quizzes.chapter_id, quizzes.\"order\"").
+		Joins("LEFT JOIN chapters ON chapters.id = quizzes.chapter_id").
+		Where("quizzes.path_id = ?", pathID).
+		Order(`chapters."order" ASC, quizzes."order" ASC, quizzes.id ASC`).
+		Find(&lessons)
+	return lessons
+}
+
+// PathLocks menghitung status kunci path dan setiap materi di dalamnya untuk user.
+// userID 0 berarti pengunjung yang belum login (belum menyelesaikan apa pun).
+func PathLocks(userID uint, path models.LearningPath) (models.LessonLock, map[uint]models.LessonLock) {
+	completed := completedLessonIDs(userID)
+	pathLock := pathPrerequisiteLock(path.ID, completed)
+
+	lessons := orderedLessons(path.ID)
+	lessonIDs := []uint{}
+	titles := map[uint]string{}
+	for _, l := range lessons {
+		lessonIDs = append(lessonIDs, l.ID)
+		titles[l.ID] = l.Title
+	}
+
+	var edges []models.LessonPrerequisite
+	if len(lessonIDs) > 0 {
+		config.DB.Where("quiz_id IN ?", lessonIDs).Find(&edges)
+	}
+	required := map[uint][]uint{}
+	var externalIDs []uint
+	for _, e := range edges {
+		required[e.QuizID] = append(required[e.QuizID], e.RequiredQuizID)
+		if _, inPath := titles[e.RequiredQuizID]; !inPath {
+			externalIDs = append(externalIDs, e.RequiredQuizID)
+		}
+	}
+	// Prasyarat bisa berasal dari path lain, ambil judulnya untuk pesan alasan
+	if len(externalIDs) > 0 {
+		var external []models.Quiz
+		config.DB.Select("id, title").Where("id IN ?", externalIDs).Find(&external)
+		for _, q := range external {
+			titles[q.ID] = q.Title
+		}
+	}
+
+	// Jadwal drip hanya berlaku untuk peserta cohort
+	unlocks := chapterUnlockTimes(userID, path.ID)
+	now := time.Now()
+
+	locks := map[uint]models.LessonLock{}
+	firstIncomplete := uint(0)
+	for _, lesson := range lessons {
+		lock := models.LessonLock{}
+		unlockAt, scheduled := unlocks[lesson.ChapterID]
+		switch {
+		case pathLock.Locked:
+			lock = pathLock
+		case scheduled && now.Before(unlockAt):
+			lock = models.LessonLock{
+				Locked:   true,
+				Reason:   "Bab ini terbuka pada " + unlockAt.Format("02 Jan 2006 15:04"),
+				UnlockAt: &unlockAt,
+			}
+		case path.Sequential && firstIncomplete != 0:
+			lock = models.LessonLock{Locked: true, Reason: fmt.Sprintf("Selesaikan \"%s\" terlebih dahulu", titles[firstIncomplete])}
+		default:
+			for _, requiredID := range required[lesson.ID] {
+				if !completed[requiredID] {
+					lock = models.LessonLock{Locked: true, Reason: fmt.Sprintf("Materi prasyarat \"%s\" belum selesai", titles[requiredID])}
+					break
+				}
+			}
+		}
+		locks[lesson.ID] = lock
+
+		if firstIncomplete == 0 && !completed[lesson.ID] {
+			firstIncomplete = lesson.ID
+		}
+	}
+
+	return pathLock, locks
+}
+
+// pathPrerequisiteLock mengunci path jika ada path prasyarat yang belum diselesaikan
+func pathPrerequisiteLock(pathID uint, completed map[uint]bool) models.LessonLock {
+	var edges []models.PathPrerequisite
+	config.DB.Preload("RequiredPath").Where("path_id = ?", pathID).Find(&edges)
+
+	for _, e := range edges {
+		var lessonIDs []uint
+		config.DB.Model(&models.Quiz{}).Where("path_id = ?", e.RequiredPathID).Pluck("id", &lessonIDs)
+		for _, id := range lessonIDs {
+			if !completed[id] {
+				return models.LessonLock{Locked: true, Reason: fmt.Sprintf("Selesaikan learning path \"%s\" terlebih dahulu", e.RequiredPath.Title)}
+			}
+		}
+	}
+	return models.LessonLock{}
+}
+
+// LessonLockFor menghitung status kunci satu materi untuk user
+func LessonLockFor(userID uint, quiz models.Quiz) models.LessonLock {
+	var path models.LearningPath
+	if err := config.DB.First(&path, quiz.PathID).Error; err != nil {
+		return models.LessonLock{}
+	}
+	_, locks := PathLocks(userID, path)
+	return locks[quiz.ID]
+}
+
+// SetLessonPrerequisites mengganti daftar prasyarat sebuah materi dan menolak siklus
+func SetLessonPrerequisites(quizID uint, requiredIDs []uint) error {
+	requiredIDs = uniqueIDs(requiredIDs)
+
+	var found int64
+	config.DB.Model(&models.Quiz{}).Where("id IN ?", requiredIDs).Count(&found)
+	if int(found) != len(requiredIDs) {
+		return errors.New("materi prasyarat tidak ditemukan")
+	}
+
+	var edges []models.LessonPrerequisite
+	config.DB.Find(&edges)
+	graph := map[uint][]uint{}
+	for _, e := range edges {
+		if e.QuizID != quizID {
+			graph[e.QuizID] = append(graph[e.QuizID], e.RequiredQuizID)
+		}
+	}
+	graph[quizID] = requiredIDs
+	if hasCycle(graph, quizID) {
+		return errors.New("prasyarat membentuk siklus")
+	}
+
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("quiz_id = ?", quizID).Delete(&models.LessonPrerequisite{}).Error; err != nil {
+			return err
+		}
+		for _, id := range requiredIDs {
+			if err := tx.Create(&models.LessonPrerequisite{QuizID: quizID, RequiredQuizID: id}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SetPathPrerequisites mengganti daftar path prasyarat sebuah learning path dan menolak siklus
+func SetPathPrerequisites(pathID uint, requiredIDs []uint) error {
+	requiredIDs = uniqueIDs(requiredIDs)
+
+	var found int64
+	config.DB.Model(&models.LearningPath{}).Where("id IN ?", requiredIDs).Count(&found)
+	if int(found) != len(requiredIDs) {
+		return errors.New("learning path prasyarat tidak ditemukan")
+	}
+
+	var edges []models.PathPrerequisite
+	config.DB.Find(&edges)
+	graph := map[uint][]uint{}
+	for _, e := range edges {
+		if e.PathID != pathID {
+			graph[e.PathID] = append(graph[e.PathID], e.RequiredPathID)
+		}
+	}
+	graph[pathID] = requiredIDs
+	if hasCycle(graph, pathID) {
+		return errors.New("prasyarat membentuk siklus")
+	}
+
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("path_id = ?", pathID).Delete(&models.PathPrerequisite{}).Error; err != nil {
+			return err
+		}
+		for _, id := range requiredIDs {
+			if err := tx.Create(&models.PathPrerequisite{PathID: pathID, RequiredPathID: id}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// LessonPrerequisiteIDs mengembalikan prasyarat setiap materi dalam bentuk map
+func LessonPrerequisiteIDs(quizIDs []uint) map[uint][]uint {
+	result := map[uint][]uint{}
+	if len(quizIDs) == 0 {
+		return result
+	}
+	var edges []models.LessonPrerequisite
+	config.DB.Where("quiz_id IN ?", quizIDs).Order("required_quiz_id ASC").Find(&edges)
+	for _, e := range edges {
+		result[e.QuizID] = append(result[e.QuizID], e.RequiredQuizID)
+	}
+	return result
+}
+
+// PathPrerequisiteIDs mengembalikan daftar path prasyarat sebuah learning path
+func PathPrerequisiteIDs(pathID uint) []uint {
+	ids := []uint{}
+	config.DB.Model(&models.PathPrerequisite{}).Where("path_id = ?", pathID).
+		Order("required_path_id ASC").Pluck("required_path_id", &ids)
+	return ids
+}
+
+// hasCycle memeriksa apakah start dapat dicapai kembali dari prasyaratnya sendiri
+func hasCycle(graph map[uint][]uint, start uint) bool {
+	visited := map[uint]bool{}
+	stack := append([]uint{}, graph[start]...)
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if node == start {
+			return true
+		}
+		if visited[node] {
+			continue
+		}
+		visited[node] = true
+		stack = append(stack, graph[node]...)
+	}
+	return false
+}
+
+// uniqueIDs membuang ID duplikat dan mengurutkannya
+func uniqueIDs(ids []uint) []uint {
+	seen := map[uint]bool{}
+	result := []uint{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+	return result
+}
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+
+	"github.com/imam/backend-blog-kuis/config"
+	"github.com/imam/backend-blog-kuis/models"
+)
+
+var ErrUploadNotOwned = errors.New("file tidak ditemukan atau bukan milik anda")
+
+// RecordUpload mencatat pemilik file yang baru disimpan di folder uploads
+func RecordUpload(userID uint, url, originalName string, size int64) error {
+	return config.DB.Create(&models.Upload{UserID: userID, URL: url, OriginalName: originalName, Size: size}).Error
+}
+
+// OwnsUpload memeriksa bahwa URL "/uploads/xxx" diunggah oleh user tersebut
+func OwnsUpload(userID uint, url string) bool {
+	var count int64
+	config.DB.Model(&models.Upload{}).Where("user_id = ? AND url = ?", userID, url).Count(&count)
+	return count > 0
+}
+
+// ownedUploadPath memetakan URL upload milik user ke path file lokal; URL lain ditolak
+func ownedUploadPath(userID uint, url string) (string, error) {
+	if !strings.HasPrefix(url, "/uploads/") {
+		return "", errors.New("hanya file yang diunggah ke server yang bisa dinilai otomatis")
+	}
+	var upload models.Upload
+	if err := config.DB.Where("user_id = ? AND url = ?", userID, url).First(&upload).Error; err != nil {
+		return "", ErrUploadNotOwned
+	}
+	return filepath.Join("uploads", filepath.Base(upload.URL)), nil
+}
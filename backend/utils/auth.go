@@ -1,11 +1,8 @@
 package utils
 
 import (
-	"errors"
 	"os"
-	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -32,34 +29,3 @@ func CheckPasswordHash(password, hash string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil
 }
-
-// GenerateToken membuat token JWT baru yang berlaku selama 24 jam
-func GenerateToken(username string) (string, error) {
-	// Menentukan klaim (payload) token
-	claims := jwt.MapClaims{
-		"username": username,
-		"exp":      time.Now().Add(time.Hour * 24).Unix(), // Kadaluwarsa dalam 24 jam
-		"iat":      time.Now().Unix(),                     // Issued At (kapan dibuat)
-	}
-
-	// Membuat objek token dengan algoritma HS256
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	// Menandatangani token dengan secret key
-	tokenString, err := token.SignedString(GetJWTSecret())
-	if err != nil {
-		return "", err
-	}
-
-	return tokenString, nil
-}
-
-// VerifyToken (Opsional) jika ingin memvalidasi token secara manual di luar middleware
-func VerifyToken(tokenString string) (*jwt.Token, error) {
-	return jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("metode signing tidak valid")
-		}
-		return GetJWTSecret(), nil
-	})
-}
@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"os"
+	"strings"
+)
+
+// FrontendURL returns the public URL of the frontend (FRONTEND_URL), used for links in emails and QR codes
+func FrontendURL() string {
+	if url := strings.TrimRight(os.Getenv("FRONTEND_URL"), "/"); url != "" {
+		return url
+	}
+	return "https://aiotchain.vercel.app"
+}
+
+// APIURL returns the public URL of this backend (API_URL). When unset, fallback (usually derived from the request) is used.
+func APIURL(fallback string) string {
+	if url := strings.TrimRight(os.Getenv("API_URL"), "/"); url != "" {
+		return url
+	}
+	return strings.TrimRight(fallback, "/")
+}